@@ -0,0 +1,78 @@
+// Package aws generates the logs of AWS services: ALB access logs, CloudFront standard logs,
+// VPC flow logs, CloudTrail events and the runtime logs of Lambda functions. The clusters of
+// the generator are named after AWS regions, so they are used as the regions of the resources.
+package aws
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+)
+
+// AccountID is the AWS account owning the resources.
+const AccountID = "123456789012"
+
+// hash returns a stable hash of parts.
+func hash(parts ...string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.Join(parts, "/")))
+	return h.Sum64()
+}
+
+// hexID returns n hex digits identifying parts, like the suffixes of AWS resource IDs.
+func hexID(n int, parts ...string) string {
+	id := fmt.Sprintf("%016x%016x", hash(parts...), hash(append(parts, "#")...))
+	return id[:n]
+}
+
+// seconds formats a duration in seconds like ALB processing times, -1 when negative.
+func seconds(d time.Duration) string {
+	if d < 0 {
+		return "-1"
+	}
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// ALBLogFormat : {type} {time} {elb} {client:port} {target:port} {request_processing_time} {target_processing_time} {response_processing_time} {elb_status_code} {target_status_code} {received_bytes} {sent_bytes} "{request}" "{user_agent}" {ssl_cipher} {ssl_protocol} {target_group_arn} "{trace_id}" "{domain_name}" "{chosen_cert_arn}" {matched_rule_priority} {request_creation_time} "{actions_executed}" "{redirect_url}" "{error_reason}" "{target:port_list}" "{target_status_code_list}" "{classification}" "{classification_reason}" {conn_trace_id}
+const ALBLogFormat = `%s %s %s %s:%d %s %s %s %s %d %s %d %d "%s %s %s" "%s" %s %s %s "%s" "%s" "%s" %d %s "%s" "-" "%s" "%s" "%s" "-" "-" TID_%s`
+
+// ALB renders a request served by the load balancer of region in the ALB access log format.
+// The request time is when the load balancer received it.
+func ALB(r flog.Request, region string) string {
+	lb := "app/shop-alb/" + hexID(16, region, "alb")
+	targetGroup := fmt.Sprintf("arn:aws:elasticloadbalancing:%s:%s:targetgroup/%s/%s", region, AccountID, r.Service(), hexID(16, region, r.Service()))
+	cert := fmt.Sprintf("arn:aws:acm:%s:%s:certificate/%s", region, AccountID, uuid(region, "cert"))
+	id := r.RequestID()
+
+	target, targetStatus, errorReason := r.UpstreamHost(), fmt.Sprint(r.Status), "-"
+	requestTime, targetTime, responseTime := time.Duration(rand.Intn(2))*time.Millisecond, r.Upstream, time.Duration(0)
+	switch {
+	case r.Upstream < 0:
+		// Answered by the load balancer itself.
+		target, targetStatus, targetTime, responseTime = "-", "-", -1, -1
+	case r.Status == 504:
+		targetStatus, targetTime, responseTime, errorReason = "-", -1, -1, "TargetResponseTimeout"
+	case r.Status == 502:
+		targetStatus, targetTime, responseTime, errorReason = "-", -1, -1, "TargetConnectionError"
+	}
+	return fmt.Sprintf(ALBLogFormat, "https", r.Time.Add(r.Duration).UTC().Format("2006-01-02T15:04:05.000000Z"), lb,
+		r.Host, r.ClientPort(), target, seconds(requestTime), seconds(targetTime), seconds(responseTime), r.Status, targetStatus,
+		r.ReceivedBytes()+200, r.Bytes, r.Method, fmt.Sprintf("https://%s:443%s", flog.Authority, r.URI), r.Protocol, r.Agent,
+		"ECDHE-RSA-AES128-GCM-SHA256", "TLSv1.2", targetGroup, traceID(r.Time, id), flog.Authority, cert, 1+int(hash(r.Service())%5),
+		r.Time.UTC().Format("2006-01-02T15:04:05.000000Z"), "forward", errorReason, target, targetStatus, hexID(20, id))
+}
+
+// traceID returns the X-Amzn-Trace-Id of a request.
+func traceID(t time.Time, id string) string {
+	return fmt.Sprintf("Root=1-%08x-%s", t.Unix(), hexID(24, id))
+}
+
+// uuid returns a UUID identifying parts.
+func uuid(parts ...string) string {
+	id := hexID(32, parts...)
+	return id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:]
+}
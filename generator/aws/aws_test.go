@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestALB(t *testing.T) {
+	a := assert.New(t)
+	r := flog.Request{
+		Time: time.Date(2024, 5, 1, 10, 0, 0, 250_000_000, time.UTC), Status: 200,
+		Duration: 1500 * time.Millisecond, Upstream: 1498 * time.Millisecond,
+	}
+	line := ALB(r, "eu-west-1")
+	a.True(strings.HasPrefix(line, "https 2024-05-01T10:00:01.750000Z "), "entries are written when the response is sent")
+	a.Contains(line, " 1.498 0.000 200 200 ")
+
+	r.Status = 504
+	line = ALB(r, "eu-west-1")
+	a.Contains(line, " -1 -1 504 - ")
+	a.Contains(line, `"forward" "-" "TargetResponseTimeout"`)
+}
+
+func TestCloudFront(t *testing.T) {
+	a := assert.New(t)
+	r := flog.Request{Status: 200, Agent: "Mozilla/5.0 (X11; Linux x86_64)", Duration: 1500 * time.Millisecond}
+	fields := strings.Split(CloudFront(r, "eu-west-1"), "\t")
+	require.Len(t, fields, len(CloudFrontFields))
+	values := map[string]string{}
+	for i, name := range CloudFrontFields {
+		values[name] = fields[i]
+	}
+	a.Contains(EdgeLocations["eu-west-1"], values["x-edge-location"])
+	a.Equal("1.500", values["time-taken"])
+	a.NotContains(values["cs(User-Agent)"], " ", "spaces of user agents are encoded")
+}
+
+func TestFlowLogRejected(t *testing.T) {
+	a := assert.New(t)
+	for i := 0; i < 1000; i++ {
+		f := NewFlowLog(time.Now(), "us-east-1")
+		if f.Action == "REJECT" && f.LogStatus == "OK" {
+			a.Equal(log.WARN, f.Level())
+			a.Contains(f.Format(FlowLogFields), " REJECT OK")
+			return
+		}
+	}
+	t.Fatal("no rejected flow")
+}
+
+func TestCloudTrailDenied(t *testing.T) {
+	a := assert.New(t)
+	for i := 0; i < 1000; i++ {
+		e := NewCloudTrailEvent(time.Now(), "us-west-1")
+		if e.ErrorCode == "" {
+			continue
+		}
+		a.Equal(log.WARN, e.Level())
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal([]byte(e.String()), &fields))
+		a.Contains(fields["errorMessage"], "is not authorized to perform: ")
+		return
+	}
+	t.Fatal("no denied event")
+}
+
+func TestLambda(t *testing.T) {
+	a := assert.New(t)
+	f := Functions["order-processor"]
+	f.ColdStart = 1
+	lines := f.Invoke(time.Now(), "eu-west-1", log.INFO)
+	require.Len(t, lines, 5)
+	a.True(strings.HasPrefix(lines[0].Text, "INIT_START "))
+	a.True(strings.HasPrefix(lines[1].Text, "START RequestId: "))
+	a.Contains(lines[4].Text, "\tInit Duration: ")
+	for i := 1; i < len(lines); i++ {
+		a.False(lines[i].Time.Before(lines[i-1].Time), "lines are in time order")
+	}
+}
+
+func TestLambdaTimeout(t *testing.T) {
+	a := assert.New(t)
+	f := Functions["image-resizer"]
+	f.ColdStart = 0
+	start := time.Now()
+	for _, level := range []model.LabelValue{log.CRITICAL, log.FATAL} {
+		lines := f.Invoke(start, "eu-west-1", level)
+		require.Len(t, lines, 4)
+		a.Contains(lines[1].Text, "Task timed out after 30.00 seconds")
+		a.Equal(start.Add(f.Timeout), lines[3].Time, "the invocation ends at the timeout")
+		a.True(strings.HasSuffix(lines[3].Text, "\tStatus: timeout\t"))
+	}
+}
@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	pathpkg "path"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+)
+
+// CloudFrontFields are the fields of CloudFront standard logs, in order.
+var CloudFrontFields = []string{
+	"date", "time", "x-edge-location", "sc-bytes", "c-ip", "cs-method", "cs(Host)", "cs-uri-stem", "sc-status",
+	"cs(Referer)", "cs(User-Agent)", "cs-uri-query", "cs(Cookie)", "x-edge-result-type", "x-edge-request-id",
+	"x-host-header", "cs-protocol", "cs-bytes", "time-taken", "x-forwarded-for", "ssl-protocol", "ssl-cipher",
+	"x-edge-response-result-type", "cs-protocol-version", "fle-status", "fle-encrypted-fields", "c-port",
+	"time-to-first-byte", "x-edge-detailed-result-type", "sc-content-type", "sc-content-len", "sc-range-start",
+	"sc-range-end",
+}
+
+// CloudFrontHeader returns the header lines starting every CloudFront log file.
+func CloudFrontHeader() []string {
+	return []string{"#Version: 1.0", "#Fields: " + strings.Join(CloudFrontFields, " ")}
+}
+
+// EdgeLocations are the CloudFront edge locations serving the clients of each region.
+var EdgeLocations = map[string][]string{
+	"us-west-1": {"SFO5-P1", "SFO53-P2", "LAX50-C1"},
+	"us-east-1": {"IAD89-C1", "IAD12-P1", "JFK50-P3"},
+	"us-east-2": {"ORD58-P4", "CMH68-P2"},
+	"eu-west-1": {"DUB56-P1", "LHR61-P2", "FRA56-P5"},
+}
+
+// staticTypes are the content types of the static files cached by the edge locations.
+var staticTypes = map[string]string{
+	".js":  "application/javascript",
+	".css": "text/css",
+	".png": "image/png",
+	".jpg": "image/jpeg",
+}
+
+// cloudFrontDomain is the domain of the distribution.
+const cloudFrontDomain = "d111111abcdef8.cloudfront.net"
+
+// CloudFront renders a request served by an edge location near region as a line of a CloudFront
+// standard log. Static files are mostly served from the cache, API calls go to the origin.
+func CloudFront(r flog.Request, region string) string {
+	edges := EdgeLocations[region]
+	if len(edges) == 0 {
+		edges = EdgeLocations["us-east-1"]
+	}
+	h := hash(r.RequestID())
+	edge := edges[h%uint64(len(edges))]
+
+	path, query, _ := strings.Cut(r.URI, "?")
+	result, contentType := "Miss", "application/json"
+	switch {
+	case r.Status >= 500:
+		result = "Error"
+	case r.Status == 403 || r.Status == 429:
+		result = "LimitExceeded"
+	case staticTypes[pathpkg.Ext(path)] != "":
+		contentType = staticTypes[pathpkg.Ext(path)]
+		if h%10 < 9 {
+			result = "Hit"
+		}
+	case r.Method == "GET" && h%10 < 2:
+		result = "RefreshHit"
+	}
+	if query == "" {
+		query = "-"
+	}
+	took := r.Duration
+	if result == "Hit" {
+		// Cache hits don't wait for the origin.
+		took = time.Duration(1+h%3) * time.Millisecond
+	}
+	firstByte := took - time.Duration(h%2)*time.Millisecond
+	// Edge request IDs are 56 characters of base64.
+	id := base64.URLEncoding.EncodeToString([]byte(hexID(32, r.RequestID()) + hexID(8, r.RequestID(), edge)))
+
+	fields := []string{
+		r.Time.UTC().Format("2006-01-02"), r.Time.UTC().Format("15:04:05"), edge, fmt.Sprint(r.Bytes + 350), r.Host, r.Method,
+		cloudFrontDomain, path, fmt.Sprint(r.Status), dash(r.Referer), url.PathEscape(r.Agent), query, "-", result, id,
+		flog.Authority, "https", fmt.Sprint(r.ReceivedBytes() + 120), fmt.Sprintf("%.3f", took.Seconds()), "-", "TLSv1.3",
+		"TLS_AES_128_GCM_SHA256", result, r.Protocol, "-", "-", fmt.Sprint(r.ClientPort()),
+		fmt.Sprintf("%.3f", max(firstByte, 0).Seconds()), result, contentType, fmt.Sprint(r.Bytes), "-", "-",
+	}
+	return strings.Join(fields, "\t")
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,302 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// UserIdentity is the identity making an API call.
+type UserIdentity struct {
+	Type           string          `json:"type"`
+	PrincipalID    string          `json:"principalId,omitempty"`
+	ARN            string          `json:"arn,omitempty"`
+	AccountID      string          `json:"accountId,omitempty"`
+	AccessKeyID    string          `json:"accessKeyId,omitempty"`
+	UserName       string          `json:"userName,omitempty"`
+	SessionContext *SessionContext `json:"sessionContext,omitempty"`
+	InvokedBy      string          `json:"invokedBy,omitempty"`
+}
+
+// SessionContext is the session of an assumed role.
+type SessionContext struct {
+	SessionIssuer struct {
+		Type        string `json:"type"`
+		PrincipalID string `json:"principalId"`
+		ARN         string `json:"arn"`
+		AccountID   string `json:"accountId"`
+		UserName    string `json:"userName"`
+	} `json:"sessionIssuer"`
+	Attributes struct {
+		CreationDate     string `json:"creationDate"`
+		MFAAuthenticated string `json:"mfaAuthenticated"`
+	} `json:"attributes"`
+}
+
+// CloudTrailEvent is a CloudTrail record.
+type CloudTrailEvent struct {
+	EventVersion       string         `json:"eventVersion"`
+	UserIdentity       UserIdentity   `json:"userIdentity"`
+	EventTime          string         `json:"eventTime"`
+	EventSource        string         `json:"eventSource"`
+	EventName          string         `json:"eventName"`
+	AWSRegion          string         `json:"awsRegion"`
+	SourceIPAddress    string         `json:"sourceIPAddress"`
+	UserAgent          string         `json:"userAgent"`
+	ErrorCode          string         `json:"errorCode,omitempty"`
+	ErrorMessage       string         `json:"errorMessage,omitempty"`
+	RequestParameters  map[string]any `json:"requestParameters"`
+	ResponseElements   map[string]any `json:"responseElements"`
+	AdditionalData     map[string]any `json:"additionalEventData,omitempty"`
+	RequestID          string         `json:"requestID,omitempty"`
+	EventID            string         `json:"eventID"`
+	ReadOnly           bool           `json:"readOnly"`
+	EventType          string         `json:"eventType"`
+	ManagementEvent    bool           `json:"managementEvent"`
+	RecipientAccountID string         `json:"recipientAccountId"`
+	EventCategory      string         `json:"eventCategory"`
+}
+
+// Level returns the level of the event: warn for denied calls and failed sign-ins, info otherwise.
+func (e CloudTrailEvent) Level() model.LabelValue {
+	if e.ErrorCode != "" {
+		return log.WARN
+	}
+	if e.ResponseElements != nil && e.ResponseElements["ConsoleLogin"] == "Failure" {
+		return log.WARN
+	}
+	return log.INFO
+}
+
+func (e CloudTrailEvent) String() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+// apiCall is an API call made by principals.
+type apiCall struct {
+	source, name string
+	readOnly     bool
+	params       func(region string) map[string]any
+}
+
+// principal is an identity calling AWS APIs.
+type principal struct {
+	identity func(t time.Time) UserIdentity
+	agent    string
+	// ip is empty for AWS services calling on behalf of the account.
+	ip     func() string
+	weight int
+	calls  []apiCall
+	// denied are calls the principal isn't allowed to make.
+	denied []apiCall
+}
+
+func role(name, session string) func(t time.Time) UserIdentity {
+	return func(t time.Time) UserIdentity {
+		id := "AROA" + hexID(17, name)
+		c := &SessionContext{}
+		c.SessionIssuer.Type = "Role"
+		c.SessionIssuer.PrincipalID = id
+		c.SessionIssuer.ARN = fmt.Sprintf("arn:aws:iam::%s:role/%s", AccountID, name)
+		c.SessionIssuer.AccountID = AccountID
+		c.SessionIssuer.UserName = name
+		c.Attributes.CreationDate = t.Add(-time.Duration(t.Minute()) * time.Minute).UTC().Format(time.RFC3339)
+		c.Attributes.MFAAuthenticated = "false"
+		return UserIdentity{
+			Type:           "AssumedRole",
+			PrincipalID:    id + ":" + session,
+			ARN:            fmt.Sprintf("arn:aws:sts::%s:assumed-role/%s/%s", AccountID, name, session),
+			AccountID:      AccountID,
+			AccessKeyID:    "ASIA" + hexID(16, name, session, fmt.Sprint(t.Hour())),
+			SessionContext: c,
+		}
+	}
+}
+
+func user(name string) func(time.Time) UserIdentity {
+	return func(time.Time) UserIdentity {
+		return UserIdentity{
+			Type:        "IAMUser",
+			PrincipalID: "AIDA" + hexID(17, name),
+			ARN:         fmt.Sprintf("arn:aws:iam::%s:user/%s", AccountID, name),
+			AccountID:   AccountID,
+			AccessKeyID: "AKIA" + hexID(16, name, "key"),
+			UserName:    name,
+		}
+	}
+}
+
+func internal() string {
+	return fmt.Sprintf("10.0.%d.%d", rand.Intn(16), 2+rand.Intn(250))
+}
+
+var (
+	getObject = apiCall{"s3.amazonaws.com", "GetObject", true, func(region string) map[string]any {
+		return map[string]any{"bucketName": "shop-assets-" + region, "key": fmt.Sprintf("products/%d.jpg", rand.Intn(5000))}
+	}}
+	putObject = apiCall{"s3.amazonaws.com", "PutObject", false, func(region string) map[string]any {
+		return map[string]any{"bucketName": "shop-invoices-" + region, "key": fmt.Sprintf("invoices/%d.pdf", rand.Intn(1_000_000))}
+	}}
+	getSecret = apiCall{"secretsmanager.amazonaws.com", "GetSecretValue", true, func(region string) map[string]any {
+		return map[string]any{"secretId": fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:prod/db-%s", region, AccountID, hexID(6, region))}
+	}}
+	decrypt = apiCall{"kms.amazonaws.com", "Decrypt", true, func(region string) map[string]any {
+		return map[string]any{"encryptionAlgorithm": "SYMMETRIC_DEFAULT", "keyId": fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", region, AccountID, uuid(region, "kms"))}
+	}}
+	assumeRole = apiCall{"sts.amazonaws.com", "AssumeRoleWithWebIdentity", false, func(string) map[string]any {
+		return map[string]any{"roleArn": fmt.Sprintf("arn:aws:iam::%s:role/checkout-service", AccountID), "roleSessionName": fmt.Sprintf("botocore-session-%d", 1714550000+rand.Intn(1_000_000))}
+	}}
+	describeInstances = apiCall{"ec2.amazonaws.com", "DescribeInstances", true, func(string) map[string]any {
+		return map[string]any{"instancesSet": map[string]any{}, "filterSet": map[string]any{}}
+	}}
+	createTags = apiCall{"ec2.amazonaws.com", "CreateTags", false, func(string) map[string]any {
+		return map[string]any{"resourcesSet": map[string]any{"items": []map[string]string{{"resourceId": "i-" + hexID(17, fmt.Sprint(rand.Intn(20)))}}}}
+	}}
+	setDesiredCapacity = apiCall{"autoscaling.amazonaws.com", "SetDesiredCapacity", false, func(string) map[string]any {
+		return map[string]any{"autoScalingGroupName": "eks-workers", "desiredCapacity": 3 + rand.Intn(6), "honorCooldown": false}
+	}}
+	putParameter = apiCall{"ssm.amazonaws.com", "PutParameter", false, func(string) map[string]any {
+		return map[string]any{"name": "/prod/checkout/feature-flags", "type": "String", "overwrite": true}
+	}}
+	deleteBucket = apiCall{"s3.amazonaws.com", "DeleteBucket", false, func(region string) map[string]any {
+		return map[string]any{"bucketName": "shop-invoices-" + region}
+	}}
+	createAccessKey = apiCall{"iam.amazonaws.com", "CreateAccessKey", false, func(string) map[string]any {
+		return map[string]any{"userName": "deploy-bot"}
+	}}
+)
+
+var principals = []principal{
+	{
+		identity: role("checkout-service", "botocore-session-1714550000"),
+		agent:    "aws-sdk-go-v2/1.30.3 os/linux lang/go#1.22.5 md/GOOS#linux md/GOARCH#amd64 api/s3#1.58.2",
+		ip:       internal,
+		weight:   40,
+		calls:    []apiCall{getObject, putObject, getSecret, decrypt},
+		denied:   []apiCall{deleteBucket},
+	},
+	{
+		identity: role("eks-node-group", "i-0a1b2c3d4e5f67890"),
+		agent:    "aws-sdk-go/1.54.19 (go1.22.5; linux; amd64)",
+		ip:       internal,
+		weight:   25,
+		calls:    []apiCall{describeInstances, createTags, assumeRole},
+	},
+	{
+		identity: role("AWSServiceRoleForAutoScaling", "AutoScaling"),
+		agent:    "autoscaling.amazonaws.com",
+		weight:   10,
+		calls:    []apiCall{setDesiredCapacity, describeInstances},
+	},
+	{
+		identity: user("alice"),
+		agent:    "aws-cli/2.17.20 md/awscrt#0.20.11 ua/2.0 os/macos#23.5.0 md/arch#arm64 lang/python#3.11.9",
+		ip:       flog.FakeIP,
+		weight:   3,
+		calls:    []apiCall{putParameter, describeInstances, getSecret, createAccessKey},
+	},
+	{
+		identity: user("bob"),
+		agent:    "aws-cli/2.15.30 Python/3.11.8 Linux/6.5.0-1022-aws exe/x86_64.ubuntu.22",
+		ip:       flog.FakeIP,
+		weight:   2,
+		calls:    []apiCall{describeInstances, getObject},
+		denied:   []apiCall{createAccessKey, putParameter, deleteBucket},
+	},
+}
+
+// NewCloudTrailEvent generates an event recorded by the trail of region at t: mostly service
+// roles reading and writing their resources, some engineers, their denied calls and their
+// console sign-ins.
+func NewCloudTrailEvent(t time.Time, region string) CloudTrailEvent {
+	e := CloudTrailEvent{
+		EventVersion:       "1.09",
+		EventTime:          t.UTC().Format(time.RFC3339),
+		AWSRegion:          region,
+		EventID:            uuid(fmt.Sprint(t.UnixNano()), fmt.Sprint(rand.Int63())),
+		RequestID:          uuid(fmt.Sprint(t.UnixNano()), "request", fmt.Sprint(rand.Int63())),
+		EventType:          "AwsApiCall",
+		ManagementEvent:    true,
+		RecipientAccountID: AccountID,
+		EventCategory:      "Management",
+	}
+	if rand.Intn(50) == 0 {
+		return consoleLogin(e)
+	}
+	total := 0
+	for _, p := range principals {
+		total += p.weight
+	}
+	r := rand.Intn(total)
+	p := principals[0]
+	for _, candidate := range principals {
+		if r -= candidate.weight; r < 0 {
+			p = candidate
+			break
+		}
+	}
+	call := p.calls[rand.Intn(len(p.calls))]
+	if len(p.denied) > 0 && rand.Intn(10) == 0 {
+		call = p.denied[rand.Intn(len(p.denied))]
+	}
+	e.UserIdentity = p.identity(t)
+	e.EventSource, e.EventName, e.ReadOnly = call.source, call.name, call.readOnly
+	e.RequestParameters = call.params(region)
+	e.UserAgent = p.agent
+	e.SourceIPAddress = p.agent
+	if p.ip != nil {
+		e.SourceIPAddress = p.ip()
+	} else {
+		e.UserIdentity.InvokedBy = p.agent
+	}
+	if call.source == "s3.amazonaws.com" && (call.name == "GetObject" || call.name == "PutObject") {
+		// Object level calls are data events.
+		e.ManagementEvent, e.EventCategory = false, "Data"
+	}
+	for _, denied := range p.denied {
+		if denied.name == call.name {
+			e.ErrorCode = "AccessDenied"
+			e.ErrorMessage = fmt.Sprintf("User: %s is not authorized to perform: %s:%s because no identity-based policy allows the %s:%s action",
+				e.UserIdentity.ARN, service(call.source), call.name, service(call.source), call.name)
+			e.RequestParameters = nil
+		}
+	}
+	return e
+}
+
+// consoleLogin returns the console sign-in of an engineer, some of them failing.
+func consoleLogin(e CloudTrailEvent) CloudTrailEvent {
+	name := []string{"alice", "bob"}[rand.Intn(2)]
+	e.UserIdentity = user(name)(time.Time{})
+	e.UserIdentity.AccessKeyID = ""
+	e.EventSource, e.EventName, e.EventType, e.ReadOnly = "signin.amazonaws.com", "ConsoleLogin", "AwsConsoleSignIn", false
+	e.AWSRegion = "us-east-1"
+	e.SourceIPAddress = flog.FakeIP()
+	e.UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+	e.RequestID = ""
+	result := "Success"
+	if rand.Intn(5) == 0 {
+		result = "Failure"
+		e.ErrorMessage = "Failed authentication"
+	}
+	e.ResponseElements = map[string]any{"ConsoleLogin": result}
+	e.AdditionalData = map[string]any{
+		"LoginTo":       "https://console.aws.amazon.com/console/home",
+		"MobileVersion": "No",
+		"MFAUsed":       map[bool]string{true: "Yes", false: "No"}[name == "alice"],
+	}
+	return e
+}
+
+// service returns the IAM prefix of an event source, e.g. s3 for s3.amazonaws.com.
+func service(source string) string {
+	prefix, _, _ := strings.Cut(source, ".")
+	return prefix
+}
@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// Function is a Lambda function. Each invocation logs START, the lines of the handler, END and
+// REPORT with the billed duration and the memory used.
+type Function struct {
+	Name string
+	// Runtime is the runtime of the function, e.g. nodejs20.x or python3.12.
+	Runtime string
+	// MemoryMB is the memory size, Timeout the time after which invocations are stopped.
+	MemoryMB int
+	Timeout  time.Duration
+	// Duration is the distribution of the durations of the invocations in seconds.
+	Duration dist.Distribution
+	// Messages are logged by the handler, one of them per invocation.
+	Messages []string
+	// ColdStart is the share of invocations starting a new execution environment.
+	ColdStart float64
+}
+
+// Invoke returns the lines of an invocation in region started at t, the last one is logged when
+// the invocation ends. Failing invocations throw an error or time out.
+func (f Function) Invoke(t time.Time, region string, level model.LabelValue) []log.Line {
+	id := uuid(f.Name, fmt.Sprint(t.UnixNano()), fmt.Sprint(rand.Int63()))
+	var lines []log.Line
+	add := func(at time.Time, level model.LabelValue, text string) {
+		lines = append(lines, log.Line{Time: at, Level: level, Text: text})
+	}
+
+	var init time.Duration
+	if rand.Float64() < f.ColdStart {
+		init = time.Duration(dist.Between(dist.LogNormal{Median: 0.25, Sigma: 0.4}, 0.05, 10) * float64(time.Second))
+		add(t, log.INFO, fmt.Sprintf("INIT_START Runtime Version: %s.v%d\tRuntime Version ARN: arn:aws:lambda:%s::runtime:%s",
+			runtimeVersion(f.Runtime), 20+len(f.Name)%15, region, hexID(32, f.Runtime)+hexID(32, f.Runtime, "arn")))
+		t = t.Add(init)
+	}
+	add(t, log.INFO, fmt.Sprintf("START RequestId: %s Version: $LATEST", id))
+
+	d := time.Duration(dist.Between(f.Duration, 0.001, f.Timeout.Seconds()) * float64(time.Second))
+	status := ""
+	switch level {
+	case log.ERROR:
+		add(t.Add(d), log.ERROR, f.errorLine(t.Add(d), id))
+	case log.CRITICAL, log.FATAL:
+		d, status = f.Timeout, "timeout"
+	default:
+		add(t.Add(d/2), level, fmt.Sprintf("%s\t%s\t%s\t%s", t.Add(d/2).UTC().Format("2006-01-02T15:04:05.000Z"), id, levelName(level), f.Messages[rand.Intn(len(f.Messages))]))
+	}
+	end := t.Add(d)
+	if status == "timeout" {
+		add(end, log.ERROR, fmt.Sprintf("%s %s Task timed out after %.2f seconds", end.UTC().Format("2006-01-02T15:04:05.000Z"), id, f.Timeout.Seconds()))
+	}
+	add(end, log.INFO, "END RequestId: "+id)
+
+	// Durations are billed by the millisecond, along with the initialization.
+	ms := float64(d.Microseconds()) / 1000
+	billed := int(math.Ceil(ms + float64(init.Microseconds())/1000))
+	used := int(float64(f.MemoryMB) * (0.3 + 0.5*rand.Float64()))
+	report := fmt.Sprintf("REPORT RequestId: %s\tDuration: %.2f ms\tBilled Duration: %d ms\tMemory Size: %d MB\tMax Memory Used: %d MB", id, ms, billed, f.MemoryMB, used)
+	if init > 0 {
+		report += fmt.Sprintf("\tInit Duration: %.2f ms", float64(init.Microseconds())/1000)
+	}
+	reportLevel := log.INFO
+	if status != "" {
+		report += "\tStatus: " + status
+		reportLevel = log.ERROR
+	}
+	add(end, reportLevel, report+"\t")
+	return lines
+}
+
+// errorLine returns the line of the handler throwing an error, in the format of the runtime.
+func (f Function) errorLine(t time.Time, id string) string {
+	message := log.RandServiceError(f.Name)
+	if strings.HasPrefix(f.Runtime, "python") {
+		return fmt.Sprintf("[ERROR] RuntimeError: %s\nTraceback (most recent call last):\n  File \"/var/task/handler.py\", line 31, in handler\n    raise RuntimeError(message)", message)
+	}
+	b, _ := json.Marshal(map[string]any{
+		"errorType": "Error", "errorMessage": message,
+		"stack": []string{"Error: " + message, "    at Runtime.handler (file:///var/task/index.mjs:27:11)", "    at Runtime.handleOnceNonStreaming (file:///var/runtime/index.mjs:1173:29)"},
+	})
+	return fmt.Sprintf("%s\t%s\tERROR\tInvoke Error \t%s", t.UTC().Format("2006-01-02T15:04:05.000Z"), id, b)
+}
+
+// runtimeVersion returns the name of the runtime in its version, e.g. nodejs:20 for nodejs20.x.
+func runtimeVersion(runtime string) string {
+	runtime = strings.TrimSuffix(runtime, ".x")
+	if i := strings.IndexAny(runtime, "0123456789"); i > 0 {
+		return runtime[:i] + ":" + runtime[i:]
+	}
+	return runtime
+}
+
+// levelName returns the name of a level as logged by the runtimes.
+func levelName(level model.LabelValue) string {
+	switch level {
+	case log.WARN:
+		return "WARN"
+	case log.DEBUG:
+		return "DEBUG"
+	case log.TRACE:
+		return "TRACE"
+	}
+	return "INFO"
+}
+
+// Functions are the generated Lambda functions.
+var Functions = map[string]Function{
+	"order-processor": {
+		Name: "order-processor", Runtime: "nodejs20.x", MemoryMB: 256, Timeout: 6 * time.Second,
+		Duration:  dist.LogNormal{Median: 0.12, Sigma: 0.8},
+		ColdStart: 0.05,
+		Messages:  []string{"Processing order", "Order saved to DynamoDB", "Published OrderCreated event to EventBridge", "Retrying conditional write"},
+	},
+	"image-resizer": {
+		Name: "image-resizer", Runtime: "python3.12", MemoryMB: 1024, Timeout: 30 * time.Second,
+		Duration:  dist.LogNormal{Median: 1.5, Sigma: 0.6},
+		ColdStart: 0.1,
+		Messages:  []string{"Resizing s3://shop-assets/uploads/product.jpg to 3 sizes", "Thumbnail written to s3://shop-assets/thumbnails/", "Skipping already resized image"},
+	},
+}
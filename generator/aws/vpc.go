@@ -0,0 +1,191 @@
+package aws
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// FlowLog is a VPC flow log record: the traffic of a network interface between two addresses
+// during an aggregation interval.
+type FlowLog struct {
+	AccountID   string
+	InterfaceID string
+	SrcAddr     string
+	DstAddr     string
+	SrcPort     int
+	DstPort     int
+	// Protocol is the IANA protocol number: 6 for TCP, 17 for UDP, 1 for ICMP.
+	Protocol   int
+	Packets    int
+	Bytes      int
+	Start, End time.Time
+	// Action is ACCEPT or REJECT.
+	Action string
+	// LogStatus is OK, NODATA when the interface had no traffic or SKIPDATA when records were skipped.
+	LogStatus string
+	VPCID     string
+	SubnetID  string
+	TCPFlags  int
+	Region    string
+	AZID      string
+	// FlowDirection is ingress or egress.
+	FlowDirection string
+}
+
+// FlowLogFields are the fields of the default format of version 2.
+var FlowLogFields = []string{"version", "account-id", "interface-id", "srcaddr", "dstaddr", "srcport", "dstport", "protocol", "packets", "bytes", "start", "end", "action", "log-status"}
+
+// FlowLogV5Fields are the fields of a custom format using fields up to version 5.
+var FlowLogV5Fields = []string{"version", "vpc-id", "subnet-id", "interface-id", "account-id", "type", "srcaddr", "dstaddr", "srcport", "dstport", "protocol", "packets", "bytes", "start", "end", "action", "tcp-flags", "log-status", "region", "az-id", "flow-direction"}
+
+// flowLogVersion returns the version of a format, the highest version of its fields.
+func flowLogVersion(fields []string) int {
+	for _, field := range fields {
+		if !slices.Contains(FlowLogFields, field) {
+			return 5
+		}
+	}
+	return 2
+}
+
+// Format renders the record with fields, missing values are written as -.
+func (f FlowLog) Format(fields []string) string {
+	noData := f.LogStatus != "OK"
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		var v any
+		switch field {
+		case "version":
+			v = flowLogVersion(fields)
+		case "account-id":
+			v = f.AccountID
+		case "interface-id":
+			v = f.InterfaceID
+		case "start":
+			v = f.Start.Unix()
+		case "end":
+			v = f.End.Unix()
+		case "log-status":
+			v = f.LogStatus
+		case "vpc-id":
+			v = f.VPCID
+		case "subnet-id":
+			v = f.SubnetID
+		case "region":
+			v = f.Region
+		case "az-id":
+			v = f.AZID
+		}
+		if v == nil && !noData {
+			switch field {
+			case "srcaddr":
+				v = f.SrcAddr
+			case "dstaddr":
+				v = f.DstAddr
+			case "srcport":
+				v = f.SrcPort
+			case "dstport":
+				v = f.DstPort
+			case "protocol":
+				v = f.Protocol
+			case "packets":
+				v = f.Packets
+			case "bytes":
+				v = f.Bytes
+			case "action":
+				v = f.Action
+			case "tcp-flags":
+				v = f.TCPFlags
+			case "type":
+				v = "IPv4"
+			case "flow-direction":
+				v = f.FlowDirection
+			}
+		}
+		if v == nil {
+			v = "-"
+		}
+		values[i] = fmt.Sprint(v)
+	}
+	return strings.Join(values, " ")
+}
+
+// Level returns the level of the record, warn for rejected traffic.
+func (f FlowLog) Level() model.LabelValue {
+	if f.Action == "REJECT" && f.LogStatus == "OK" {
+		return log.WARN
+	}
+	return log.INFO
+}
+
+// flowInterval is the maximum aggregation interval of the flow logs.
+const flowInterval = time.Minute
+
+// NewFlowLog generates a record of the traffic of the VPC of region ending at t. Most records are
+// accepted TCP traffic between the services and their clients or databases, some are probes
+// from the internet rejected by the security groups.
+func NewFlowLog(t time.Time, region string) FlowLog {
+	az := fmt.Sprintf("%s-az%d", azPrefix(region), 1+rand.Intn(3))
+	f := FlowLog{
+		AccountID:   AccountID,
+		InterfaceID: "eni-" + hexID(17, region, fmt.Sprint(rand.Intn(12))),
+		Start:       t.Add(-flowInterval + time.Duration(rand.Intn(10))*time.Second),
+		End:         t,
+		Action:      "ACCEPT",
+		LogStatus:   "OK",
+		VPCID:       "vpc-" + hexID(17, region, "vpc"),
+		SubnetID:    "subnet-" + hexID(17, region, az),
+		Protocol:    6,
+		Region:      region,
+		AZID:        az,
+		// SYN, SYN-ACK and FIN.
+		TCPFlags:      19,
+		FlowDirection: "ingress",
+	}
+	local := fmt.Sprintf("10.0.%d.%d", rand.Intn(16), 2+rand.Intn(250))
+	switch r := rand.Float64(); {
+	case r < 0.02:
+		f.LogStatus = "NODATA"
+	case r < 0.15:
+		// A scan of a well known port, dropped by the security group.
+		f.SrcAddr, f.DstAddr = flog.FakeIP(), local
+		f.SrcPort, f.DstPort = 1024+rand.Intn(64000), []int{22, 23, 3389, 445, 1433, 3306, 5432, 6379}[rand.Intn(8)]
+		f.Packets, f.Bytes, f.Action, f.TCPFlags = 1, 40+rand.Intn(20), "REJECT", 2
+	case r < 0.2:
+		// DNS lookups to the VPC resolver.
+		f.SrcAddr, f.DstAddr = local, "10.0.0.2"
+		f.SrcPort, f.DstPort, f.Protocol = 1024+rand.Intn(64000), 53, 17
+		f.Packets = 1 + rand.Intn(10)
+		f.Bytes, f.TCPFlags, f.FlowDirection = f.Packets*(60+rand.Intn(60)), 0, "egress"
+	case r < 0.6:
+		// Clients of the load balancer.
+		f.SrcAddr, f.DstAddr = flog.FakeIP(), local
+		f.SrcPort, f.DstPort = 1024+rand.Intn(64000), 443
+		f.Packets = 5 + rand.Intn(200)
+		f.Bytes = f.Packets * (100 + rand.Intn(1300))
+	default:
+		// Services talking to their databases and caches.
+		f.SrcAddr, f.DstAddr = local, fmt.Sprintf("10.0.%d.%d", 100+rand.Intn(4), 10+rand.Intn(20))
+		f.SrcPort, f.DstPort = 32768+rand.Intn(28000), []int{5432, 3306, 27017, 6379, 9092}[rand.Intn(5)]
+		f.Packets = 2 + rand.Intn(2000)
+		f.Bytes = f.Packets * (80 + rand.Intn(1400))
+		f.FlowDirection = "egress"
+	}
+	return f
+}
+
+// azPrefix returns the prefix of the availability zone IDs of a region, e.g. use1 for us-east-1.
+func azPrefix(region string) string {
+	parts := strings.Split(region, "-")
+	if len(parts) != 3 {
+		return region
+	}
+	return parts[0] + string(parts[1][0]) + parts[2]
+}
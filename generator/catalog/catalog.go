@@ -0,0 +1,246 @@
+// Package catalog keeps the recurring errors of services. Like in real systems, a few errors
+// make up most of the failures, new errors appear from time to time and old ones fade out once
+// fixed. Every error has a stable message, code, status and stack trace, so the errors of a
+// service can be grouped and ranked.
+package catalog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/stacktrace"
+)
+
+// Error is a recurring error of a service.
+type Error struct {
+	// ID fingerprints the error, it names its stack traces.
+	ID      string
+	Message string
+	// Code is an application error code like E4012, Status the HTTP status of the failed requests.
+	Code   string
+	Status int
+	// Since is when the error appeared, Until when it is gone, never when zero.
+	Since, Until time.Time
+}
+
+// Trace returns the stack trace of the error in a service written in lang.
+func (e Error) Trace(lang stacktrace.Language, service string) stacktrace.Trace {
+	return stacktrace.New(lang, service, e.ID)
+}
+
+// Options configures a catalog.
+type Options struct {
+	// Size is the number of errors at start, 8 by default.
+	Size int
+	// Chronic is the number of top errors that never go away, 2 by default.
+	Chronic int
+	// S is the exponent of the Zipf distribution of the errors by rank, 1.3 by default.
+	S float64
+	// NewPerHour is the mean number of new errors per hour, 0.5 by default.
+	NewPerHour float64
+	// Lifetime is the time errors last in seconds, a log-normal with a median of 12h by default.
+	Lifetime dist.Distribution
+	// Fade is the time it takes for new errors to reach their rate and for old ones to go away,
+	// 30m by default.
+	Fade time.Duration
+}
+
+func (o *Options) defaults() {
+	if o.Size <= 0 {
+		o.Size = 8
+	}
+	if o.Chronic <= 0 {
+		o.Chronic = min(2, o.Size)
+	}
+	if o.S <= 0 {
+		o.S = 1.3
+	}
+	if o.NewPerHour <= 0 {
+		o.NewPerHour = 0.5
+	}
+	if o.Lifetime == nil {
+		o.Lifetime = dist.LogNormal{Median: 12 * 3600, Sigma: 0.8}
+	}
+	if o.Fade <= 0 {
+		o.Fade = 30 * time.Minute
+	}
+}
+
+// step is the resolution at which errors appear.
+const step = time.Minute
+
+// maxSteps bounds the work of a single evolution, e.g. after a long pause.
+const maxSteps = 7 * 24 * 60
+
+// Catalog is the errors of a service. It is safe for concurrent use.
+type Catalog struct {
+	service string
+	opts    Options
+
+	mtx   sync.Mutex
+	rnd   *rand.Rand
+	faker *gofakeit.Faker
+	// errors are sorted by rank, the first one is the most frequent.
+	errors []Error
+	count  int
+	last   time.Time
+}
+
+// New creates the catalog of a service started at start. The same service starts with the same
+// errors.
+func New(service string, start time.Time, opts Options) *Catalog {
+	opts.defaults()
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(service))
+	seed := h.Sum64()
+	c := &Catalog{
+		service: service,
+		opts:    opts,
+		rnd:     rand.New(rand.NewSource(int64(seed))),
+		faker:   gofakeit.New(seed),
+		last:    start,
+	}
+	for i := 0; i < opts.Size; i++ {
+		// The errors of the catalog were there before it started.
+		e := c.newError(start.Add(-opts.Fade - time.Duration(c.rnd.Intn(24))*time.Hour))
+		if i < opts.Chronic {
+			e.Until = time.Time{}
+		} else {
+			// They already lived part of their lifetime.
+			e.Until = start.Add(time.Duration(c.rnd.Float64() * float64(e.Until.Sub(e.Since))))
+		}
+		c.errors = append(c.errors, e)
+	}
+	return c
+}
+
+var (
+	mtx      sync.Mutex
+	catalogs = map[string]*Catalog{}
+)
+
+// For returns the catalog of a service, created on first use with the default options.
+func For(service string, t time.Time) *Catalog {
+	mtx.Lock()
+	defer mtx.Unlock()
+	c, ok := catalogs[service]
+	if !ok {
+		c = New(service, t, Options{})
+		catalogs[service] = c
+	}
+	return c
+}
+
+// Pick returns a random error at t, following the Zipf distribution of the errors by rank.
+func (c *Catalog) Pick(t time.Time) Error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.evolve(t)
+	var total float64
+	weights := make([]float64, len(c.errors))
+	for i, e := range c.errors {
+		weights[i] = c.weight(i, e, t)
+		total += weights[i]
+	}
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r -= w; r < 0 {
+			return c.errors[i]
+		}
+	}
+	return c.errors[len(c.errors)-1]
+}
+
+// Errors returns the errors at t by rank.
+func (c *Catalog) Errors(t time.Time) []Error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.evolve(t)
+	return append([]Error(nil), c.errors...)
+}
+
+// weight returns how often an error happens at t compared to the others.
+func (c *Catalog) weight(rank int, e Error, t time.Time) float64 {
+	w := 1 / math.Pow(float64(rank+1), c.opts.S)
+	fade := float64(c.opts.Fade)
+	if age := float64(t.Sub(e.Since)); age < fade {
+		w *= math.Max(age/fade, 0.05)
+	}
+	if !e.Until.IsZero() {
+		if left := float64(e.Until.Sub(t)); left < fade {
+			w *= math.Max(left/fade, 0.05)
+		}
+	}
+	return w
+}
+
+// evolve adds the errors appearing and removes the errors gone since the previous call.
+func (c *Catalog) evolve(t time.Time) {
+	steps := int(t.Sub(c.last) / step)
+	if steps <= 0 {
+		return
+	}
+	from := c.last
+	if steps > maxSteps {
+		from = t.Add(-maxSteps * step)
+		steps = maxSteps
+	}
+	c.last = c.last.Add(time.Duration(int(t.Sub(c.last)/step)) * step)
+	p := c.opts.NewPerHour * step.Hours()
+	for i := 1; i <= steps; i++ {
+		if c.rnd.Float64() < p {
+			// New errors don't take over the chronic ones right away.
+			e := c.newError(from.Add(time.Duration(i) * step))
+			rank := min(c.opts.Chronic, len(c.errors)) + c.rnd.Intn(len(c.errors)-min(c.opts.Chronic, len(c.errors))+1)
+			c.errors = append(c.errors[:rank], append([]Error{e}, c.errors[rank:]...)...)
+		}
+	}
+	active := c.errors[:0]
+	for _, e := range c.errors {
+		if e.Until.IsZero() || e.Until.After(t) {
+			active = append(active, e)
+		}
+	}
+	c.errors = active
+	if len(c.errors) == 0 {
+		c.errors = append(c.errors, c.newError(t))
+	}
+}
+
+// newError creates an error appearing at since.
+func (c *Catalog) newError(since time.Time) Error {
+	c.count++
+	var (
+		err      error
+		statuses []int
+	)
+	switch c.rnd.Intn(6) {
+	case 0:
+		err, statuses = c.faker.ErrorDatabase(), []int{500, 503}
+	case 1:
+		err, statuses = c.faker.ErrorGRPC(), []int{502, 504}
+	case 2:
+		err, statuses = c.faker.ErrorHTTPServer(), []int{500, 502, 503, 504}
+	case 3:
+		err, statuses = c.faker.ErrorRuntime(), []int{500}
+	case 4:
+		err, statuses = c.faker.ErrorValidation(), []int{400, 422}
+	default:
+		err, statuses = c.faker.ErrorObject(), []int{500}
+	}
+	lifetime := time.Duration(math.Max(dist.SampleFrom(c.opts.Lifetime, c.rnd), 60) * float64(time.Second))
+	return Error{
+		ID:      fmt.Sprintf("%s-%d", c.service, c.count),
+		Message: err.Error(),
+		Code:    fmt.Sprintf("E%d", 1000+c.rnd.Intn(9000)),
+		Status:  statuses[c.rnd.Intn(len(statuses))],
+		Since:   since,
+		Until:   since.Add(lifetime),
+	}
+}
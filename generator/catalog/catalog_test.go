@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestNew(t *testing.T) {
+	a, b := New("checkout", start, Options{}), New("checkout", start, Options{})
+	ea, eb := a.Errors(start), b.Errors(start)
+	require.Len(t, ea, 8)
+	for i := range ea {
+		assert.Equal(t, ea[i].ID, eb[i].ID)
+		assert.Equal(t, ea[i].Message, eb[i].Message)
+		assert.Equal(t, ea[i].Code, eb[i].Code)
+		assert.Equal(t, ea[i].Status, eb[i].Status)
+		assert.Equal(t, ea[i].Since, eb[i].Since)
+		assert.Equal(t, ea[i].Until, eb[i].Until, "lifetimes are seeded too")
+		assert.Regexp(t, `^E\d{4}$`, ea[i].Code)
+		assert.True(t, ea[i].Since.Before(start))
+	}
+	assert.True(t, ea[0].Until.IsZero())
+	assert.True(t, ea[1].Until.IsZero())
+	assert.False(t, ea[2].Until.IsZero())
+
+	other := New("cart", start, Options{}).Errors(start)
+	assert.NotEqual(t, ea[0].Message, other[0].Message)
+}
+
+func TestPickFollowsRanks(t *testing.T) {
+	c := New("checkout", start, Options{NewPerHour: 1e-9})
+	errors := c.Errors(start)
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[c.Pick(start).ID]++
+	}
+	// The top errors make up most of the picks.
+	assert.Greater(t, counts[errors[0].ID], counts[errors[1].ID])
+	assert.Greater(t, counts[errors[0].ID]+counts[errors[1].ID], 5000)
+	assert.Greater(t, counts[errors[1].ID], counts[errors[len(errors)-1].ID])
+}
+
+func TestEvolve(t *testing.T) {
+	c := New("checkout", start, Options{NewPerHour: 2})
+	initial := c.Errors(start)
+	later := start.Add(48 * time.Hour)
+	errors := c.Errors(later)
+
+	ids := map[string]bool{}
+	for _, e := range errors {
+		ids[e.ID] = true
+		assert.True(t, e.Until.IsZero() || e.Until.After(later), e.ID)
+	}
+	// The chronic errors are still there, most of the others are gone and new ones appeared.
+	assert.Equal(t, initial[0].ID, errors[0].ID)
+	assert.Equal(t, initial[1].ID, errors[1].ID)
+	var kept int
+	for _, e := range initial[2:] {
+		if ids[e.ID] {
+			kept++
+		}
+	}
+	assert.Less(t, kept, len(initial)-2)
+	var added int
+	for _, e := range errors {
+		if e.Since.After(start) {
+			added++
+		}
+	}
+	assert.Greater(t, added, 0)
+}
+
+func TestWeightFades(t *testing.T) {
+	c := New("checkout", start, Options{})
+	e := Error{Since: start, Until: start.Add(2 * time.Hour)}
+	full := c.weight(3, e, start.Add(time.Hour))
+	assert.Less(t, c.weight(3, e, start.Add(time.Minute)), full)
+	assert.Less(t, c.weight(3, e, start.Add(2*time.Hour-time.Minute)), full)
+	assert.Greater(t, c.weight(0, e, start.Add(time.Hour)), full)
+}
+
+func TestFor(t *testing.T) {
+	assert.Same(t, For("checkout", start), For("checkout", start.Add(time.Hour)))
+	assert.NotSame(t, For("checkout", start), For("cart", start))
+}
+
+func TestTrace(t *testing.T) {
+	e := New("checkout", start, Options{}).Errors(start)[0]
+	a, b := e.Trace(stacktrace.Java, "checkout"), e.Trace(stacktrace.Java, "checkout")
+	assert.Equal(t, a.Type, b.Type)
+	assert.Equal(t, a.Message, b.Message)
+}
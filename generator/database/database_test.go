@@ -0,0 +1,81 @@
+package database
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	a := assert.New(t)
+	for i := 0; i < 100; i++ {
+		q := NewQuery(time.Now())
+		a.True(strings.HasPrefix(q.SQL(), q.Kind), q.SQL())
+		a.LessOrEqual(q.Sent, max(q.Examined, 1))
+		a.LessOrEqual(q.Examined, q.Table.Rows)
+	}
+}
+
+func TestQueryDurationField(t *testing.T) {
+	a := assert.New(t)
+	dist.SetField("query", dist.Normal{Mean: 2, StdDev: 0})
+	defer dist.UnsetField("query")
+	q := NewQuery(time.Now())
+	a.Equal(2*time.Second, q.Duration)
+	a.Contains(MySQLSlow(q), "# Query_time: 2.000000")
+}
+
+func TestPostgresDeadlock(t *testing.T) {
+	a := assert.New(t)
+	q := NewQuery(time.Now())
+	lines := strings.Split(postgresDeadlock(time.Now(), 1234, q), "\n")
+	require.Len(t, lines, 8)
+	a.Contains(lines[0], "[1234] "+q.User+"@shop ERROR:  deadlock detected")
+	a.True(strings.HasPrefix(lines[2], "\tProcess "))
+	a.Contains(lines[7], "STATEMENT:  UPDATE "+q.Table.Name)
+}
+
+func TestMySQLSlow(t *testing.T) {
+	a := assert.New(t)
+	q := NewQuery(time.Date(2024, 5, 1, 12, 0, 0, 123_000_000, time.UTC))
+	lines := strings.Split(MySQLSlow(q), "\n")
+	a.Equal("# Time: 2024-05-01T12:00:00.123000Z", lines[0])
+	a.Equal("SET timestamp=1714564800;", lines[len(lines)-2])
+	a.NotContains(lines[len(lines)-1], "$1", "placeholders are the ones of MySQL")
+}
+
+func TestFatal(t *testing.T) {
+	a := assert.New(t)
+	for _, level := range []model.LabelValue{log.CRITICAL, log.FATAL} {
+		a.Contains(Postgres(time.Now(), level), " FATAL:  ")
+		a.Contains(MySQL(time.Now(), level), " [ERROR] ")
+		a.Contains(Mongo(time.Now(), level), `"s":"F"`)
+	}
+}
+
+func TestMySQLNotes(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.NotContains(t, MySQL(time.Now(), log.ERROR), "[Note]", "notes are not errors")
+	}
+}
+
+func TestMongoSlowQuery(t *testing.T) {
+	a := assert.New(t)
+	q := NewQuery(time.Now())
+	q.Kind, q.Indexed, q.Duration = "SELECT", false, 1500*time.Millisecond
+	var e struct {
+		Msg  string
+		Attr map[string]any
+	}
+	require.NoError(t, json.Unmarshal([]byte(MongoSlowQuery(q).String()), &e))
+	a.Equal("Slow query", e.Msg)
+	a.Equal("COLLSCAN", e.Attr["planSummary"])
+	a.Equal(float64(1500), e.Attr["durationMillis"])
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// MongoEntry is a MongoDB structured log entry.
+type MongoEntry struct {
+	T struct {
+		Date string `json:"$date"`
+	} `json:"t"`
+	// S is the severity: F, E, W, I or D1 to D5.
+	S         string         `json:"s"`
+	Component string         `json:"c"`
+	ID        int            `json:"id"`
+	Ctx       string         `json:"ctx"`
+	Msg       string         `json:"msg"`
+	Attr      map[string]any `json:"attr,omitempty"`
+}
+
+func (e MongoEntry) String() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+func newMongoEntry(t time.Time, severity, component string, id int, ctx, msg string, attr map[string]any) MongoEntry {
+	e := MongoEntry{S: severity, Component: component, ID: id, Ctx: ctx, Msg: msg, Attr: attr}
+	e.T.Date = t.UTC().Format("2006-01-02T15:04:05.000-07:00")
+	return e
+}
+
+// MongoSlowQuery returns the "Slow query" entry of a query.
+func MongoSlowQuery(q Query) MongoEntry {
+	ns := Database + "." + q.Table.Name
+	var command map[string]any
+	filter := map[string]any{q.Table.Columns[1]: q.id}
+	switch q.Kind {
+	case "INSERT":
+		command = map[string]any{"insert": q.Table.Name, "ordered": true, "$db": Database}
+	case "UPDATE":
+		command = map[string]any{"update": q.Table.Name, "ordered": true, "$db": Database}
+	case "DELETE":
+		command = map[string]any{"delete": q.Table.Name, "ordered": true, "$db": Database}
+	default:
+		command = map[string]any{"find": q.Table.Name, "filter": filter, "limit": max(q.Sent, 1), "$db": Database}
+	}
+	plan := fmt.Sprintf("IXSCAN { %s: 1 }", q.Table.Columns[1])
+	keys := q.Examined
+	if !q.Indexed {
+		plan, keys = "COLLSCAN", 0
+	}
+	attr := map[string]any{
+		"type":           "command",
+		"ns":             ns,
+		"appName":        q.User,
+		"command":        command,
+		"planSummary":    plan,
+		"keysExamined":   keys,
+		"docsExamined":   q.Examined,
+		"numYields":      q.Examined / 1000,
+		"nreturned":      q.Sent,
+		"reslen":         200 + q.Sent*180,
+		"protocol":       "op_msg",
+		"durationMillis": q.Duration.Milliseconds(),
+	}
+	if q.Kind != "SELECT" {
+		delete(attr, "nreturned")
+		attr["nModified"] = q.Sent
+	}
+	return newMongoEntry(q.Time, "I", "COMMAND", 51803, fmt.Sprintf("conn%d", pid(q.User)), "Slow query", attr)
+}
+
+// Mongo returns a MongoDB log entry at the level.
+func Mongo(t time.Time, level model.LabelValue) string {
+	q := NewQuery(t)
+	ctx := fmt.Sprintf("conn%d", pid(q.User))
+	switch level {
+	case log.CRITICAL, log.FATAL:
+		return newMongoEntry(t, "F", "STORAGE", 28595, "Checkpointer", "Terminating.",
+			map[string]any{"reason": "28: No space left on device"}).String()
+	case log.ERROR:
+		if rand.Intn(2) == 0 {
+			return newMongoEntry(t, "E", "QUERY", 4615610, ctx, "Plan executor error during find command", map[string]any{
+				"error": map[string]any{"code": 292, "codeName": "QueryExceededMemoryLimitNoDiskUseAllowed",
+					"errmsg": "Executor error during find command :: caused by :: Sort exceeded memory limit of 104857600 bytes, but did not opt in to external sorting."},
+				"stats": map[string]any{"stage": "SORT", "nReturned": 0, "docsExamined": q.Examined},
+				"cmd":   map[string]any{"find": q.Table.Name, "sort": map[string]any{q.Table.Columns[len(q.Table.Columns)-1]: -1}},
+			}).String()
+		}
+		return newMongoEntry(t, "E", "WRITE", 20883, ctx, "Write error", map[string]any{
+			"ns":    Database + "." + q.Table.Name,
+			"error": map[string]any{"code": 11000, "codeName": "DuplicateKey", "errmsg": fmt.Sprintf("E11000 duplicate key error collection: %s.%s index: _id_ dup key: { _id: %d }", Database, q.Table.Name, q.id)},
+		}).String()
+	case log.WARN:
+		if rand.Intn(3) == 0 {
+			return newMongoEntry(t, "W", "REPL", 22225, "FlowControlRefresher", "Flow control is engaged and the sustainer point is not moving. Please check the health of all secondaries.", nil).String()
+		}
+		// A collection scan.
+		q.Kind, q.Indexed, q.Examined = "SELECT", false, q.Table.Rows
+		q.Duration += time.Second
+		return MongoSlowQuery(q).String()
+	case log.DEBUG, log.TRACE:
+		return newMongoEntry(t, "D1", "QUERY", 20967, ctx, "Beginning planning", map[string]any{"options": "INDEX_INTERSECTION", "query": map[string]any{q.Table.Columns[1]: q.id}}).String()
+	}
+	connection := map[string]any{
+		"remote":          fmt.Sprintf("%s:%d", q.ClientIP(), 30000+rand.Intn(30000)),
+		"uuid":            log.PodUID("", q.User, fmt.Sprint(q.id)),
+		"connectionId":    pid(q.User),
+		"connectionCount": 20 + rand.Intn(80),
+	}
+	switch r := rand.Intn(10); {
+	case r < 6:
+		// slowms: 0 logs every operation with its duration.
+		return MongoSlowQuery(q).String()
+	case r < 8:
+		return newMongoEntry(t, "I", "NETWORK", 22943, "listener", "Connection accepted", connection).String()
+	}
+	return newMongoEntry(t, "I", "NETWORK", 22944, ctx, "Connection ended", connection).String()
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// MySQLSlow returns an entry of the MySQL slow query log, its header comments followed by the
+// statement.
+func MySQLSlow(q Query) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Time: %s\n", q.Time.UTC().Format("2006-01-02T15:04:05.000000Z"))
+	fmt.Fprintf(&b, "# User@Host: %s[%s] @  [%s]  Id: %6d\n", q.User, q.User, q.ClientIP(), pid(q.User))
+	lock := time.Duration(rand.Intn(200)) * time.Microsecond
+	if q.Kind != "SELECT" {
+		// Writes wait for row locks.
+		lock += time.Duration(rand.Float64() * float64(q.Duration) / 4)
+	}
+	fmt.Fprintf(&b, "# Query_time: %.6f  Lock_time: %.6f Rows_sent: %d  Rows_examined: %d\n", q.Duration.Seconds(), lock.Seconds(), q.Sent, q.Examined)
+	if rand.Intn(20) == 0 {
+		fmt.Fprintf(&b, "use %s;\n", Database)
+	}
+	fmt.Fprintf(&b, "SET timestamp=%d;\n", q.Time.Unix())
+	b.WriteString(mysqlSQL(q.SQL()) + ";")
+	return b.String()
+}
+
+// mysqlSQL replaces the placeholders of PostgreSQL with the ones of MySQL.
+func mysqlSQL(sql string) string {
+	for i := 9; i > 0; i-- {
+		sql = strings.ReplaceAll(sql, fmt.Sprintf("$%d", i), "?")
+	}
+	return sql
+}
+
+// MySQL returns an entry of a MySQL server at the level: the slow query log, with long_query_time
+// = 0 so every query is logged, for successful queries and the error log otherwise. Notes of the
+// error log are logged at info.
+func MySQL(t time.Time, level model.LabelValue) string {
+	q := NewQuery(t)
+	ts := t.UTC().Format("2006-01-02T15:04:05.000000Z")
+	id := pid(q.User)
+	switch level {
+	case log.CRITICAL, log.FATAL:
+		return fmt.Sprintf("%s 0 [ERROR] [MY-012592] [InnoDB] Operating system error number 28 in a file operation.\n%s 0 [ERROR] [MY-012596] [InnoDB] Error number 28 means 'No space left on device'", ts, ts)
+	case log.ERROR:
+		return fmt.Sprintf("%s %d [ERROR] [MY-010584] [Repl] Replica SQL for channel '': Worker 1 failed executing transaction 'ANONYMOUS' at source log binlog.%06d, end_log_pos %d; Could not execute Update_rows event on table %s.%s; Deadlock found when trying to get lock; try restarting transaction, Error_code: 1213; handler error HA_ERR_LOCK_DEADLOCK, Error_code: MY-001213",
+			ts, id, 1+rand.Intn(300), rand.Intn(100_000_000), Database, q.Table.Name)
+	case log.WARN:
+		if rand.Intn(2) == 0 {
+			return fmt.Sprintf("%s %d [Warning] [MY-010055] [Server] IP address '%s' could not be resolved: Name or service not known", ts, id, q.ClientIP())
+		}
+		// Queries without an index are logged with log_queries_not_using_indexes.
+		q.Kind, q.Indexed = "SELECT", false
+		q.Examined = q.Table.Rows
+		q.Duration += time.Second
+	case log.INFO:
+		if rand.Intn(10) == 0 {
+			// Clients going away without closing their connection are a note of the error log.
+			return fmt.Sprintf("%s %d [Note] [MY-010914] [Server] Aborted connection %d to db: '%s' user: '%s' host: '%s' (Got an error reading communication packets).",
+				ts, id, id, Database, q.User, q.ClientIP())
+		}
+	}
+	return MySQLSlow(q)
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// postgresTime is the layout of %m.
+	postgresTime = "2006-01-02 15:04:05.000 MST"
+	// checkpointer is the pid of the checkpointer process, its entries have no user.
+	checkpointer = 27
+)
+
+// postgresPrefix renders log_line_prefix = '%m [%p] %q%u@%d '.
+func postgresPrefix(t time.Time, pid int, user string) string {
+	return fmt.Sprintf("%s [%d] %s@%s ", t.UTC().Format(postgresTime), pid, user, Database)
+}
+
+// Postgres returns a PostgreSQL log entry at the level, as written to stderr with the
+// log_line_prefix of postgresPrefix. Entries with a DETAIL span several lines.
+func Postgres(t time.Time, level model.LabelValue) string {
+	q := NewQuery(t)
+	p := pid(q.User)
+	prefix := postgresPrefix(t, p, q.User)
+	switch level {
+	case log.CRITICAL, log.FATAL:
+		return postgresFatal(t, p, q)
+	case log.ERROR:
+		switch rand.Intn(4) {
+		case 0:
+			return postgresDeadlock(t, p, q)
+		case 1:
+			return prefix + fmt.Sprintf("ERROR:  duplicate key value violates unique constraint \"%s_pkey\"\n", q.Table.Name) +
+				prefix + fmt.Sprintf("DETAIL:  Key (id)=(%d) already exists.\n", q.id) +
+				prefix + "STATEMENT:  " + insert(q)
+		case 2:
+			return prefix + "ERROR:  canceling statement due to statement timeout\n" +
+				prefix + "STATEMENT:  " + q.SQL()
+		default:
+			return prefix + "ERROR:  could not serialize access due to concurrent update\n" +
+				prefix + "STATEMENT:  " + update(q)
+		}
+	case log.WARN:
+		switch rand.Intn(3) {
+		case 0:
+			return prefix + "WARNING:  there is no transaction in progress"
+		case 1:
+			return postgresPrefix(t, p, "postgres") + fmt.Sprintf("WARNING:  skipping vacuum of \"%s\" --- lock not available", q.Table.Name)
+		default:
+			return fmt.Sprintf("%s [%d] LOG:  checkpoints are occurring too frequently (%d seconds apart)\n", t.UTC().Format(postgresTime), checkpointer, 5+rand.Intn(20)) +
+				fmt.Sprintf("%s [%d] HINT:  Consider increasing the configuration parameter \"max_wal_size\".", t.UTC().Format(postgresTime), checkpointer)
+		}
+	case log.DEBUG, log.TRACE:
+		return prefix + fmt.Sprintf("DEBUG:  StartTransaction(1) name: unnamed; blockState: DEFAULT; state: INPROGRESS, xid/subid/cid: %d/1/0", 700_000+q.id%100_000)
+	}
+	switch r := rand.Intn(10); {
+	case r < 6:
+		// log_min_duration_statement = 0 logs every statement with its duration.
+		return prefix + fmt.Sprintf("LOG:  duration: %.3f ms  statement: %s", float64(q.Duration.Microseconds())/1000, q.SQL())
+	case r < 8:
+		return prefix + fmt.Sprintf("LOG:  connection authorized: user=%s database=%s application_name=%s SSL enabled (protocol=TLSv1.3, cipher=TLS_AES_256_GCM_SHA384, bits=256)", q.User, Database, q.User)
+	case r < 9:
+		return fmt.Sprintf("%s [%d] [unknown]@[unknown] LOG:  connection received: host=%s port=%d", t.UTC().Format(postgresTime), p, q.ClientIP(), 30000+rand.Intn(30000))
+	}
+	buffers := 100 + rand.Intn(5000)
+	return fmt.Sprintf("%s [%d] LOG:  checkpoint complete: wrote %d buffers (%.1f%%); 0 WAL file(s) added, 0 removed, %d recycled; write=%.3f s, sync=%.3f s, total=%.3f s; sync files=%d, longest=%.3f s, average=%.3f s; distance=%d kB, estimate=%d kB",
+		t.UTC().Format(postgresTime), checkpointer, buffers, float64(buffers)/163.84, rand.Intn(4),
+		float64(buffers)*0.01, rand.Float64()*0.05, float64(buffers)*0.01+0.05, 10+rand.Intn(50), rand.Float64()*0.02, rand.Float64()*0.005, buffers*8, buffers*9)
+}
+
+// postgresDeadlock returns the entry of a transaction aborted to resolve a deadlock with another
+// backend updating the same rows in the opposite order.
+func postgresDeadlock(t time.Time, p int, q Query) string {
+	prefix := postgresPrefix(t, p, q.User)
+	other := p + 1 + rand.Intn(500)
+	xid := 700_000 + rand.Intn(100_000)
+	mine, theirs := update(q), update(NewQuery(t))
+	var b strings.Builder
+	b.WriteString(prefix + "ERROR:  deadlock detected\n")
+	fmt.Fprintf(&b, "%sDETAIL:  Process %d waits for ShareLock on transaction %d; blocked by process %d.\n", prefix, p, xid+1, other)
+	fmt.Fprintf(&b, "\tProcess %d waits for ShareLock on transaction %d; blocked by process %d.\n", other, xid, p)
+	fmt.Fprintf(&b, "\tProcess %d: %s\n", p, mine)
+	fmt.Fprintf(&b, "\tProcess %d: %s\n", other, theirs)
+	b.WriteString(prefix + "HINT:  See server log for query details.\n")
+	fmt.Fprintf(&b, "%sCONTEXT:  while updating tuple (%d,%d) in relation \"%s\"\n", prefix, rand.Intn(50_000), 1+rand.Intn(60), q.Table.Name)
+	b.WriteString(prefix + "STATEMENT:  " + mine)
+	return b.String()
+}
+
+func postgresFatal(t time.Time, p int, q Query) string {
+	switch rand.Intn(3) {
+	case 0:
+		return postgresPrefix(t, p, q.User) + fmt.Sprintf("FATAL:  password authentication failed for user \"%s\"\n", q.User) +
+			postgresPrefix(t, p, q.User) + `DETAIL:  Connection matched pg_hba.conf line 99: "host all all all scram-sha-256"`
+	case 1:
+		return postgresPrefix(t, p, q.User) + "FATAL:  sorry, too many clients already"
+	}
+	return postgresPrefix(t, p, q.User) + "FATAL:  terminating connection due to administrator command"
+}
+
+// update returns an UPDATE of the table of q by its primary key.
+func update(q Query) string {
+	return fmt.Sprintf("UPDATE %s SET %s = $1 WHERE id = %d", q.Table.Name, q.Table.Columns[len(q.Table.Columns)-1], q.id)
+}
+
+// insert returns an INSERT of a row of the table of q with its primary key.
+func insert(q Query) string {
+	cols := q.Table.Columns
+	values := make([]string, len(cols))
+	values[0] = fmt.Sprint(q.id)
+	for i := 1; i < len(cols); i++ {
+		values[i] = fmt.Sprintf("$%d", i)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", q.Table.Name, strings.Join(cols, ", "), strings.Join(values, ", "))
+}
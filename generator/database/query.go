@@ -0,0 +1,131 @@
+// Package database generates the logs of database servers: PostgreSQL with its log_line_prefix,
+// the MySQL slow query log and MongoDB structured logs. They share a model of the queries of a
+// shop, so the same tables, statements and durations show up in every format.
+package database
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/dist"
+)
+
+// DurationDist is the distribution of query durations in seconds. The "query" field of
+// dist.Configure overrides it, e.g. query=lognormal(5ms, 2).
+var DurationDist dist.Distribution = dist.LogNormal{Median: 0.002, Sigma: 1.8}
+
+// Database is the name of the database of the shop.
+const Database = "shop"
+
+// Users are the database users of the services.
+var Users = []string{"checkout", "cart", "payment", "shipping", "order", "api"}
+
+// Table is a table, or a collection, of the shop with its columns.
+type Table struct {
+	Name    string
+	Columns []string
+	// Rows is the approximate number of rows.
+	Rows int
+}
+
+// Tables are the tables of the shop, the first ones are queried the most.
+var Tables = []Table{
+	{"orders", []string{"id", "user_id", "status", "total", "created_at"}, 4_000_000},
+	{"carts", []string{"id", "user_id", "updated_at"}, 800_000},
+	{"products", []string{"id", "sku", "name", "price", "stock"}, 120_000},
+	{"users", []string{"id", "email", "name", "created_at"}, 1_500_000},
+	{"payments", []string{"id", "order_id", "amount", "state"}, 3_900_000},
+	{"shipments", []string{"id", "order_id", "carrier", "tracking_number"}, 3_500_000},
+	{"sessions", []string{"id", "user_id", "expires_at"}, 9_000_000},
+}
+
+var tableDist = dist.NewZipf(1.2, 0)
+
+// Query is a query run by a client of the database.
+type Query struct {
+	Time  time.Time
+	User  string
+	Table Table
+	// Kind is the statement: SELECT, INSERT, UPDATE or DELETE.
+	Kind     string
+	Duration time.Duration
+	// Sent is the number of rows returned or changed, Examined the number of rows scanned.
+	Sent, Examined int
+	// Indexed is false for queries scanning the whole table.
+	Indexed bool
+	id      int
+}
+
+// NewQuery generates a query started at t. Slow queries return more rows and scan more of the
+// table, the slowest ones don't use an index.
+func NewQuery(t time.Time) Query {
+	q := Query{
+		Time:     t,
+		User:     Users[rand.Intn(len(Users))],
+		Table:    Tables[dist.Pick(tableDist, len(Tables))],
+		Duration: time.Duration(dist.Between(dist.Field("query", DurationDist), 0.00001, 600) * float64(time.Second)).Round(time.Microsecond),
+		id:       1 + rand.Intn(5_000_000),
+	}
+	switch r := rand.Float64(); {
+	case r < 0.7:
+		q.Kind = "SELECT"
+	case r < 0.85:
+		q.Kind = "UPDATE"
+	case r < 0.97:
+		q.Kind = "INSERT"
+	default:
+		q.Kind = "DELETE"
+	}
+	// Scanning a row takes about a microsecond.
+	scanned := math.Max(q.Duration.Seconds()*1e6*rand.Float64(), 1)
+	q.Indexed = q.Duration < time.Second || rand.Intn(3) == 0
+	q.Examined = int(math.Min(scanned, float64(q.Table.Rows)))
+	if !q.Indexed {
+		q.Examined = q.Table.Rows
+	}
+	switch q.Kind {
+	case "SELECT":
+		q.Sent = int(math.Min(float64(q.Examined), 1+math.Sqrt(scanned)))
+	case "INSERT":
+		q.Sent, q.Examined = 1, 0
+	default:
+		q.Sent = int(math.Min(float64(q.Examined), 1+math.Log1p(scanned)))
+	}
+	return q
+}
+
+// SQL returns the statement of the query.
+func (q Query) SQL() string {
+	cols := q.Table.Columns
+	filter := fmt.Sprintf("%s = %d", cols[1], q.id)
+	if !q.Indexed {
+		// Filtering on an unindexed expression scans the table.
+		filter = fmt.Sprintf("lower(%s) LIKE '%%%s%%'", cols[2], strings.ToLower(string(rune('a'+q.id%26))))
+	}
+	switch q.Kind {
+	case "INSERT":
+		values := make([]string, len(cols)-1)
+		for i := range values {
+			values[i] = fmt.Sprintf("$%d", i+1)
+		}
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", q.Table.Name, strings.Join(cols[1:], ", "), strings.Join(values, ", "))
+	case "UPDATE":
+		return fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s", q.Table.Name, cols[len(cols)-1], filter)
+	case "DELETE":
+		return fmt.Sprintf("DELETE FROM %s WHERE %s", q.Table.Name, filter)
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s DESC LIMIT %d", strings.Join(cols, ", "), q.Table.Name, filter, cols[0], max(q.Sent, 1))
+}
+
+// ClientIP returns the address of the service running the query.
+func (q Query) ClientIP() string {
+	return fmt.Sprintf("10.0.%d.%d", len(q.User), 10+int(q.User[0])%200)
+}
+
+// pid returns a backend process or connection id, stable for a user.
+func pid(user string) int {
+	return 1000 + len(user)*317 + rand.Intn(40)
+}
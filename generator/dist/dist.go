@@ -0,0 +1,305 @@
+// Package dist provides random distributions for generated values. Real logs are skewed: a few
+// URLs, users and tenants dominate and latencies have long tails, which is what makes top-N and
+// percentile views interesting.
+package dist
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Distribution returns random values.
+type Distribution interface {
+	Sample() float64
+}
+
+// Ranker is implemented by distributions that pick among n items themselves.
+type Ranker interface {
+	Rank(n int) int
+}
+
+// Source generates the random numbers of samples, *rand.Rand implements it.
+type Source interface {
+	Float64() float64
+	NormFloat64() float64
+	ExpFloat64() float64
+}
+
+// Seeded is implemented by distributions that can draw their samples from a given source.
+type Seeded interface {
+	SampleFrom(r Source) float64
+}
+
+// SampleFrom returns a sample drawn from r, so a seeded rand.Rand gives reproducible values.
+// Distributions that are not Seeded use the global source.
+func SampleFrom(d Distribution, r Source) float64 {
+	if s, ok := d.(Seeded); ok {
+		return s.SampleFrom(r)
+	}
+	return d.Sample()
+}
+
+// global is the Source of the math/rand functions.
+type global struct{}
+
+func (global) Float64() float64     { return rand.Float64() }
+func (global) NormFloat64() float64 { return rand.NormFloat64() }
+func (global) ExpFloat64() float64  { return rand.ExpFloat64() }
+
+// Pick returns an index between 0 and n-1. Samples out of range are clamped.
+func Pick(d Distribution, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	if r, ok := d.(Ranker); ok {
+		return r.Rank(n)
+	}
+	i := int(math.Floor(d.Sample()))
+	return min(max(i, 0), n-1)
+}
+
+// Between returns a sample clamped between lo and hi.
+func Between(d Distribution, lo, hi float64) float64 {
+	return math.Min(math.Max(d.Sample(), lo), hi)
+}
+
+// Uniform returns values between Min and Max. It picks items evenly.
+type Uniform struct {
+	Min, Max float64
+}
+
+func (u Uniform) Sample() float64 { return u.SampleFrom(global{}) }
+
+func (u Uniform) SampleFrom(r Source) float64 { return u.Min + r.Float64()*(u.Max-u.Min) }
+
+func (u Uniform) Rank(n int) int { return rand.Intn(n) }
+
+// Normal is the normal distribution.
+type Normal struct {
+	Mean, StdDev float64
+}
+
+func (d Normal) Sample() float64 { return d.SampleFrom(global{}) }
+
+func (d Normal) SampleFrom(r Source) float64 { return d.Mean + d.StdDev*r.NormFloat64() }
+
+// LogNormal is skewed to the right with half of the values below Median, larger Sigma gives longer tails.
+type LogNormal struct {
+	Median, Sigma float64
+}
+
+func (d LogNormal) Sample() float64 { return d.SampleFrom(global{}) }
+
+func (d LogNormal) SampleFrom(r Source) float64 { return d.Median * math.Exp(d.Sigma*r.NormFloat64()) }
+
+// Exponential is the distribution of the time between independent events.
+type Exponential struct {
+	Mean float64
+}
+
+func (d Exponential) Sample() float64 { return d.SampleFrom(global{}) }
+
+func (d Exponential) SampleFrom(r Source) float64 { return r.ExpFloat64() * d.Mean }
+
+// Pareto is a heavy tailed distribution starting at Scale, lower Shape gives heavier tails.
+type Pareto struct {
+	Scale, Shape float64
+}
+
+func (d Pareto) Sample() float64 { return d.SampleFrom(global{}) }
+
+func (d Pareto) SampleFrom(r Source) float64 { return d.Scale * math.Pow(1-r.Float64(), -1/d.Shape) }
+
+// Zipf picks ranks with a probability proportional to 1/(rank+1)^S, so the first items dominate.
+type Zipf struct {
+	S float64
+	// N is the number of ranks Sample picks from, Rank uses the number of items instead.
+	N int
+
+	mtx  sync.Mutex
+	cdfs map[int][]float64
+}
+
+// NewZipf creates a Zipf distribution over n ranks.
+func NewZipf(s float64, n int) *Zipf {
+	return &Zipf{S: s, N: n}
+}
+
+func (z *Zipf) Sample() float64 { return float64(z.Rank(max(z.N, 1))) }
+
+func (z *Zipf) Rank(n int) int {
+	cdf := z.cdf(n)
+	return sort.SearchFloat64s(cdf, rand.Float64()*cdf[n-1])
+}
+
+func (z *Zipf) cdf(n int) []float64 {
+	z.mtx.Lock()
+	defer z.mtx.Unlock()
+	if cdf, ok := z.cdfs[n]; ok {
+		return cdf
+	}
+	if z.cdfs == nil {
+		z.cdfs = map[int][]float64{}
+	}
+	cdf := make([]float64, n)
+	var total float64
+	for i := range cdf {
+		total += 1 / math.Pow(float64(i+1), z.S)
+		cdf[i] = total
+	}
+	z.cdfs[n] = cdf
+	return cdf
+}
+
+// Bucket is a histogram bucket, values are between the previous bucket's upper bound and Upper.
+type Bucket struct {
+	Upper, Weight float64
+}
+
+// Histogram is an empirical distribution, for example measured latencies. The first bucket starts at 0.
+type Histogram struct {
+	Buckets []Bucket
+}
+
+func (h Histogram) Sample() float64 { return h.SampleFrom(global{}) }
+
+func (h Histogram) SampleFrom(rnd Source) float64 {
+	var total float64
+	for _, b := range h.Buckets {
+		total += b.Weight
+	}
+	r := rnd.Float64() * total
+	lower := 0.0
+	for _, b := range h.Buckets {
+		if r < b.Weight {
+			return lower + rnd.Float64()*(b.Upper-lower)
+		}
+		r -= b.Weight
+		lower = b.Upper
+	}
+	return lower
+}
+
+// New creates a distribution from its name and parameters:
+//
+//	uniform [min max], between 0 and 1 without parameters
+//	normal mean stddev
+//	lognormal median sigma
+//	exponential mean
+//	pareto scale shape
+//	zipf s [n]
+//	histogram upper weight [upper weight...]
+func New(name string, params ...float64) (Distribution, error) {
+	expect := func(n ...int) error {
+		for _, c := range n {
+			if len(params) == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: unexpected number of parameters %d", name, len(params))
+	}
+	switch strings.ToLower(name) {
+	case "uniform":
+		if err := expect(0, 2); err != nil {
+			return nil, err
+		}
+		if len(params) == 0 {
+			return Uniform{Max: 1}, nil
+		}
+		return Uniform{Min: params[0], Max: params[1]}, nil
+	case "normal":
+		if err := expect(2); err != nil {
+			return nil, err
+		}
+		return Normal{Mean: params[0], StdDev: params[1]}, nil
+	case "lognormal":
+		if err := expect(2); err != nil {
+			return nil, err
+		}
+		return LogNormal{Median: params[0], Sigma: params[1]}, nil
+	case "exponential":
+		if err := expect(1); err != nil {
+			return nil, err
+		}
+		return Exponential{Mean: params[0]}, nil
+	case "pareto":
+		if err := expect(2); err != nil {
+			return nil, err
+		}
+		if params[1] <= 0 {
+			return nil, fmt.Errorf("pareto: shape must be positive")
+		}
+		return Pareto{Scale: params[0], Shape: params[1]}, nil
+	case "zipf":
+		if err := expect(1, 2); err != nil {
+			return nil, err
+		}
+		n := 100
+		if len(params) == 2 {
+			n = int(params[1])
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("zipf: n must be positive")
+		}
+		return NewZipf(params[0], n), nil
+	case "histogram":
+		if len(params) == 0 || len(params)%2 != 0 {
+			return nil, fmt.Errorf("histogram: expected pairs of upper bound and weight")
+		}
+		h := Histogram{}
+		for i := 0; i < len(params); i += 2 {
+			if len(h.Buckets) > 0 && params[i] < h.Buckets[len(h.Buckets)-1].Upper {
+				return nil, fmt.Errorf("histogram: upper bounds must be increasing")
+			}
+			h.Buckets = append(h.Buckets, Bucket{Upper: params[i], Weight: params[i+1]})
+		}
+		return h, nil
+	default:
+		return nil, fmt.Errorf("unknown distribution %q", name)
+	}
+}
+
+// ParseParam parses a number or a duration, durations are returned in seconds.
+func ParseParam(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid parameter %q", s)
+	}
+	return d.Seconds(), nil
+}
+
+// Parse parses a distribution written as name(params...), e.g. lognormal(200ms, 1.5) or
+// histogram(10:50, 100:30, 1000:20). Durations are converted to seconds.
+func Parse(spec string) (Distribution, error) {
+	spec = strings.TrimSpace(spec)
+	name, rest, ok := strings.Cut(spec, "(")
+	if !ok {
+		return New(name)
+	}
+	if !strings.HasSuffix(rest, ")") {
+		return nil, fmt.Errorf("%s: missing closing parenthesis", spec)
+	}
+	var params []float64
+	if args := strings.TrimSuffix(rest, ")"); strings.TrimSpace(args) != "" {
+		for _, arg := range strings.Split(args, ",") {
+			for _, part := range strings.Split(arg, ":") {
+				v, err := ParseParam(part)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", spec, err)
+				}
+				params = append(params, v)
+			}
+		}
+	}
+	return New(name, params...)
+}
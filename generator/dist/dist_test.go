@@ -0,0 +1,148 @@
+package dist
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const samples = 20000
+
+func percentile(d Distribution, p float64) float64 {
+	values := make([]float64, samples)
+	for i := range values {
+		values[i] = d.Sample()
+	}
+	sort.Float64s(values)
+	return values[int(p*float64(len(values)-1))]
+}
+
+func TestDistributions(t *testing.T) {
+	a := assert.New(t)
+
+	a.InDelta(15, percentile(Uniform{Min: 10, Max: 20}, 0.5), 0.5)
+	a.InDelta(100, percentile(Normal{Mean: 100, StdDev: 10}, 0.5), 1)
+	a.InDelta(110, percentile(Normal{Mean: 100, StdDev: 10}, 0.841), 1.5)
+	a.InDelta(0.05, percentile(LogNormal{Median: 0.05, Sigma: 2}, 0.5), 0.005)
+	a.InEpsilon(0.05*math.Exp(4), percentile(LogNormal{Median: 0.05, Sigma: 2}, 0.977), 0.15)
+	a.InDelta(10*math.Ln2, percentile(Exponential{Mean: 10}, 0.5), 0.3)
+
+	// Pareto: P(X > x) = (scale/x)^shape
+	pareto := Pareto{Scale: 100, Shape: 1.5}
+	a.GreaterOrEqual(percentile(pareto, 0), 100.0)
+	a.InDelta(100*math.Pow(2, 1/1.5), percentile(pareto, 0.5), 4)
+	a.Greater(percentile(pareto, 0.999), 10*percentile(pareto, 0.5), "heavy tail")
+
+	h := Histogram{Buckets: []Bucket{{Upper: 10, Weight: 50}, {Upper: 100, Weight: 50}, {Upper: 1000, Weight: 0}}}
+	below := 0
+	for i := 0; i < samples; i++ {
+		v := h.Sample()
+		a.True(v >= 0 && v <= 100, v)
+		if v < 10 {
+			below++
+		}
+	}
+	a.InDelta(samples/2, below, samples*0.02)
+}
+
+func TestZipf(t *testing.T) {
+	a := assert.New(t)
+	z := NewZipf(1, 0)
+	counts := make([]int, 5)
+	for i := 0; i < samples; i++ {
+		counts[Pick(z, 5)]++
+	}
+	// Weights 1, 1/2, 1/3, 1/4, 1/5
+	total := 1 + 1.0/2 + 1.0/3 + 1.0/4 + 1.0/5
+	for rank, count := range counts {
+		a.InDelta(samples/(float64(rank+1)*total), count, samples*0.02, "rank %d", rank)
+	}
+
+	z = NewZipf(2, 3)
+	for i := 0; i < 100; i++ {
+		a.Contains([]float64{0, 1, 2}, z.Sample())
+	}
+}
+
+func TestPick(t *testing.T) {
+	a := assert.New(t)
+	counts := make([]int, 4)
+	for i := 0; i < samples; i++ {
+		counts[Pick(Uniform{}, 4)]++
+	}
+	for _, count := range counts {
+		a.InDelta(samples/4, count, samples*0.02)
+	}
+	a.Equal(3, Pick(Normal{Mean: 100}, 4), "samples are clamped")
+	a.Equal(0, Pick(Normal{Mean: -5}, 4))
+	a.Equal(0, Pick(NewZipf(1, 0), 1))
+	a.Equal(2.0, Between(Normal{Mean: 5}, 0, 2))
+}
+
+func TestSampleFrom(t *testing.T) {
+	a := assert.New(t)
+	for _, d := range []Distribution{
+		Uniform{Max: 10}, Normal{Mean: 5, StdDev: 1}, LogNormal{Median: 1, Sigma: 1}, Exponential{Mean: 2}, Pareto{Scale: 1, Shape: 1.2},
+		Histogram{Buckets: []Bucket{{Upper: 1, Weight: 1}, {Upper: 10, Weight: 1}}},
+	} {
+		a.Equal(SampleFrom(d, rand.New(rand.NewSource(42))), SampleFrom(d, rand.New(rand.NewSource(42))), "%T samples are seeded", d)
+	}
+}
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+	for spec, expected := range map[string]Distribution{
+		"uniform":                         Uniform{Max: 1},
+		"uniform(1, 5)":                   Uniform{Min: 1, Max: 5},
+		"normal(100ms, 10ms)":             Normal{Mean: 0.1, StdDev: 0.01},
+		" lognormal(50ms,2) ":             LogNormal{Median: 0.05, Sigma: 2},
+		"exponential(2s)":                 Exponential{Mean: 2},
+		"pareto(500, 1.2)":                Pareto{Scale: 500, Shape: 1.2},
+		"histogram(10:50, 100:30, 1s:20)": Histogram{Buckets: []Bucket{{10, 50}, {100, 30}, {1, 20}}},
+	} {
+		d, err := Parse(spec)
+		if spec == "histogram(10:50, 100:30, 1s:20)" {
+			a.ErrorContains(err, "upper bounds must be increasing")
+			continue
+		}
+		require.NoError(t, err, spec)
+		a.Equal(expected, d, spec)
+	}
+
+	d, err := Parse("zipf(1.5, 10)")
+	require.NoError(t, err)
+	a.Equal(1.5, d.(*Zipf).S)
+	a.Equal(10, d.(*Zipf).N)
+
+	for spec, msg := range map[string]string{
+		"gamma(1)":          `unknown distribution "gamma"`,
+		"normal(1":          "missing closing parenthesis",
+		"normal(1)":         "normal: unexpected number of parameters 1",
+		"pareto(1, 0)":      "shape must be positive",
+		"zipf(1, 0)":        "n must be positive",
+		"histogram(1)":      "expected pairs",
+		"lognormal(1, abc)": `invalid parameter "abc"`,
+	} {
+		_, err := Parse(spec)
+		a.ErrorContains(err, msg, spec)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	a := assert.New(t)
+	fallback := Uniform{}
+	require.NoError(t, Configure("uri=zipf(1.5); duration = lognormal(100ms, 2);"))
+	defer UnsetField("uri")
+	defer UnsetField("duration")
+	a.IsType(&Zipf{}, Field("uri", fallback))
+	a.Equal(LogNormal{Median: 0.1, Sigma: 2}, Field("duration", fallback))
+	a.Equal(fallback, Field("bytes", fallback))
+
+	a.ErrorContains(Configure("uri"), "expected name=distribution")
+	a.ErrorContains(Configure("uri=nope"), "field uri: unknown distribution")
+	a.ErrorContains(Configure("url=zipf(1.5)"), `unknown field "url"`)
+}
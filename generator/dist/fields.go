@@ -0,0 +1,64 @@
+package dist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Fields lists the fields generators sample from a configurable distribution.
+var Fields = []string{"org", "user", "file", "ip", "method", "resource", "latency", "bytes", "uri", "duration", "query", "apply"}
+
+var (
+	fieldsMtx sync.RWMutex
+	fields    = map[string]Distribution{}
+)
+
+// Field returns the distribution configured for a field, or fallback.
+func Field(name string, fallback Distribution) Distribution {
+	fieldsMtx.RLock()
+	defer fieldsMtx.RUnlock()
+	if d, ok := fields[name]; ok {
+		return d
+	}
+	return fallback
+}
+
+// SetField configures the distribution of a field.
+func SetField(name string, d Distribution) {
+	fieldsMtx.Lock()
+	defer fieldsMtx.Unlock()
+	fields[name] = d
+}
+
+// UnsetField removes the distribution configured for a field, Field returns its fallback again.
+func UnsetField(name string) {
+	fieldsMtx.Lock()
+	defer fieldsMtx.Unlock()
+	delete(fields, name)
+}
+
+// Configure sets field distributions from a list like "uri=zipf(1.5);duration=lognormal(100ms, 2)".
+// Fields that are not in Fields are rejected.
+func Configure(spec string) error {
+	for _, field := range strings.Split(spec, ";") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("invalid field distribution %q, expected name=distribution", field)
+		}
+		name = strings.TrimSpace(name)
+		if !slices.Contains(Fields, name) {
+			return fmt.Errorf("unknown field %q, expected one of %s", name, strings.Join(Fields, ", "))
+		}
+		d, err := Parse(value)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		SetField(name, d)
+	}
+	return nil
+}
@@ -2,11 +2,11 @@ package flog
 
 import (
 	"fmt"
-	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/brianvoe/gofakeit"
+	"github.com/grafana/explore-logs/generator/dist"
 )
 
 const (
@@ -28,17 +28,9 @@ const (
 
 // NewApacheCommonLog creates a log string with apache common log format
 func NewApacheCommonLog(t time.Time, URI string, statusCode int) string {
-	return fmt.Sprintf(
-		ApacheCommonLog,
-		gofakeit.IPv4Address(),
-		RandAuthUserID(),
-		t.Format(Apache),
-		gofakeit.HTTPMethod(),
-		URI,
-		RandHTTPVersion(),
-		statusCode,
-		gofakeit.Number(0, 30000),
-	)
+	r := NewRequest(t, URI, statusCode)
+	r.Host = gofakeit.IPv4Address()
+	return r.ApacheCommon()
 }
 
 var ips = []string{
@@ -50,24 +42,12 @@ var ips = []string{
 }
 
 func FakeIP() string {
-	return ips[rand.Intn(len(ips))]
+	return ips[dist.Pick(dist.Field("ip", ipDist), len(ips))]
 }
 
 // NewApacheCombinedLog creates a log string with apache combined log format
 func NewApacheCombinedLog(t time.Time, URI string, statusCode int) string {
-	return fmt.Sprintf(
-		ApacheCombinedLog,
-		ips[rand.Intn(len(ips))],
-		RandAuthUserID(),
-		t.Format(Apache),
-		gofakeit.HTTPMethod(),
-		URI,
-		RandHTTPVersion(),
-		statusCode,
-		gofakeit.Number(30, 100000),
-		gofakeit.URL(),
-		gofakeit.UserAgent(),
-	)
+	return NewRequest(t, URI, statusCode).ApacheCombined()
 }
 
 // NewApacheErrorLog creates a log string with apache error log format
@@ -116,32 +96,12 @@ func NewRFC5424Log(t time.Time) string {
 
 // NewCommonLogFormat creates a log string with common log format
 func NewCommonLogFormat(t time.Time, URI string, statusCode int) string {
-	return fmt.Sprintf(
-		CommonLogFormat,
-		gofakeit.IPv4Address(),
-		RandAuthUserID(),
-		t.Format(CommonLog),
-		gofakeit.HTTPMethod(),
-		URI,
-		RandHTTPVersion(),
-		statusCode,
-		gofakeit.Number(0, 30000),
-	)
+	r := NewRequest(t, URI, statusCode)
+	r.Host = gofakeit.IPv4Address()
+	return r.CommonLog()
 }
 
 // NewJSONLogFormat creates a log string with json log format
 func NewJSONLogFormat(t time.Time, URI string, statusCode int) string {
-	return fmt.Sprintf(
-		JSONLogFormat,
-		ips[rand.Intn(len(ips))],
-		RandAuthUserID(),
-		t.Format(CommonLog),
-		gofakeit.HTTPMethod(),
-		URI,
-		RandHTTPVersion(),
-		statusCode,
-		gofakeit.Number(0, 30000),
-		gofakeit.URL(),
-		gofakeit.Number(0, 25),
-	)
+	return NewRequest(t, URI, statusCode).JSON()
 }
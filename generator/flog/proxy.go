@@ -0,0 +1,325 @@
+package flog
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Authority is the host requested through the proxies.
+const Authority = "shop.example.com"
+
+// hash returns a hash of the request, stable across the formats rendering it.
+func (r Request) hash() uint64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d %s %s %s", r.Time.UnixNano(), r.Host, r.Method, r.URI)
+	return h.Sum64()
+}
+
+// RequestID returns the x-request-id of the request.
+func (r Request) RequestID() string {
+	h := r.hash()
+	return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x", uint32(h>>32), uint16(h>>16), uint16(h)&0xfff, 0x8000|uint16(h>>8)&0x3fff, (h*0x9e3779b97f4a7c15)>>16)
+}
+
+// ClientPort returns the source port of the request.
+func (r Request) ClientPort() int {
+	return 32768 + int(r.hash()%28000)
+}
+
+// ReceivedBytes returns the size of the request body, empty for reads.
+func (r Request) ReceivedBytes() int {
+	switch r.Method {
+	case "POST", "PUT", "PATCH":
+		return 100 + int(r.hash()%8000)
+	}
+	return 0
+}
+
+// Service returns the upstream service of the request, named after its path.
+func (r Request) Service() string {
+	path, _, _ := strings.Cut(r.URI, "?")
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment != "" && segment != "api" {
+			return segment
+		}
+	}
+	return "frontend"
+}
+
+// UpstreamHost returns the address of the upstream pod serving the request.
+func (r Request) UpstreamHost() string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(r.Service()))
+	s := h.Sum32()
+	return fmt.Sprintf("10.42.%d.%d:8080", s%16, 2+(s>>8)%250)
+}
+
+// upstreamCluster is the envoy cluster of the upstream service.
+func (r Request) upstreamCluster() string {
+	return fmt.Sprintf("outbound|8080||%s.default.svc.cluster.local", r.Service())
+}
+
+// millis formats a duration in milliseconds, -1 when negative like the proxies log missing timers.
+func millis(d time.Duration) int64 {
+	if d < 0 {
+		return -1
+	}
+	return d.Milliseconds()
+}
+
+// EnvoyResponseFlags returns the response flags envoy logs for the request, "-" without any.
+func (r Request) EnvoyResponseFlags() string {
+	switch r.Status {
+	case 429:
+		return "RL"
+	case 502:
+		return "UC"
+	case 503:
+		if r.Upstream < 0 {
+			return "UH"
+		}
+		return "UF"
+	case 504:
+		return "UT"
+	}
+	return "-"
+}
+
+// EnvoyLogFormat : [{start-time}] "{method} {path} {protocol}" {response-code} {response-flags} {bytes-received} {bytes-sent} {duration} {upstream-service-time} "{x-forwarded-for}" "{user-agent}" "{x-request-id}" "{authority}" "{upstream-host}"
+const EnvoyLogFormat = `[%s] "%s %s %s" %d %s %d %d %d %s "%s" "%s" "%s" "%s" "%s"`
+
+// Envoy renders the request in the default envoy access log format.
+func (r Request) Envoy() string {
+	upstreamTime, upstreamHost := "-", "-"
+	if r.Upstream >= 0 {
+		upstreamTime, upstreamHost = strconv.FormatInt(r.Upstream.Milliseconds(), 10), r.UpstreamHost()
+	}
+	return fmt.Sprintf(EnvoyLogFormat, r.Time.UTC().Format("2006-01-02T15:04:05.000Z"), r.Method, r.URI, r.Protocol, r.Status, r.EnvoyResponseFlags(),
+		r.ReceivedBytes(), r.Bytes, r.Duration.Milliseconds(), upstreamTime, r.Host, r.Agent, r.RequestID(), Authority, upstreamHost)
+}
+
+// EnvoyJSON renders the request in the JSON access log format of istio proxies.
+func (r Request) EnvoyJSON() string {
+	entry := struct {
+		StartTime           string  `json:"start_time"`
+		Method              string  `json:"method"`
+		Path                string  `json:"path"`
+		Protocol            string  `json:"protocol"`
+		ResponseCode        int     `json:"response_code"`
+		ResponseFlags       string  `json:"response_flags"`
+		ResponseCodeDetails string  `json:"response_code_details"`
+		BytesReceived       int     `json:"bytes_received"`
+		BytesSent           int     `json:"bytes_sent"`
+		Duration            int64   `json:"duration"`
+		UpstreamServiceTime *string `json:"upstream_service_time"`
+		XForwardedFor       string  `json:"x_forwarded_for"`
+		UserAgent           string  `json:"user_agent"`
+		RequestID           string  `json:"request_id"`
+		Authority           string  `json:"authority"`
+		UpstreamHost        *string `json:"upstream_host"`
+		UpstreamCluster     string  `json:"upstream_cluster"`
+		DownstreamRemote    string  `json:"downstream_remote_address"`
+		RouteName           string  `json:"route_name"`
+	}{
+		StartTime:           r.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Method:              r.Method,
+		Path:                r.URI,
+		Protocol:            r.Protocol,
+		ResponseCode:        r.Status,
+		ResponseFlags:       r.EnvoyResponseFlags(),
+		ResponseCodeDetails: r.envoyResponseCodeDetails(),
+		BytesReceived:       r.ReceivedBytes(),
+		BytesSent:           r.Bytes,
+		Duration:            r.Duration.Milliseconds(),
+		XForwardedFor:       r.Host,
+		UserAgent:           r.Agent,
+		RequestID:           r.RequestID(),
+		Authority:           Authority,
+		UpstreamCluster:     r.upstreamCluster(),
+		DownstreamRemote:    fmt.Sprintf("%s:%d", r.Host, r.ClientPort()),
+		RouteName:           "default",
+	}
+	if r.Upstream >= 0 {
+		upstreamTime, upstreamHost := strconv.FormatInt(r.Upstream.Milliseconds(), 10), r.UpstreamHost()
+		entry.UpstreamServiceTime, entry.UpstreamHost = &upstreamTime, &upstreamHost
+	}
+	b, _ := json.Marshal(entry)
+	return string(b)
+}
+
+func (r Request) envoyResponseCodeDetails() string {
+	switch r.EnvoyResponseFlags() {
+	case "RL":
+		return "request_rate_limited"
+	case "UC":
+		return "upstream_reset_before_response_started{connection_termination}"
+	case "UH":
+		return "no_healthy_upstream"
+	case "UF":
+		return "upstream_reset_before_response_started{connection_failure}"
+	case "UT":
+		return "response_timeout"
+	}
+	if r.Upstream < 0 {
+		return "direct_response"
+	}
+	return "via_upstream"
+}
+
+// HAProxyTerminationState returns the termination state HAProxy logs for the request: who ended
+// the session, in which state, then the persistence cookie flags.
+func (r Request) HAProxyTerminationState() string {
+	switch {
+	case r.Status == 504:
+		return "sH--"
+	case r.Status == 502:
+		return "SH--"
+	case r.Status == 503 && r.Upstream < 0:
+		return "SC--"
+	case r.Upstream < 0:
+		// Denied by an http-request rule.
+		return "PR--"
+	}
+	return "----"
+}
+
+// HAProxyLogFormat : {client-ip}:{client-port} [{accept-date}] {frontend} {backend}/{server} {TR}/{Tw}/{Tc}/{Tr}/{Ta} {status} {bytes} {req-cookie} {res-cookie} {termination-state} {actconn}/{feconn}/{beconn}/{srv-conn}/{retries} {srv-queue}/{backend-queue} "{request}"
+const HAProxyLogFormat = `%s:%d [%s] %s %s/%s %d/%d/%d/%d/%d %d %d - - %s %d/%d/%d/%d/%d %d/%d "%s %s %s"`
+
+// HAProxy renders the request in the HAProxy HTTP log format.
+func (r Request) HAProxy() string {
+	h := r.hash()
+	// TR: receiving the request, Tw: queued, Tc: connecting, Tr: waiting for the response.
+	tr := time.Duration(h%3) * time.Millisecond
+	tw, tc, tresp := time.Duration(0), time.Duration(h>>8%2)*time.Millisecond, r.Upstream
+	server := fmt.Sprintf("%s-%d", r.Service(), h>>16%3)
+	switch {
+	case r.Upstream < 0:
+		tw, tc, server = -1, -1, "<NOSRV>"
+	case r.Status == 504:
+		tresp = -1
+	}
+	conns := 10 + int(h>>24%90)
+	return fmt.Sprintf(HAProxyLogFormat, r.Host, r.ClientPort(), r.Time.Format("02/Jan/2006:15:04:05.000"), "http-in", r.Service()+"_backend", server,
+		millis(tr), millis(tw), millis(tc), millis(tresp), r.Duration.Milliseconds()+tr.Milliseconds(), r.Status, r.Bytes, r.HAProxyTerminationState(),
+		conns, conns, conns/3, conns/9, 0, 0, 0, r.Method, r.URI, r.Protocol)
+}
+
+// Traefik renders the request in the traefik JSON access log format.
+func (r Request) Traefik() string {
+	service := r.Service()
+	entry := struct {
+		ClientAddr            string
+		ClientHost            string
+		ClientPort            string
+		ClientUsername        string
+		DownstreamContentSize int
+		DownstreamStatus      int
+		Duration              int64
+		OriginContentSize     int
+		OriginDuration        int64
+		OriginStatus          int
+		Overhead              int64
+		RequestAddr           string
+		RequestContentSize    int
+		RequestCount          int
+		RequestHost           string
+		RequestMethod         string
+		RequestPath           string
+		RequestPort           string
+		RequestProtocol       string
+		RequestScheme         string
+		RetryAttempts         int
+		RouterName            string
+		ServiceAddr           string `json:",omitempty"`
+		ServiceName           string
+		ServiceURL            string `json:",omitempty"`
+		StartLocal            string
+		StartUTC              string
+		EntryPointName        string `json:"entryPointName"`
+		Level                 string `json:"level"`
+		Msg                   string `json:"msg"`
+		Time                  string `json:"time"`
+	}{
+		ClientAddr:            fmt.Sprintf("%s:%d", r.Host, r.ClientPort()),
+		ClientHost:            r.Host,
+		ClientPort:            strconv.Itoa(r.ClientPort()),
+		ClientUsername:        r.User,
+		DownstreamContentSize: r.Bytes,
+		DownstreamStatus:      r.Status,
+		Duration:              r.Duration.Nanoseconds(),
+		Overhead:              r.Duration.Nanoseconds(),
+		RequestAddr:           Authority,
+		RequestContentSize:    r.ReceivedBytes(),
+		RequestCount:          int(r.hash() % 1_000_000),
+		RequestHost:           Authority,
+		RequestMethod:         r.Method,
+		RequestPath:           r.URI,
+		RequestPort:           "-",
+		RequestProtocol:       r.Protocol,
+		RequestScheme:         "https",
+		RouterName:            service + "@kubernetes",
+		ServiceName:           service + "@kubernetes",
+		StartLocal:            r.Time.Format(time.RFC3339Nano),
+		StartUTC:              r.Time.UTC().Format(time.RFC3339Nano),
+		EntryPointName:        "websecure",
+		Level:                 "info",
+		Time:                  r.Time.Add(r.Duration).UTC().Format(time.RFC3339),
+	}
+	if r.Upstream >= 0 {
+		entry.ServiceAddr = r.UpstreamHost()
+		entry.ServiceURL = "http://" + r.UpstreamHost()
+		entry.OriginContentSize = r.Bytes
+		entry.OriginDuration = r.Upstream.Nanoseconds()
+		entry.OriginStatus = r.Status
+		entry.Overhead = (r.Duration - r.Upstream).Nanoseconds()
+	}
+	b, _ := json.Marshal(entry)
+	return string(b)
+}
+
+// NginxErrorLogFormat : {date} [{severity}] {pid}#{tid}: *{connection} {message}
+const NginxErrorLogFormat = "%s [%s] %d#%d: *%d %s"
+
+// NginxError renders the entry nginx writes to its error log about the request at severity:
+// debug, info, notice, warn, error or crit. Failed requests explain their status.
+func (r Request) NginxError(severity string) string {
+	h := r.hash()
+	pid := 20 + int(h%8)
+	request := fmt.Sprintf(`client: %s, server: %s, request: "%s %s %s", host: "%s"`, r.Host, Authority, r.Method, r.URI, r.Protocol, Authority)
+	upstream := fmt.Sprintf(`upstream: "http://%s%s", `, r.UpstreamHost(), r.URI)
+	var msg string
+	switch {
+	case r.Status == 504:
+		msg = "upstream timed out (110: Connection timed out) while reading response header from upstream, " + insertUpstream(request, upstream)
+	case r.Status == 502:
+		msg = "connect() failed (111: Connection refused) while connecting to upstream, " + insertUpstream(request, upstream)
+	case r.Status == 503:
+		msg = fmt.Sprintf(`no live upstreams while connecting to upstream "%s", %s`, r.Service(), request)
+	case r.Status == 429:
+		msg = fmt.Sprintf(`limiting requests, excess: %d.%03d by zone "api", %s`, 1+h%20, h%1000, request)
+	case r.Status == 404:
+		msg = fmt.Sprintf(`open() "/usr/share/nginx/html%s" failed (2: No such file or directory), %s`, r.URI, request)
+	case r.Status == 403:
+		msg = fmt.Sprintf(`access forbidden by rule, %s`, request)
+	case r.Status >= 500:
+		msg = "upstream prematurely closed connection while reading response header from upstream, " + insertUpstream(request, upstream)
+	case severity == "warn":
+		msg = fmt.Sprintf("an upstream response is buffered to a temporary file /var/cache/nginx/proxy_temp/%d/%02d/%010d while reading upstream, %s", h%10, h>>8%100, h%10_000_000_000, insertUpstream(request, upstream))
+	case severity == "debug":
+		msg = fmt.Sprintf(`http upstream request: "%s?"`, r.URI)
+	default:
+		msg = fmt.Sprintf("client %s closed keepalive connection", r.Host)
+	}
+	return fmt.Sprintf(NginxErrorLogFormat, r.Time.Format("2006/01/02 15:04:05"), severity, pid, pid, h%1_000_000, msg)
+}
+
+// insertUpstream adds the upstream to the request details, before the host like nginx does.
+func insertUpstream(request, upstream string) string {
+	i := strings.LastIndex(request, "host: ")
+	return request[:i] + upstream + request[i:]
+}
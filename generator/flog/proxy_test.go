@@ -0,0 +1,133 @@
+package flog
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRequest() Request {
+	return Request{
+		Time: time.Date(2024, 5, 1, 10, 0, 0, 250_000_000, time.UTC), Host: "10.0.0.1", User: "-", Method: "GET", URI: "/api/loki/v1/query", Protocol: "HTTP/1.1",
+		Status: 200, Bytes: 1234, Referer: "https://grafana.com", Agent: "curl/8.0",
+		Duration: 1500 * time.Millisecond, Upstream: 1498 * time.Millisecond,
+	}
+}
+
+func TestProxyFields(t *testing.T) {
+	a := assert.New(t)
+	r := testRequest()
+	a.Equal("loki", r.Service())
+	a.Equal(r.RequestID(), r.RequestID())
+	a.Regexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, r.RequestID())
+	a.Equal(0, r.ReceivedBytes())
+	r.Method = "POST"
+	a.Positive(r.ReceivedBytes())
+	a.Equal("frontend", Request{URI: "/"}.Service())
+}
+
+func TestEnvoy(t *testing.T) {
+	a := assert.New(t)
+	r := testRequest()
+	a.Equal(`[2024-05-01T10:00:00.250Z] "GET /api/loki/v1/query HTTP/1.1" 200 - 0 1234 1500 1498 "10.0.0.1" "curl/8.0" "`+r.RequestID()+`" "shop.example.com" "`+r.UpstreamHost()+`"`, r.Envoy())
+
+	r.Status, r.Upstream = 503, -1
+	a.Regexp(`" 503 UH 0 1234 1500 - .* "-"$`, r.Envoy())
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal([]byte(r.EnvoyJSON()), &fields))
+	a.Equal("UH", fields["response_flags"])
+	a.Equal("no_healthy_upstream", fields["response_code_details"])
+	a.Nil(fields["upstream_host"])
+	a.Equal(r.RequestID(), fields["request_id"])
+
+	r = testRequest()
+	fields = nil
+	require.NoError(t, json.Unmarshal([]byte(r.EnvoyJSON()), &fields))
+	a.Equal("1498", fields["upstream_service_time"])
+	a.Equal("outbound|8080||loki.default.svc.cluster.local", fields["upstream_cluster"])
+	a.Equal(1500.0, fields["duration"])
+}
+
+var haproxyLine = regexp.MustCompile(`^10\.0\.0\.1:\d+ \[01/May/2024:10:00:00\.250\] http-in (\S+)/(\S+) (-?\d+)/(-?\d+)/(-?\d+)/(-?\d+)/(\d+) (\d+) (\d+) - - (\S{4}) \d+/\d+/\d+/\d+/0 0/0 "GET /api/loki/v1/query HTTP/1\.1"$`)
+
+func TestHAProxy(t *testing.T) {
+	a := assert.New(t)
+	r := testRequest()
+	m := haproxyLine.FindStringSubmatch(r.HAProxy())
+	require.NotNil(t, m, r.HAProxy())
+	a.Equal("loki_backend", m[1])
+	a.Equal("1498", m[6])
+	total, _ := strconv.Atoi(m[7])
+	a.GreaterOrEqual(total, 1500)
+	a.Equal("----", m[10])
+
+	r.Status = 504
+	m = haproxyLine.FindStringSubmatch(r.HAProxy())
+	require.NotNil(t, m, r.HAProxy())
+	a.Equal("-1", m[6])
+	a.Equal("sH--", m[10])
+
+	r.Status, r.Upstream = 429, -1
+	m = haproxyLine.FindStringSubmatch(r.HAProxy())
+	require.NotNil(t, m, r.HAProxy())
+	a.Equal("<NOSRV>", m[2])
+	a.Equal("-1", m[5])
+	a.Equal("PR--", m[10])
+}
+
+func TestTraefik(t *testing.T) {
+	a := assert.New(t)
+	r := testRequest()
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal([]byte(r.Traefik()), &fields))
+	a.Equal(1.5e9, fields["Duration"])
+	a.Equal(1.498e9, fields["OriginDuration"])
+	a.Equal(2e6, fields["Overhead"])
+	a.Equal(200.0, fields["DownstreamStatus"])
+	a.Equal("loki@kubernetes", fields["ServiceName"])
+	a.Equal("/api/loki/v1/query", fields["RequestPath"])
+	a.Equal("info", fields["level"])
+
+	r.Status, r.Upstream = 401, -1
+	fields = nil
+	require.NoError(t, json.Unmarshal([]byte(r.Traefik()), &fields))
+	a.Equal(401.0, fields["DownstreamStatus"])
+	a.NotContains(fields, "ServiceURL")
+}
+
+func TestNginxError(t *testing.T) {
+	a := assert.New(t)
+	r := testRequest()
+	r.Status = 504
+	a.Regexp(`^2024/05/01 10:00:00 \[error\] (\d+)#(\d+): \*\d+ upstream timed out \(110: Connection timed out\) while reading response header from upstream, client: 10\.0\.0\.1, server: shop\.example\.com, request: "GET /api/loki/v1/query HTTP/1\.1", upstream: "http://10\.42\.\d+\.\d+:8080/api/loki/v1/query", host: "shop\.example\.com"$`, r.NginxError("error"))
+
+	r.Status = 404
+	a.Contains(r.NginxError("error"), `open() "/usr/share/nginx/html/api/loki/v1/query" failed (2: No such file or directory)`)
+
+	r.Status = 200
+	a.Contains(r.NginxError("warn"), "[warn]")
+	a.Contains(r.NginxError("warn"), "buffered to a temporary file")
+	a.Contains(r.NginxError("info"), "closed keepalive connection")
+}
+
+func TestProxyFormatsAreConsistent(t *testing.T) {
+	a := assert.New(t)
+	for i := 0; i < 100; i++ {
+		r := NewRequest(time.Now(), "/api/v1/push", 502)
+		var envoy, traefik map[string]any
+		require.NoError(t, json.Unmarshal([]byte(r.EnvoyJSON()), &envoy))
+		require.NoError(t, json.Unmarshal([]byte(r.Traefik()), &traefik))
+		a.Equal("POST", envoy["method"])
+		a.Equal(envoy["method"], traefik["RequestMethod"])
+		a.Equal(envoy["bytes_sent"], traefik["DownstreamContentSize"])
+		a.Equal(envoy["duration"], traefik["Duration"].(float64)/1e6)
+		a.Contains(r.HAProxy(), " 502 ")
+		a.Contains(r.HAProxy(), " SH-- ")
+	}
+}
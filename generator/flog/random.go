@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/brianvoe/gofakeit"
+	"github.com/grafana/explore-logs/generator/dist"
 )
 
 var ressourceURIs []string
@@ -16,9 +17,16 @@ func init() {
 	}
 }
 
+// Default distributions of the formatter fields, dist.Configure overrides them by field name.
+var (
+	resourceDist = dist.NewZipf(1, 0)
+	ipDist       = dist.NewZipf(1, 0)
+	methodDist   = dist.NewZipf(1.5, 0)
+)
+
 // RandResourceURI generates a random resource URI
 func RandResourceURI() string {
-	return ressourceURIs[rand.Intn(len(ressourceURIs))]
+	return ressourceURIs[dist.Pick(dist.Field("resource", resourceDist), len(ressourceURIs))]
 }
 
 func randResourceURI() string {
@@ -42,3 +50,10 @@ func RandHTTPVersion() string {
 	versions := []string{"HTTP/1.0", "HTTP/1.1", "HTTP/2.0"}
 	return versions[rand.Intn(3)]
 }
+
+var httpMethods = []string{"GET", "POST", "PUT", "HEAD", "PATCH", "DELETE"}
+
+// RandHTTPMethod returns a random http method, GET being the most common
+func RandHTTPMethod() string {
+	return httpMethods[dist.Pick(dist.Field("method", methodDist), len(httpMethods))]
+}
@@ -0,0 +1,207 @@
+package flog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit"
+	"github.com/grafana/explore-logs/generator/dist"
+)
+
+// Request is a generated HTTP request. Its method, size and latency depend on the endpoint and
+// the status, so every format rendering it tells the same story.
+type Request struct {
+	Time     time.Time
+	Host     string
+	User     string
+	Method   string
+	URI      string
+	Protocol string
+	Status   int
+	Bytes    int
+	Referer  string
+	Agent    string
+	// Duration is the total time spent serving the request.
+	Duration time.Duration
+	// Upstream is the time spent waiting for the upstream, negative when the proxy answered itself.
+	Upstream time.Duration
+	// Timing adds request_time and upstream_response_time to the rendered line.
+	Timing bool
+}
+
+// Endpoint describes the requests served by a URI. Latencies are in seconds.
+type Endpoint struct {
+	// Methods are picked uniformly, RandHTTPMethod is used when empty.
+	Methods []string
+	Latency dist.Distribution
+	Bytes   dist.Distribution
+}
+
+// ErrorProfile describes how requests failing with a status behave.
+type ErrorProfile struct {
+	// LatencyFactor scales the endpoint latency, it is ignored when Latency is set.
+	LatencyFactor float64
+	Latency       dist.Distribution
+	Bytes         dist.Distribution
+	// Upstream is false when the proxy answers without reaching the upstream.
+	Upstream bool
+}
+
+// ErrorProfiles gives errors latencies of their own: client errors are rejected early, a 502
+// fails fast on a broken connection while a 504 waits for the upstream timeout.
+var ErrorProfiles = map[int]ErrorProfile{
+	400: {LatencyFactor: 0.3, Bytes: dist.LogNormal{Median: 150, Sigma: 0.3}, Upstream: true},
+	401: {Latency: dist.LogNormal{Median: 0.002, Sigma: 0.5}, Bytes: dist.LogNormal{Median: 120, Sigma: 0.2}},
+	403: {Latency: dist.LogNormal{Median: 0.002, Sigma: 0.5}, Bytes: dist.LogNormal{Median: 130, Sigma: 0.2}},
+	404: {LatencyFactor: 0.2, Bytes: dist.LogNormal{Median: 160, Sigma: 0.2}, Upstream: true},
+	429: {Latency: dist.LogNormal{Median: 0.001, Sigma: 0.5}, Bytes: dist.LogNormal{Median: 90, Sigma: 0.2}},
+	500: {LatencyFactor: 2, Bytes: dist.LogNormal{Median: 250, Sigma: 0.5}, Upstream: true},
+	502: {Latency: dist.LogNormal{Median: 0.005, Sigma: 1}, Bytes: dist.LogNormal{Median: 160, Sigma: 0.1}, Upstream: true},
+	503: {Latency: dist.LogNormal{Median: 0.001, Sigma: 0.5}, Bytes: dist.LogNormal{Median: 190, Sigma: 0.1}},
+	504: {Latency: dist.Normal{Mean: 30, StdDev: 0.05}, Bytes: dist.LogNormal{Median: 170, Sigma: 0.1}, Upstream: true},
+}
+
+var (
+	endpointsMtx sync.Mutex
+	endpoints    = map[string]Endpoint{}
+)
+
+// SetEndpoint configures the requests served by a URI.
+func SetEndpoint(uri string, e Endpoint) {
+	endpointsMtx.Lock()
+	defer endpointsMtx.Unlock()
+	endpoints[uri] = e
+}
+
+// EndpointFor returns the endpoint configured for a URI. Unknown URIs get a profile derived from
+// the URI, so the same URI is always about as fast and as large.
+func EndpointFor(uri string) Endpoint {
+	endpointsMtx.Lock()
+	defer endpointsMtx.Unlock()
+	if e, ok := endpoints[uri]; ok {
+		return e
+	}
+	e := defaultEndpoint(uri)
+	endpoints[uri] = e
+	return e
+}
+
+func defaultEndpoint(uri string) Endpoint {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(uri))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	// Medians between 5ms and 200ms and between 200B and 20kB.
+	latency := 0.005 * math.Pow(40, r.Float64())
+	size := 200 * math.Pow(100, r.Float64())
+
+	path := strings.ToLower(uri)
+	switch {
+	case strings.Contains(path, "push"), strings.Contains(path, "ingest"), strings.Contains(path, "write"):
+		return Endpoint{Methods: []string{"POST"}, Latency: dist.LogNormal{Median: latency, Sigma: 0.6}, Bytes: dist.LogNormal{Median: 30, Sigma: 0.3}}
+	case strings.Contains(path, "query"), strings.Contains(path, "search"):
+		return Endpoint{Methods: []string{"GET", "GET", "POST"}, Latency: dist.LogNormal{Median: latency * 5, Sigma: 1.2}, Bytes: dist.LogNormal{Median: size * 10, Sigma: 1.5}}
+	case strings.HasSuffix(path, ".css"), strings.HasSuffix(path, ".js"), strings.HasSuffix(path, ".png"), strings.HasSuffix(path, ".jpg"):
+		return Endpoint{Methods: []string{"GET"}, Latency: dist.LogNormal{Median: 0.001, Sigma: 0.5}, Bytes: dist.LogNormal{Median: size * 5, Sigma: 0.1}}
+	}
+	return Endpoint{Latency: dist.LogNormal{Median: latency, Sigma: 0.8}, Bytes: dist.LogNormal{Median: size, Sigma: 1}}
+}
+
+// NewRequest generates a request to uri answered with status at t. The "latency" and "bytes"
+// fields of dist.Configure override the endpoint distributions of successful requests.
+func NewRequest(t time.Time, uri string, status int) Request {
+	e := EndpointFor(uri)
+	r := Request{
+		Time:     t,
+		Host:     FakeIP(),
+		User:     RandAuthUserID(),
+		Method:   RandHTTPMethod(),
+		URI:      uri,
+		Protocol: RandHTTPVersion(),
+		Status:   status,
+		Referer:  gofakeit.URL(),
+		Agent:    gofakeit.UserAgent(),
+	}
+	if len(e.Methods) > 0 {
+		r.Method = e.Methods[rand.Intn(len(e.Methods))]
+	}
+
+	latency := dist.Field("latency", e.Latency).Sample()
+	size := dist.Field("bytes", e.Bytes).Sample()
+	upstream := true
+	if p, ok := ErrorProfiles[status]; ok {
+		switch {
+		case p.Latency != nil:
+			latency = p.Latency.Sample()
+		case p.LatencyFactor > 0:
+			latency *= p.LatencyFactor
+		}
+		size = p.Bytes.Sample()
+		upstream = p.Upstream
+	}
+	if r.Method == "HEAD" || status == 204 || status == 304 {
+		size = 0
+	}
+
+	r.Bytes = int(math.Max(size, 0))
+	r.Duration = time.Duration(math.Max(latency, 0.0001) * float64(time.Second)).Round(time.Millisecond)
+	r.Upstream = -1
+	if upstream {
+		// The proxy adds a few milliseconds on top of the upstream.
+		r.Upstream = max(r.Duration-time.Duration(rand.Intn(3))*time.Millisecond, 0)
+	}
+	return r
+}
+
+// RequestTime formats the duration in seconds with millisecond resolution, the way nginx logs $request_time.
+func (r Request) RequestTime() string {
+	return strconv.FormatFloat(r.Duration.Seconds(), 'f', 3, 64)
+}
+
+// UpstreamResponseTime formats the upstream time like nginx $upstream_response_time, "-" without upstream.
+func (r Request) UpstreamResponseTime() string {
+	if r.Upstream < 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(r.Upstream.Seconds(), 'f', 3, 64)
+}
+
+func (r Request) timing() string {
+	if !r.Timing {
+		return ""
+	}
+	return fmt.Sprintf(" request_time=%s upstream_response_time=%s", r.RequestTime(), r.UpstreamResponseTime())
+}
+
+// CommonLog renders the request in common log format.
+func (r Request) CommonLog() string {
+	return fmt.Sprintf(CommonLogFormat, r.Host, r.User, r.Time.Format(CommonLog), r.Method, r.URI, r.Protocol, r.Status, r.Bytes) + r.timing()
+}
+
+// ApacheCommon renders the request in apache common log format.
+func (r Request) ApacheCommon() string {
+	return fmt.Sprintf(ApacheCommonLog, r.Host, r.User, r.Time.Format(Apache), r.Method, r.URI, r.Protocol, r.Status, r.Bytes) + r.timing()
+}
+
+// ApacheCombined renders the request in apache combined log format.
+func (r Request) ApacheCombined() string {
+	return fmt.Sprintf(ApacheCombinedLog, r.Host, r.User, r.Time.Format(Apache), r.Method, r.URI, r.Protocol, r.Status, r.Bytes, r.Referer, r.Agent) + r.timing()
+}
+
+// JSON renders the request in json log format.
+func (r Request) JSON() string {
+	line := fmt.Sprintf(JSONLogFormat, r.Host, r.User, r.Time.Format(CommonLog), r.Method, r.URI, r.Protocol, r.Status, r.Bytes, r.Referer, gofakeit.Number(0, 25))
+	if !r.Timing {
+		return line
+	}
+	upstream := r.UpstreamResponseTime()
+	if upstream == "-" {
+		upstream = "null"
+	}
+	return fmt.Sprintf(`%s, "request_time":%s, "upstream_response_time":%s}`, strings.TrimSuffix(line, "}"), r.RequestTime(), upstream)
+}
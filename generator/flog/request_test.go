@@ -0,0 +1,92 @@
+package flog
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func medianDuration(uri string, status int) time.Duration {
+	durations := make([]time.Duration, 1000)
+	for i := range durations {
+		durations[i] = NewRequest(time.Now(), uri, status).Duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations[len(durations)/2]
+}
+
+func TestNewRequest(t *testing.T) {
+	a := assert.New(t)
+	SetEndpoint("/test/slow", Endpoint{Methods: []string{"PUT"}, Latency: dist.Uniform{Min: 1, Max: 1}, Bytes: dist.Uniform{Min: 5000, Max: 5000}})
+
+	r := NewRequest(time.Now(), "/test/slow", 200)
+	a.Equal("PUT", r.Method)
+	a.Equal(time.Second, r.Duration)
+	a.Equal(5000, r.Bytes)
+	a.LessOrEqual(r.Upstream, r.Duration)
+	a.GreaterOrEqual(r.Upstream, r.Duration-2*time.Millisecond)
+
+	r = NewRequest(time.Now(), "/test/slow", 500)
+	a.Equal(2*time.Second, r.Duration, "server errors take longer")
+	a.Less(r.Bytes, 5000)
+
+	r = NewRequest(time.Now(), "/test/slow", 429)
+	a.Less(r.Duration, 100*time.Millisecond, "rate limited requests are rejected early")
+	a.Equal("-", r.UpstreamResponseTime())
+
+	a.InDelta(30*time.Second, NewRequest(time.Now(), "/test/slow", 504).Duration, float64(time.Second))
+	a.Zero(NewRequest(time.Now(), "/test/slow", 304).Bytes)
+}
+
+func TestDefaultEndpoints(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(EndpointFor("/api/v1/users"), EndpointFor("/api/v1/users"))
+	a.Equal([]string{"POST"}, EndpointFor("/api/v1/push").Methods)
+
+	// Queries take at least 25ms on average, static files a millisecond.
+	a.Greater(medianDuration("/api/v1/query", 200), 20*time.Millisecond)
+	a.Less(medianDuration("/static/app.js", 200), 5*time.Millisecond)
+}
+
+func TestRequestFormats(t *testing.T) {
+	a := assert.New(t)
+	r := testRequest()
+	a.Equal(`10.0.0.1 - - [01/May/2024:10:00:00 +0000] "GET /api/loki/v1/query HTTP/1.1" 200 1234`, r.CommonLog())
+	a.Equal(`10.0.0.1 - - [01/May/2024:10:00:00 +0000] "GET /api/loki/v1/query HTTP/1.1" 200 1234 "https://grafana.com" "curl/8.0"`, r.ApacheCombined())
+
+	r.Timing = true
+	a.Equal(`10.0.0.1 - - [01/May/2024:10:00:00 +0000] "GET /api/loki/v1/query HTTP/1.1" 200 1234 request_time=1.500 upstream_response_time=1.498`, r.CommonLog())
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal([]byte(r.JSON()), &fields))
+	a.Equal(1.5, fields["request_time"])
+	a.Equal(1.498, fields["upstream_response_time"])
+	a.Equal(1234.0, fields["bytes"])
+
+	r.Upstream = -1
+	require.NoError(t, json.Unmarshal([]byte(r.JSON()), &fields))
+	a.Nil(fields["upstream_response_time"])
+}
+
+func TestHTTPFormatsAreConsistent(t *testing.T) {
+	a := assert.New(t)
+	line := regexp.MustCompile(`"(\w+) (\S+) HTTP/[\d.]+" (\d+) (\d+)`)
+	for i := 0; i < 100; i++ {
+		m := line.FindStringSubmatch(NewApacheCombinedLog(time.Now(), "/api/v1/push", 204))
+		require.NotNil(t, m)
+		a.Equal("POST", m[1])
+		a.Equal("204", m[3])
+		a.Equal("0", m[4])
+
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal([]byte(NewJSONLogFormat(time.Now(), "/api/v1/push", 401)), &fields))
+		a.Equal("POST", fields["method"])
+		a.Less(fields["bytes"], 1000.0)
+	}
+}
@@ -3,74 +3,122 @@ package main
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/grafana/explore-logs/generator/aws"
+	"github.com/grafana/explore-logs/generator/catalog"
+	"github.com/grafana/explore-logs/generator/database"
+	"github.com/grafana/explore-logs/generator/dist"
 	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/kube"
+	"github.com/grafana/explore-logs/generator/lifecycle"
+	"github.com/grafana/explore-logs/generator/load"
 	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/explore-logs/generator/platform"
+	"github.com/grafana/explore-logs/generator/rollout"
+	"github.com/grafana/explore-logs/generator/scenario"
+	"github.com/grafana/explore-logs/generator/security"
+	"github.com/grafana/explore-logs/generator/stacktrace"
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
 )
 
 type LogGenerator func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter)
 
+// levelProfiles gives some services a level mix of their own, others use log.DefaultLevelWeights.
+var levelProfiles = log.LevelConfig{
+	Services: map[model.LabelValue]log.LevelProfile{
+		"httpd": {
+			Weights:  log.LevelWeights{log.TRACE: 20, log.DEBUG: 25, log.INFO: 40, log.WARN: 8, log.ERROR: 4, log.CRITICAL: 1, log.UNKNOWN: 2},
+			Statuses: log.LevelStatuses{log.WARN: {400, 401, 403, 404, 429}, log.ERROR: {500, 502, 504}},
+		},
+		"order-processor": lambdaLevels,
+		"image-resizer":   lambdaLevels,
+		"postgres":        databaseLevels,
+		"mysql":           databaseLevels,
+		"mongodb":         databaseLevels,
+	},
+}
+
+// lambdaLevels is the level mix of the Lambda functions, critical invocations time out.
+var lambdaLevels = log.LevelProfile{
+	Weights: log.LevelWeights{log.DEBUG: 20, log.INFO: 68, log.WARN: 5, log.ERROR: 5, log.CRITICAL: 2},
+}
+
+// databaseLevels is the level mix of the database servers, which rarely but sometimes crash.
+var databaseLevels = log.LevelProfile{
+	Weights: log.LevelWeights{log.DEBUG: 20, log.INFO: 65, log.WARN: 8, log.ERROR: 5, log.CRITICAL: 1, log.FATAL: 1},
+}
+
 var generators = map[model.LabelValue]map[model.LabelValue]LogGenerator{
 	"gateway": {
 		"apache": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
 			go func() {
 				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewApacheCommonLog(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					level := logger.RandLevel()
+					t := load.Now()
+					logger.LogWithMetadata(level, t, flog.NewApacheCommonLog(t, log.RandURI(), logger.Status(level)), metadata)
+					load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 				}
 			}()
 		},
 		"httpd": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
 			go func() {
 				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewApacheCombinedLog(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					level := logger.RandLevel()
+					t := load.Now()
+					req := flog.NewRequest(t, log.RandURI(), logger.Status(level))
+					req.Timing = true
+					logger.LogWithMetadata(level, t, req.ApacheCombined(), metadata)
+					load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 				}
 			}()
 		},
 		"nginx": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
 			go func() {
 				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewCommonLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					level := logger.RandLevel()
+					t := load.Now()
+					logger.LogWithMetadata(level, t, flog.NewCommonLogFormat(t, log.RandURI(), logger.Status(level)), metadata)
+					load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 				}
 			}()
 		},
 		"nginx-json": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
 			go func() {
 				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					level := logger.RandLevel()
+					t := load.Now()
+					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), logger.Status(level)), metadata)
+					load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 				}
 			}()
 		},
 		"nginx-json-mixed": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
 			go func() {
 				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
+					level := logger.RandLevel()
+					t := load.Now()
 					if level == log.ERROR {
-						log := flog.NewCommonLogFormat(t, log.RandURI(), statusFromLevel(level))
+						log := flog.NewCommonLogFormat(t, log.RandURI(), logger.Status(level))
 						// Add a stacktrace to the logfmt log, and include a field that will conflict with stream selectors
-						logger.LogWithMetadata(level, t, fmt.Sprintf("%s %s", log, `method=GET namespace=whoopsie caller=flush.go:253 stacktrace="Exception in thread \"main\" java.lang.NullPointerException\n        at com.example.myproject.Book.getTitle(Book.java:16)\n        at com.example.myproject.Author.getBookTitles(Author.java:25)\n        at com.example.myproject.Bootstrap.main(Bootstrap.java:14)"`), metadata)
+						trace := stacktrace.New(stacktrace.Java, string(logger.Labels()["service_name"]), fmt.Sprint(rand.Intn(3)))
+						logger.LogWithMetadata(level, t, fmt.Sprintf("%s method=GET namespace=whoopsie caller=flush.go:253 stacktrace=%s", log, strconv.Quote(trace.String())), metadata)
 					}
-					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), logger.Status(level)), metadata)
+					load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 				}
 			}()
 		},
+		"envoy":       proxyAccessLog(flog.Request.Envoy),
+		"istio-proxy": proxyAccessLog(flog.Request.EnvoyJSON),
+		"haproxy":     proxyAccessLog(flog.Request.HAProxy),
+		"traefik":     proxyAccessLog(flog.Request.Traefik),
+		"nginx-error": nginxErrorLog,
 	},
 
 	"mimir-dev": {
@@ -90,6 +138,47 @@ var generators = map[model.LabelValue]map[model.LabelValue]LogGenerator{
 		"tempo-ingester":    noisyTempo,
 		"tempo-distributor": noisyTempo,
 	},
+	"polyglot": {
+		"checkout-java":         stackTraceApp(stacktrace.Java),
+		"search-go":             stackTraceApp(stacktrace.Go),
+		"recommendation-python": stackTraceApp(stacktrace.Python),
+		"frontend-node":         stackTraceApp(stacktrace.Node),
+		"billing-dotnet":        stackTraceApp(stacktrace.DotNet),
+		"storefront-ruby":       stackTraceApp(stacktrace.Ruby),
+	},
+	"db": {
+		"postgres": databaseServer(database.Postgres),
+		"mysql":    databaseServer(database.MySQL),
+		"mongodb":  databaseServer(database.Mongo),
+	},
+	"aws": {
+		"alb":             awsRequests(aws.ALB),
+		"cloudfront":      cloudFront,
+		"vpc-flow-logs":   vpcFlowLogs,
+		"cloudtrail":      cloudTrail,
+		"order-processor": lambdaFunction(aws.Functions["order-processor"]),
+		"image-resizer":   lambdaFunction(aws.Functions["image-resizer"]),
+	},
+	"security": {
+		"sshd":     sshd,
+		"sudo":     sudo,
+		"auditd":   auditd,
+		"iptables": firewallDrops(security.IptablesPrefix),
+		"nftables": firewallDrops(security.NftablesPrefix),
+		"waf":      waf,
+	},
+	"queue": {
+		"kafka":    kafkaBroker,
+		"rabbitmq": rabbitMQ,
+	},
+	"cache": {
+		"redis": redis,
+	},
+	"kube-system": {
+		"kube-events":    kubeEvents,
+		"kube-apiserver": kubeAudit,
+		"etcd":           etcd,
+	},
 	"loki-otel": {
 		"loki-ingester-otel":      lokiOtelPod("loki-ingester-otel"),
 		"loki-querier-otel":       lokiOtelPod("loki-querier-otel"),
@@ -121,15 +210,22 @@ func lokiOtelPod(svc string) LogGenerator {
 		for k, v := range serviceLogs {
 			go func() {
 				for ctx.Err() == nil {
-					t := time.Now()
+					t := load.Now()
 					logger.LogWithMetadata(k, t, v, log.RandStructuredMetadata("loki-ingester", 0))
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 				}
 			}()
 		}
 	}
 }
 
+// tempoRollout moves Tempo to the next weekly release, which changes a few of its lines and
+// adds a new error. It never starts unless main sets its timing.
+var tempoRollout = rollout.Rollout{From: "weekly-r138", To: "weekly-r139"}
+
+// tempoRevisions are the git revisions of the Tempo versions.
+var tempoRevisions = map[string]string{"weekly-r138": "f1920489", "weekly-r139": "3c84a0e7"}
+
 var noisyTempo = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
 	const fmt1 = `level=debug ts=%s caller=broadcast.go:48 msg="Invalidating forwarded broadcast" key=collectors/compactor version=%d oldVersion=%d content=[compactor-%s] oldContent=[compactor-%s]`
 	const fmt2 = `level=warn ts=%s caller=instance.go:43 msg="TRACE_TOO_LARGE: max size of trace (52428800) exceeded tenant %s"`
@@ -138,61 +234,502 @@ var noisyTempo = func(ctx context.Context, logger *log.AppLogger, metadata push.
 	const fmt5 = `level=info ts=%s caller=flush.go:253 msg="completing block" userid=%s blockID=%s`
 	const fmt6 = `level=error ts=%s caller=memcached.go:153 msg="Failed to get keys from memcached" err="memcache: connect timeout to %s:11211"`
 	const fmt7 = `level=info ts=%s caller=registry.go:232 tenant=%s msg="collecting metrics" active_series=%d`
-	const fmt8 = `level=info ts=%s caller=main.go:107 msg="Starting Grafana Enterprise Traces" version="version=weekly-r138-f1920489, branch=weekly-r138, revision=f1920489"`
+	const fmt8 = `level=info ts=%s caller=main.go:107 msg="Starting Grafana Enterprise Traces" version="version=%s-%s, branch=%s, revision=%s"`
+	// The next version logs its flushes in a new shape, moves to a generic cache client and
+	// fails some searches.
+	const fmt3Next = `level=info ts=%s caller=compactor.go:251 msg="flushed to block" tenant=%s blockID=%s size=%dB objects=%d duration=%s`
+	const fmt6Next = `level=error ts=%s caller=cache.go:98 msg="cache fetch failed" backend=memcached addr=%s:11211 keys=%d err="context deadline exceeded"`
+	const fmt9Next = `level=error ts=%s caller=searchsharding.go:417 msg="failed to search block" tenant=%s blockID=%s err="rpc error: code = ResourceExhausted desc = grpc: received message larger than max (%d vs. 4194304)"`
+
+	stream := tempoRollout.Stream()
+	current, next := tempoRollout.Metadata(metadata)
+	// version returns whether the stream runs the new version at t, and its metadata.
+	version := func(t time.Time) (bool, push.LabelsAdapter) {
+		if stream.Upgraded(t) {
+			return true, next
+		}
+		return false, current
+	}
+	banner := func(t time.Time) string {
+		v := stream.Version(t)
+		return fmt.Sprintf(fmt8, t.Format(time.RFC3339Nano), v, tempoRevisions[v], v, tempoRevisions[v])
+	}
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
+			_, metadata := version(t)
 			logger.LogWithMetadata(log.DEBUG, t, fmt.Sprintf(fmt1, t.Format(time.RFC3339Nano), rand.Intn(100), rand.Intn(100), log.RandSeq(5), log.RandSeq(5)), metadata)
-			time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
+			_, metadata := version(t)
 			logger.LogWithMetadata(log.WARN, t, fmt.Sprintf(fmt2, t.Format(time.RFC3339Nano), log.RandOrgID()), metadata)
-			time.Sleep(time.Duration(rand.Intn(3000)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(3000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt3, t.Format(time.RFC3339Nano), rand.Intn(1000), rand.Intn(1000), rand.Intn(1000)), metadata)
-			time.Sleep(time.Duration(rand.Intn(4000)) * time.Millisecond)
+			t := load.Now()
+			if upgraded, metadata := version(t); upgraded {
+				logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt3Next, t.Format(time.RFC3339Nano), log.RandOrgID(), log.RandSeq(5), rand.Intn(1000), rand.Intn(1000), log.RandDuration()), metadata)
+			} else {
+				logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt3, t.Format(time.RFC3339Nano), rand.Intn(1000), rand.Intn(1000), rand.Intn(1000)), metadata)
+			}
+			load.Sleep(time.Duration(rand.Intn(4000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
+			_, metadata := version(t)
 			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt4, t.Format(time.RFC3339Nano), rand.Intn(1000)), metadata)
-			time.Sleep(time.Duration(rand.Intn(7000)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(7000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
+			_, metadata := version(t)
 			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt5, t.Format(time.RFC3339Nano), log.RandOrgID(), log.RandSeq(5)), metadata)
-			time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.ERROR, t, fmt.Sprintf(fmt6, t.Format(time.RFC3339Nano), flog.FakeIP()), metadata)
-			time.Sleep(time.Duration(rand.Intn(2000)) * time.Millisecond)
+			t := load.Now()
+			if upgraded, metadata := version(t); upgraded {
+				logger.LogWithMetadata(log.ERROR, t, fmt.Sprintf(fmt6Next, t.Format(time.RFC3339Nano), flog.FakeIP(), 1+rand.Intn(100)), metadata)
+			} else {
+				logger.LogWithMetadata(log.ERROR, t, fmt.Sprintf(fmt6, t.Format(time.RFC3339Nano), flog.FakeIP()), metadata)
+			}
+			load.Sleep(time.Duration(rand.Intn(2000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
+			_, metadata := version(t)
 			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt7, t.Format(time.RFC3339Nano), log.RandOrgID(), rand.Intn(1000)), metadata)
-			time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt8, t.Format(time.RFC3339Nano)), metadata)
-			time.Sleep(20 * time.Second)
+			t := load.Now()
+			if upgraded, metadata := version(t); upgraded {
+				logger.LogWithMetadata(log.ERROR, t, fmt.Sprintf(fmt9Next, t.Format(time.RFC3339Nano), log.RandOrgID(), log.RandSeq(5), 4194304+rand.Intn(1<<22)), metadata)
+				load.Sleep(time.Duration(rand.Intn(6000)) * time.Millisecond)
+				continue
+			}
+			// Wait for the new version.
+			load.Sleep(time.Second)
+		}
+	}()
+	go func() {
+		upgraded := false
+		for ctx.Err() == nil {
+			t := load.Now()
+			var metadata push.LabelsAdapter
+			upgraded, metadata = version(t)
+			logger.LogWithMetadata(log.INFO, t, banner(t), metadata)
+			// The new version starts as soon as the stream moves to it.
+			for i := 0; i < 20 && ctx.Err() == nil; i++ {
+				if u, _ := version(load.Now()); u != upgraded {
+					break
+				}
+				load.Sleep(time.Second)
+			}
+		}
+	}()
+}
+
+// splitStackTraces logs every line of the stack traces as an entry of its own, instead of one
+// multiline entry.
+var splitStackTraces bool
+
+// stackTraceApp serves requests and logs the stack traces of its failures in the format of lang,
+// one per error of its catalog.
+func stackTraceApp(lang stacktrace.Language) LogGenerator {
+	return func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+		service := string(logger.Labels()["service_name"])
+		go func() {
+			for ctx.Err() == nil {
+				level := logger.RandLevel()
+				t := load.Now()
+				method, path := flog.RandHTTPMethod(), log.RandURI()
+				if level != log.ERROR && level != log.CRITICAL {
+					d := time.Duration(dist.Between(dist.LogNormal{Median: 0.05, Sigma: 1}, 0.001, 10) * float64(time.Second))
+					logger.LogWithMetadata(level, t, stacktrace.Access(lang, t, method, path, logger.Status(level), d), metadata)
+				} else {
+					entry := catalog.For(service, t).Pick(t).Trace(lang, service).Entry(t, method, path)
+					if splitStackTraces {
+						times, lines := stacktrace.Split(t, entry)
+						for i, line := range lines {
+							logger.LogWithMetadata(level, times[i], line, metadata)
+						}
+					} else {
+						logger.LogWithMetadata(level, t, entry, metadata)
+					}
+				}
+				load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			}
+		}()
+	}
+}
+
+// proxyAccessLog logs the requests going through a proxy, rendered by format.
+func proxyAccessLog(format func(flog.Request) string) LogGenerator {
+	return func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+		go func() {
+			for ctx.Err() == nil {
+				level := logger.RandLevel()
+				t := load.Now()
+				logger.LogWithMetadata(level, t, format(flog.NewRequest(t, log.RandURI(), logger.Status(level))), metadata)
+				load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			}
+		}()
+	}
+}
+
+// nginxSeverities are the severities of the nginx error log by level.
+var nginxSeverities = map[model.LabelValue]string{
+	log.TRACE:    "debug",
+	log.DEBUG:    "debug",
+	log.INFO:     "info",
+	log.WARN:     "warn",
+	log.ERROR:    "error",
+	log.CRITICAL: "crit",
+}
+
+// nginxErrorLog logs the error log of nginx, explaining the failed requests.
+var nginxErrorLog = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	go func() {
+		for ctx.Err() == nil {
+			level := logger.RandLevel()
+			t := load.Now()
+			severity, ok := nginxSeverities[level]
+			if !ok {
+				severity = "notice"
+			}
+			logger.LogWithMetadata(level, t, flog.NewRequest(t, log.RandURI(), logger.Status(level)).NginxError(severity), metadata)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+}
+
+// databaseServer logs the entries of a database server, rendered by entry at the level.
+func databaseServer(entry func(t time.Time, level model.LabelValue) string) LogGenerator {
+	return func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+		go func() {
+			for ctx.Err() == nil {
+				level := logger.RandLevel()
+				t := load.Now()
+				logger.LogWithMetadata(level, t, entry(t, level), metadata)
+				load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			}
+		}()
+	}
+}
+
+// awsRequests logs the requests served by an AWS service in the region of its cluster, rendered
+// by format.
+func awsRequests(format func(r flog.Request, region string) string) LogGenerator {
+	return func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+		region := string(logger.Labels()["cluster"])
+		go func() {
+			for ctx.Err() == nil {
+				level := logger.RandLevel()
+				t := load.Now()
+				logger.LogWithMetadata(level, t, format(flog.NewRequest(t, log.RandURI(), logger.Status(level)), region), metadata)
+				load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			}
+		}()
+	}
+}
+
+// cloudFront logs the standard logs of a distribution, starting a new log file every hour with
+// its header lines.
+var cloudFront = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	region := string(logger.Labels()["cluster"])
+	go func() {
+		var file time.Time
+		for ctx.Err() == nil {
+			level := logger.RandLevel()
+			t := load.Now()
+			if t.Truncate(time.Hour) != file {
+				file = t.Truncate(time.Hour)
+				for _, line := range aws.CloudFrontHeader() {
+					logger.LogWithMetadata(log.INFO, t, line, metadata)
+				}
+			}
+			logger.LogWithMetadata(level, t, aws.CloudFront(flog.NewRequest(t, log.RandURI(), logger.Status(level)), region), metadata)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+}
+
+// vpcFlowLogs logs the flow logs of the VPC of its cluster, with the fields of version 5.
+var vpcFlowLogs = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	region := string(logger.Labels()["cluster"])
+	go func() {
+		for ctx.Err() == nil {
+			f := aws.NewFlowLog(load.Now(), region)
+			logger.LogWithMetadata(f.Level(), f.End, f.Format(aws.FlowLogV5Fields), metadata)
+			load.Sleep(time.Duration(rand.Intn(2000)) * time.Millisecond)
+		}
+	}()
+}
+
+// cloudTrail logs the CloudTrail events of the region of its cluster.
+var cloudTrail = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	region := string(logger.Labels()["cluster"])
+	go func() {
+		for ctx.Err() == nil {
+			t := load.Now()
+			e := aws.NewCloudTrailEvent(t, region)
+			logger.LogWithMetadata(e.Level(), t, e.String(), metadata)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+}
+
+// lambdaFunction logs the invocations of a Lambda function deployed in the region of its cluster.
+func lambdaFunction(f aws.Function) LogGenerator {
+	return func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+		region := string(logger.Labels()["cluster"])
+		go func() {
+			for ctx.Err() == nil {
+				// Lines are logged when they happen, the next invocation starts after the last one.
+				for _, line := range f.Invoke(load.Now(), region, logger.RandLevel()) {
+					if !load.Wait(ctx, line.Time) {
+						return
+					}
+					logger.LogWithMetadata(line.Level, line.Time, line.Text, metadata)
+				}
+				load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			}
+		}()
+	}
+}
+
+// attacks are the attacks against the hosts of the security namespace, main schedules them.
+var attacks = security.NewAttacks()
+
+// securityHost returns the host of a stream of the security namespace, a node of its cluster.
+func securityHost(logger *log.AppLogger) *security.Host {
+	nodes := log.Nodes(string(logger.Labels()["cluster"]))
+	return security.NewHost(nodes[rand.Intn(len(nodes))], load.Now())
+}
+
+func logSecurityLines(logger *log.AppLogger, lines []log.Line, metadata push.LabelsAdapter) {
+	for _, line := range lines {
+		logger.LogWithMetadata(line.Level, line.Time, line.Text, metadata)
+	}
+}
+
+// duringAttack calls f at random intervals of up to interval while an attack of kind runs, with
+// the address of the attacker.
+func duringAttack(ctx context.Context, kind security.Attack, interval time.Duration, f func(t time.Time, source string)) {
+	go func() {
+		for ctx.Err() == nil {
+			t := load.Now()
+			if source, ok := attacks.Active(kind, t); ok {
+				f(t, source)
+				load.Sleep(time.Duration(rand.Int63n(int64(interval))))
+				continue
+			}
+			load.Sleep(time.Second)
+		}
+	}()
+}
+
+// sshd logs the sshd messages of the auth.log of a host, and the attempts of SSH brute force
+// attacks.
+var sshd = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	host := securityHost(logger)
+	go func() {
+		for ctx.Err() == nil {
+			logSecurityLines(logger, host.SSHD(load.Now(), logger.RandLevel()), metadata)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+	duringAttack(ctx, security.BruteForce, time.Second, func(t time.Time, source string) {
+		logSecurityLines(logger, host.BruteForce(t, source), metadata)
+	})
+}
+
+// sudo logs the sudo messages of the auth.log of a host.
+var sudo = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	host := securityHost(logger)
+	go func() {
+		for ctx.Err() == nil {
+			logSecurityLines(logger, host.Sudo(load.Now(), logger.RandLevel()), metadata)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+}
+
+// auditd logs the audit records of a host, with the failed logins of SSH brute force attacks.
+var auditd = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	host := securityHost(logger)
+	go func() {
+		for ctx.Err() == nil {
+			logSecurityLines(logger, host.Audit(load.Now(), logger.RandLevel()), metadata)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+	duringAttack(ctx, security.BruteForce, time.Second, func(t time.Time, source string) {
+		logSecurityLines(logger, host.FailedLogin(t, source), metadata)
+	})
+}
+
+// firewallDrops logs the packets dropped by the firewall of a host with the rule logging with
+// prefix, and the probes of port scans.
+func firewallDrops(prefix string) LogGenerator {
+	return func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+		host := securityHost(logger)
+		go func() {
+			for ctx.Err() == nil {
+				line := host.Drop(load.Now(), prefix, host.NewPacket())
+				logger.LogWithMetadata(line.Level, line.Time, line.Text, metadata)
+				load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			}
+		}()
+		duringAttack(ctx, security.PortScan, time.Second, func(t time.Time, source string) {
+			for i, port := range security.ScanPorts(5 + rand.Intn(20)) {
+				line := host.Drop(t.Add(time.Duration(i)*time.Millisecond), prefix, host.ScanPacket(source, port))
+				logger.LogWithMetadata(line.Level, line.Time, line.Text, metadata)
+			}
+		})
+	}
+}
+
+// waf logs the transactions of the web application firewall of a host, and the logins of
+// credential stuffing attacks.
+var waf = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	host := securityHost(logger)
+	go func() {
+		for ctx.Err() == nil {
+			t := load.Now()
+			e := host.WAF(t, logger.RandLevel())
+			logger.LogWithMetadata(e.Level(), t, e.String(), metadata)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+	duringAttack(ctx, security.CredentialStuffing, 500*time.Millisecond, func(t time.Time, _ string) {
+		e := host.CredentialStuffing(t)
+		logger.LogWithMetadata(e.Level(), t, e.String(), metadata)
+	})
+}
+
+// logPlatformLines logs the lines returned by lines for each random level, until ctx is done.
+func logPlatformLines(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter, lines func(t time.Time, level model.LabelValue) []log.Line) {
+	go func() {
+		for ctx.Err() == nil {
+			for _, line := range lines(load.Now(), logger.RandLevel()) {
+				logger.LogWithMetadata(line.Level, line.Time, line.Text, metadata)
+			}
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+}
+
+// platformAddress returns the private address of the pod with index i of a platform service in
+// a cluster, the same on every call so peers agree on it.
+func platformAddress(cluster, service string, i int) string {
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%s/%s/%d", cluster, service, i)
+	sum := h.Sum32()
+	return fmt.Sprintf("10.0.%d.%d", sum%16, 2+sum/16%250)
+}
+
+// platformPod returns the pod of a platform service, from its metadata or its pod label.
+func platformPod(logger *log.AppLogger, metadata push.LabelsAdapter) string {
+	for _, m := range metadata {
+		if m.Name == "pod" && m.Value != "" {
+			return m.Value
+		}
+	}
+	return string(logger.Labels()["pod"])
+}
+
+// podIndex returns the index of pod among n replicas: the ordinal of pods of stateful sets like
+// kafka-1, a hash of the name of other pods, modulo n.
+func podIndex(pod string, n int) int {
+	if i := strings.LastIndex(pod, "-"); i >= 0 {
+		if ordinal, err := strconv.Atoi(pod[i+1:]); err == nil && ordinal >= 0 {
+			return ordinal % n
+		}
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(pod))
+	return int(h.Sum32() % uint32(n))
+}
+
+// kafkaBroker logs the log4j logs of a broker of a cluster of three Kafka brokers, its pod
+// tells which one.
+var kafkaBroker = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	brokers := []int{1, 2, 3}
+	broker := platform.NewBroker(brokers[podIndex(platformPod(logger, metadata), len(brokers))], brokers)
+	logPlatformLines(ctx, logger, metadata, broker.Log)
+}
+
+// rabbitMQ logs the logs of a RabbitMQ node named after its pod.
+var rabbitMQ = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	pod := platformPod(logger, metadata)
+	address := platformAddress(string(logger.Labels()["cluster"]), "rabbitmq", podIndex(pod, log.StatefulServices["rabbitmq"]))
+	node := platform.NewRabbitMQ(pod, address)
+	logPlatformLines(ctx, logger, metadata, node.Log)
+}
+
+// redis logs the logs of a Redis server of a master with two replicas, its pod tells which one:
+// the first pod is the master and the others replicate it.
+var redis = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	addresses := make([]string, log.StatefulServices["redis"])
+	for i := range addresses {
+		addresses[i] = platformAddress(string(logger.Labels()["cluster"]), "redis", i) + ":6379"
+	}
+	server := platform.NewRedis(platform.Master, addresses[0], addresses[1:]...)
+	if i := podIndex(platformPod(logger, metadata), len(addresses)); i > 0 {
+		server = platform.NewRedis(platform.Replica, addresses[i], addresses[0])
+	}
+	logPlatformLines(ctx, logger, metadata, server.Log)
+}
+
+// etcd logs the logs of a member of the etcd cluster running on the first three nodes of its
+// cluster, its pod tells which one.
+var etcd = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	members := log.Nodes(string(logger.Labels()["cluster"]))[:3]
+	member := platform.NewEtcd(members[podIndex(platformPod(logger, metadata), len(members))], members)
+	logPlatformLines(ctx, logger, metadata, member.Log)
+}
+
+// kubeEvents exports the Kubernetes events of the pods of its cluster.
+var kubeEvents = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	events := kube.NewEvents(string(logger.Labels()["cluster"]))
+	logger = logger.WithLabels(model.LabelSet{"job": "integrations/kubernetes/eventhandler"})
+	go func() {
+		for ctx.Err() == nil {
+			for _, e := range events.Next(load.Now()) {
+				logger.LogWithMetadata(e.Level(), e.Time(), e.String(), metadata)
+			}
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+		}
+	}()
+}
+
+// kubeAudit logs the audit events of the API server of its cluster.
+var kubeAudit = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	cluster := string(logger.Labels()["cluster"])
+	logger = logger.WithLabels(model.LabelSet{"job": "kube-apiserver-audit", "log_type": "audit"})
+	go func() {
+		for ctx.Err() == nil {
+			e := kube.NewAuditEvent(load.Now(), cluster)
+			logger.LogWithMetadata(e.LogLevel(), e.Time(), e.String(), metadata)
+			load.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
 		}
 	}()
 }
@@ -200,9 +737,9 @@ var noisyTempo = func(ctx context.Context, logger *log.AppLogger, metadata push.
 var mimirPod = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
 			logger.LogWithMetadata(log.INFO, t, mimirGRPCLog("", "/cortex.Ingester/Push"), metadata)
-			time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
 		}
 	}()
 }
@@ -216,16 +753,16 @@ func startFailingMimirPod(ctx context.Context, logger log.Logger) {
 
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
 			appLogger.LogWithMetadata(log.ERROR, t, mimirGRPCLog("connection refused to object store", "/cortex.Ingester/Push"), log.RandStructuredMetadata("mimir-ingester", 0))
-			time.Sleep(time.Duration(rand.Intn(10000)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(10000)) * time.Millisecond)
 		}
 	}()
 	go func() {
 		for ctx.Err() == nil {
-			t := time.Now()
+			t := load.Now()
 			appLogger.LogWithMetadata(log.INFO, t, mimirGRPCLog("", "/cortex.Ingester/Push"), log.RandStructuredMetadata("mimir-ingester", 0))
-			time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
+			load.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
 		}
 	}()
 }
@@ -246,7 +783,7 @@ func mimirGRPCLog(err string, path string) string {
 
 	log := fmt.Sprintf(
 		mimirGrpcLogFmt,
-		time.Now().Format(time.RFC3339Nano),
+		load.Now().Format(time.RFC3339Nano),
 		org,
 		level,
 		path,
@@ -269,7 +806,7 @@ func lokiGRPCLog(err, path string) string {
 
 	log := fmt.Sprintf(
 		lokiGrpcLogFmt,
-		time.Now().Format(time.RFC3339Nano),
+		load.Now().Format(time.RFC3339Nano),
 		org,
 		level,
 		path,
@@ -282,15 +819,51 @@ func lokiGRPCLog(err, path string) string {
 	return log
 }
 
-func statusFromLevel(level model.LabelValue) int {
-	switch level {
-	case log.INFO:
-		return 200
-	case log.WARN:
-		return 400
-	case log.ERROR:
-		return 500
-	default:
-		return 200
+// scenarioGenerators creates a generator for every service of a scenario.
+func scenarioGenerators(s *scenario.Scenario, failures *scenario.Failures) map[model.LabelValue]map[model.LabelValue]LogGenerator {
+	out := map[model.LabelValue]map[model.LabelValue]LogGenerator{}
+	for _, svc := range s.Services {
+		if out[svc.Namespace] == nil {
+			out[svc.Namespace] = map[model.LabelValue]LogGenerator{}
+		}
+		out[svc.Namespace][svc.Name] = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+			svc.RunWithFailures(ctx, logger, metadata, failures)
+		}
+	}
+	return out
+}
+
+// startDeployments runs a service as a deployment in every cluster. Each pod is a stream of its
+// own, with the pod in its labels instead of its metadata.
+func startDeployments(ctx context.Context, namespace, serviceName model.LabelValue, generator LogGenerator, logger log.Logger, levels log.LevelProfile) {
+	for _, cluster := range log.Clusters {
+		opts := lifecycle.Options{Replicas: rand.Intn(5) + 1}
+		if log.ClusterServices[serviceName] {
+			opts.Replicas, opts.MaxReplicas = 1, 1
+		}
+		if n, ok := log.StatefulServices[serviceName]; ok {
+			opts.Replicas, opts.MinReplicas, opts.MaxReplicas = n, n, n
+		}
+		d := &lifecycle.Deployment{
+			Labels:  log.ClusterLabels(namespace, serviceName, cluster),
+			Logger:  logger,
+			Levels:  levels,
+			Options: opts,
+		}
+		d.Start(ctx, func(ctx context.Context, app *log.AppLogger, pod lifecycle.Pod) {
+			// Remove `metadata` from nginx logs
+			if serviceName == "nginx" {
+				generator(ctx, app, push.LabelsAdapter{})
+				return
+			}
+			metadata := log.RandStructuredMetadata(string(serviceName), 0)
+			withoutPod := metadata[:0]
+			for _, m := range metadata {
+				if m.Name != "pod" {
+					withoutPod = append(withoutPod, m)
+				}
+			}
+			generator(ctx, app, withoutPod)
+		})
 	}
 }
@@ -0,0 +1,294 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+)
+
+// Format is the layout of an input file.
+type Format string
+
+const (
+	// Auto detects the format from the first line.
+	Auto Format = "auto"
+	// Text is one entry per line, indented lines continue the previous entry.
+	Text Format = "text"
+	// JSONLines is one JSON object per line. Objects written by the json dry run format keep their labels.
+	JSONLines Format = "jsonl"
+	// Logcli is the default output of `logcli query`.
+	Logcli Format = "logcli"
+	// Loki is a query API response or a push request body, possibly one per line.
+	Loki Format = "loki"
+)
+
+// Formats lists the supported input formats.
+var Formats = []Format{Auto, Text, JSONLines, Logcli, Loki}
+
+var logcliLine = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\S+ \{`)
+
+// DetectFormat guesses the format from the beginning of a file.
+func DetectFormat(data []byte) Format {
+	first := bytes.TrimSpace(data)
+	if i := bytes.IndexByte(first, '\n'); i >= 0 {
+		first = first[:i]
+	}
+	switch {
+	case bytes.HasPrefix(first, []byte("{")) && (bytes.Contains(first, []byte(`"streams"`)) || bytes.Contains(first, []byte(`"resultType"`))):
+		return Loki
+	case bytes.HasPrefix(first, []byte("{")):
+		return JSONLines
+	case logcliLine.Match(first):
+		return Logcli
+	default:
+		return Text
+	}
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	return scanner
+}
+
+func readText(r io.Reader, fn func(log.Entry) error) error {
+	scanner := newScanner(r)
+	var pending *log.Entry
+	for scanner.Scan() {
+		line := scanner.Text()
+		if pending != nil && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			pending.Line += "\n" + line
+			continue
+		}
+		if pending != nil {
+			if err := fn(*pending); err != nil {
+				return err
+			}
+			pending = nil
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ts, _ := DetectTimestamp(line)
+		pending = &log.Entry{Timestamp: ts, Line: line}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if pending != nil {
+		return fn(*pending)
+	}
+	return nil
+}
+
+// defaultTimestampFields are checked in order when no timestamp field is configured.
+var defaultTimestampFields = []string{"ts", "time", "timestamp", "@timestamp", "datetime"}
+
+func readJSONLines(r io.Reader, timestampField string, fn func(log.Entry) error) error {
+	fields := defaultTimestampFields
+	if timestampField != "" {
+		fields = []string{timestampField}
+	}
+
+	scanner := newScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		// Entries written by `-dry -dry-format json` are imported as they were generated.
+		if msg, ok := obj["line"].(string); ok {
+			if labels, ok := obj["labels"].(map[string]any); ok {
+				entry := log.Entry{Line: msg, Labels: stringMap(labels)}
+				if metadata, ok := obj["metadata"].(map[string]any); ok {
+					entry.Metadata = stringMap(metadata)
+				}
+				entry.Timestamp, _ = ParseTimestamp(obj["ts"])
+				if err := fn(entry); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		entry := log.Entry{Line: line}
+		for _, field := range fields {
+			if ts, ok := ParseTimestamp(obj[field]); ok {
+				entry.Timestamp = ts
+				break
+			}
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func readLogcli(r io.Reader, fn func(log.Entry) error) error {
+	scanner := newScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		tsEnd := strings.IndexByte(line, ' ')
+		if tsEnd < 0 {
+			return fmt.Errorf("line %d: missing labels", n)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, line[:tsEnd])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		labels, end, err := ParseLabels(line[tsEnd+1:])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		msg := strings.TrimPrefix(line[tsEnd+1+end:], " ")
+		if err := fn(log.Entry{Timestamp: ts, Labels: labels, Line: msg}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// lokiResponse covers both the query API response and the push request body.
+type lokiResponse struct {
+	Data struct {
+		Result []lokiStream `json:"result"`
+	} `json:"data"`
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string   `json:"stream"`
+	Values [][]json.RawMessage `json:"values"`
+}
+
+func readLoki(r io.Reader, fn func(log.Entry) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var resp lokiResponse
+		if err := dec.Decode(&resp); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		for _, stream := range append(resp.Data.Result, resp.Streams...) {
+			for _, value := range stream.Values {
+				entry, err := decodeLokiValue(stream.Stream, value)
+				if err != nil {
+					return err
+				}
+				if err := fn(entry); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func decodeLokiValue(stream map[string]string, value []json.RawMessage) (log.Entry, error) {
+	if len(value) < 2 {
+		return log.Entry{}, fmt.Errorf("invalid stream value with %d elements", len(value))
+	}
+	var ns, line string
+	if err := json.Unmarshal(value[0], &ns); err != nil {
+		return log.Entry{}, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if err := json.Unmarshal(value[1], &line); err != nil {
+		return log.Entry{}, fmt.Errorf("invalid line: %w", err)
+	}
+	nanos, err := strconv.ParseInt(ns, 10, 64)
+	if err != nil {
+		return log.Entry{}, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	entry := log.Entry{Timestamp: time.Unix(0, nanos), Labels: stream, Line: line}
+
+	if len(value) > 2 {
+		var metadata map[string]any
+		if err := json.Unmarshal(value[2], &metadata); err != nil {
+			return log.Entry{}, fmt.Errorf("invalid structured metadata: %w", err)
+		}
+		// Categorized responses nest metadata next to parsed labels.
+		if nested, ok := metadata["structuredMetadata"].(map[string]any); ok {
+			metadata = nested
+		}
+		entry.Metadata = stringMap(metadata)
+	}
+	return entry, nil
+}
+
+// ParseLabels parses a label set formatted as {name="value", ...} at the start of s and
+// returns the labels and the index after the closing brace.
+func ParseLabels(s string) (map[string]string, int, error) {
+	if !strings.HasPrefix(s, "{") {
+		return nil, 0, fmt.Errorf("labels must start with {")
+	}
+	labels := map[string]string{}
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated labels")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, 0, fmt.Errorf("missing = after label name")
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("label %s: value must be quoted", name)
+		}
+		end := i + 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return nil, 0, fmt.Errorf("label %s: unterminated value", name)
+		}
+		value, err := strconv.Unquote(s[i : end+1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("label %s: %w", name, err)
+		}
+		labels[name] = value
+		i = end + 1
+	}
+}
+
+func stringMap(m map[string]any) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		switch s := v.(type) {
+		case string:
+			out[k] = s
+		default:
+			out[k] = fmt.Sprint(s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,301 @@
+// Package ingest feeds captured log files through the generator's loggers, so real
+// (anonymized) data can be reproduced in Explore Logs.
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// Options controls how files are parsed and labelled.
+type Options struct {
+	Format Format
+	// Labels are added to every entry.
+	Labels model.LabelSet
+	// PathLabels named captures matched against the file path become labels.
+	PathLabels *regexp.Regexp
+	// LineLabels named captures matched against each line become labels.
+	LineLabels *regexp.Regexp
+	// TimestampField is the JSON field holding the timestamp, by default common names are tried.
+	TimestampField string
+	// ShiftToNow moves timestamps so the data appears recent. When pacing, the first entry is sent
+	// when the import starts, otherwise the last entry lands at the start time like a backfill.
+	ShiftToNow bool
+	// Speed scales the pacing of the captured timestamps, 2 imports twice as fast. Zero or less
+	// sends entries without waiting.
+	Speed float64
+}
+
+// ReadFile parses a file and calls fn for every entry. Labels are taken from the file itself
+// (logcli, loki and json dry run formats) and overridden by Options.Labels, path captures and
+// line captures in that order. The file name is the service_name when no other source sets one.
+// Entries without a timestamp inherit the previous one, or the file modification time.
+// The file is streamed, only the beginning of it is buffered to detect its format.
+func ReadFile(path string, opts Options, fn func(log.Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	static := map[string]string{}
+	for k, v := range opts.Labels {
+		static[string(k)] = string(v)
+	}
+	addCaptures(static, opts.PathLabels, path)
+	name := filepath.Base(path)
+	serviceName := strings.TrimSuffix(name, filepath.Ext(name))
+
+	prev := info.ModTime()
+	emit := func(e log.Entry) error {
+		labels := make(map[string]string, len(e.Labels)+len(static)+1)
+		for k, v := range e.Labels {
+			labels[k] = v
+		}
+		if labels["service_name"] == "" {
+			labels["service_name"] = serviceName
+		}
+		for k, v := range static {
+			labels[k] = v
+		}
+		addCaptures(labels, opts.LineLabels, e.Line)
+		e.Labels = labels
+
+		if e.Timestamp.IsZero() {
+			e.Timestamp = prev
+		}
+		prev = e.Timestamp
+		return fn(e)
+	}
+
+	r := bufio.NewReaderSize(file, 64*1024)
+	format := opts.Format
+	if format == "" || format == Auto {
+		// Peek returns what it could read when the file is smaller than the buffer.
+		head, _ := r.Peek(r.Size())
+		format = DetectFormat(head)
+	}
+	switch format {
+	case Text:
+		err = readText(r, emit)
+	case JSONLines:
+		err = readJSONLines(r, opts.TimestampField, emit)
+	case Logcli:
+		err = readLogcli(r, emit)
+	case Loki:
+		err = readLoki(r, emit)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func addCaptures(labels map[string]string, re *regexp.Regexp, s string) {
+	if re == nil {
+		return
+	}
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return
+	}
+	for i, name := range re.SubexpNames() {
+		if name != "" && match[i] != "" {
+			labels[name] = match[i]
+		}
+	}
+}
+
+// Run sends the entries of all files through logger in time order, paced by opts. Files are
+// streamed and merged by time, the entries of each file are expected to be in time order.
+// Labels are sent as captured, the level label is only set from the line when the source
+// doesn't have one and a known level is detected. The first logger error stops the import.
+func Run(ctx context.Context, paths []string, logger log.Logger, opts Options) (int, error) {
+	// A first pass finds the time span of the files, backfills end it at the start time.
+	var first, last time.Time
+	for _, path := range paths {
+		if err := ReadFile(path, opts, func(e log.Entry) error {
+			if first.IsZero() || e.Timestamp.Before(first) {
+				first = e.Timestamp
+			}
+			if e.Timestamp.After(last) {
+				last = e.Timestamp
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+	}
+	if first.IsZero() {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var sources []*source
+	for _, path := range paths {
+		s := newSource(ctx, path, opts)
+		if !s.next() {
+			if err := <-s.err; err != nil {
+				return 0, err
+			}
+			continue
+		}
+		sources = append(sources, s)
+	}
+
+	count := 0
+	pacer := newPacer(opts, first, last)
+	for len(sources) > 0 {
+		i := 0
+		for j, s := range sources {
+			if s.head.Timestamp.Before(sources[i].head.Timestamp) {
+				i = j
+			}
+		}
+		e := sources[i].head
+		ts, err := pacer.wait(ctx, e.Timestamp)
+		if err != nil {
+			return count, err
+		}
+		labels := e.LabelSet()
+		if _, ok := labels["level"]; !ok {
+			if level := DetectLevel(e.Line); slices.Contains(log.Levels, level) {
+				labels["level"] = level
+			}
+		}
+		if err := logger.HandleWithMetadata(labels, ts, e.Line, e.StructuredMetadata()); err != nil {
+			return count, err
+		}
+		count++
+
+		if !sources[i].next() {
+			if err := <-sources[i].err; err != nil {
+				return count, err
+			}
+			sources = slices.Delete(sources, i, i+1)
+		}
+	}
+	return count, nil
+}
+
+// source streams the entries of a file, head is the next entry to send.
+type source struct {
+	entries chan log.Entry
+	err     chan error
+	head    log.Entry
+}
+
+func newSource(ctx context.Context, path string, opts Options) *source {
+	s := &source{entries: make(chan log.Entry, 100), err: make(chan error, 1)}
+	go func() {
+		defer close(s.entries)
+		s.err <- ReadFile(path, opts, func(e log.Entry) error {
+			select {
+			case s.entries <- e:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return s
+}
+
+// next moves head to the next entry, it returns false at the end of the file.
+func (s *source) next() bool {
+	e, ok := <-s.entries
+	s.head = e
+	return ok
+}
+
+// pacer schedules the entries of an import spanning first to last.
+type pacer struct {
+	opts  Options
+	start time.Time
+	first time.Time
+	last  time.Time
+}
+
+// newPacer creates a pacer, the import starts now.
+func newPacer(opts Options, first, last time.Time) *pacer {
+	return &pacer{opts: opts, start: time.Now(), first: first, last: last}
+}
+
+// wait blocks until the entry with timestamp ts is due and returns the timestamp to send it with.
+func (p *pacer) wait(ctx context.Context, ts time.Time) (time.Time, error) {
+	if p.opts.Speed <= 0 {
+		if p.opts.ShiftToNow {
+			return ts.Add(p.start.Sub(p.last)), ctx.Err()
+		}
+		return ts, ctx.Err()
+	}
+
+	target := p.start.Add(time.Duration(float64(ts.Sub(p.first)) / p.opts.Speed))
+	if wait := time.Until(target); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ts, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	if p.opts.ShiftToNow {
+		return target, ctx.Err()
+	}
+	return ts, ctx.Err()
+}
+
+var (
+	levelField   = regexp.MustCompile(`(?i)\b(?:level|lvl|severity|loglevel)["']?\s*[=:]\s*["']?([a-z]+)`)
+	levelKeyword = regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|ERROR|ERR|WARNING|WARN|INFO|DEBUG|TRACE)\b`)
+)
+
+// DetectLevel finds the log level of a line from a level field or an upper case keyword.
+// It returns an empty level when nothing is found.
+func DetectLevel(line string) model.LabelValue {
+	if m := levelField.FindStringSubmatch(line); m != nil {
+		if level := normalizeLevel(m[1]); level != "" {
+			return level
+		}
+	}
+	if m := levelKeyword.FindString(line); m != "" {
+		return normalizeLevel(m)
+	}
+	return ""
+}
+
+func normalizeLevel(level string) model.LabelValue {
+	switch strings.ToLower(level) {
+	case "fatal", "panic", "emerg":
+		return log.FATAL
+	case "critical", "crit", "alert":
+		return log.CRITICAL
+	case "error", "err", "eror":
+		return log.ERROR
+	case "warning", "warn":
+		return log.WARN
+	case "info", "information", "notice":
+		return log.INFO
+	case "debug", "dbug":
+		return log.DEBUG
+	case "trace":
+		return log.TRACE
+	}
+	return ""
+}
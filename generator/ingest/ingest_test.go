@@ -0,0 +1,273 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func readAll(t *testing.T, path string, opts Options) []log.Entry {
+	t.Helper()
+	var entries []log.Entry
+	require.NoError(t, ReadFile(path, opts, func(e log.Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	return entries
+}
+
+func TestReadText(t *testing.T) {
+	a := assert.New(t)
+	path := writeFile(t, t.TempDir(), "app.log", `2024-09-13T10:00:00Z level=info msg="started"
+2024-09-13T10:00:01.5Z level=error msg="failed"
+	at com.example.Main.run(Main.java:10)
+	at com.example.Main.main(Main.java:5)
+
+127.0.0.1 - - [13/Sep/2024:10:00:02 +0000] "GET / HTTP/1.1" 200 12
+no timestamp here
+`)
+	entries := readAll(t, path, Options{})
+	require.Len(t, entries, 4)
+
+	a.Equal(time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC), entries[0].Timestamp.UTC())
+	a.Equal(map[string]string{"service_name": "app"}, entries[0].Labels)
+	a.Equal("2024-09-13T10:00:01.5Z level=error msg=\"failed\"\n\tat com.example.Main.run(Main.java:10)\n\tat com.example.Main.main(Main.java:5)", entries[1].Line)
+	a.Equal(time.Date(2024, 9, 13, 10, 0, 2, 0, time.UTC), entries[2].Timestamp.UTC())
+	a.Equal(entries[2].Timestamp, entries[3].Timestamp, "entries without timestamp inherit the previous one")
+}
+
+func TestReadJSONLines(t *testing.T) {
+	a := assert.New(t)
+	path := writeFile(t, t.TempDir(), "events.jsonl", `{"time":"2024-09-13T10:00:00Z","msg":"a"}
+{"ts":1726221601000,"msg":"b"}
+{"ts":"2024-09-13T10:00:02Z","labels":{"service_name":"nginx","cluster":"eu"},"metadata":{"pod":"nginx-1"},"line":"GET /"}
+`)
+	entries := readAll(t, path, Options{})
+	require.Len(t, entries, 3)
+
+	a.Equal(`{"time":"2024-09-13T10:00:00Z","msg":"a"}`, entries[0].Line)
+	a.Equal(time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC), entries[0].Timestamp.UTC())
+	a.Equal(time.Date(2024, 9, 13, 10, 0, 1, 0, time.UTC), entries[1].Timestamp.UTC())
+	a.Equal(log.Entry{
+		Timestamp: time.Date(2024, 9, 13, 10, 0, 2, 0, time.UTC),
+		Labels:    map[string]string{"service_name": "nginx", "cluster": "eu"},
+		Metadata:  map[string]string{"pod": "nginx-1"},
+		Line:      "GET /",
+	}, log.Entry{Timestamp: entries[2].Timestamp.UTC(), Labels: entries[2].Labels, Metadata: entries[2].Metadata, Line: entries[2].Line})
+
+	entries = readAll(t, path, Options{Format: JSONLines, TimestampField: "time"})
+	a.Equal(time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC), entries[0].Timestamp.UTC())
+	a.Equal(entries[0].Timestamp, entries[1].Timestamp, "ts is ignored when another field is configured")
+}
+
+func TestReadLogcli(t *testing.T) {
+	a := assert.New(t)
+	path := writeFile(t, t.TempDir(), "query.txt", `2024-09-13T10:00:00Z {service_name="api", pod="api-1"} level=info msg="ok \"quoted\""
+2024-09-13T10:00:01.25+02:00 {service_name="api"} second
+`)
+	a.Equal(Logcli, DetectFormat([]byte(`2024-09-13T10:00:00Z {service_name="api"} x`)))
+
+	entries := readAll(t, path, Options{})
+	require.Len(t, entries, 2)
+	a.Equal(map[string]string{"service_name": "api", "pod": "api-1"}, entries[0].Labels)
+	a.Equal(`level=info msg="ok \"quoted\""`, entries[0].Line)
+	a.Equal(time.Date(2024, 9, 13, 8, 0, 1, 250000000, time.UTC), entries[1].Timestamp.UTC())
+}
+
+func TestReadLoki(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	query := writeFile(t, dir, "query.json", `{"status":"success","data":{"resultType":"streams","result":[
+		{"stream":{"service_name":"db"},"values":[["1726221600000000000","select 1",{"structuredMetadata":{"traceID":"abc"},"parsed":{"x":"y"}}]]}
+	]}}`)
+	entries := readAll(t, query, Options{})
+	require.Len(t, entries, 1)
+	a.Equal(map[string]string{"service_name": "db"}, entries[0].Labels)
+	a.Equal(map[string]string{"traceID": "abc"}, entries[0].Metadata)
+	a.Equal(time.Unix(0, 1726221600000000000), entries[0].Timestamp)
+
+	pushes := writeFile(t, dir, "push.ndjson", `{"streams":[{"stream":{"service_name":"a"},"values":[["1726221600000000000","one",{"pod":"a-1"}]]}]}
+{"streams":[{"stream":{"service_name":"b"},"values":[["1726221601000000000","two"]]}]}
+`)
+	entries = readAll(t, pushes, Options{})
+	require.Len(t, entries, 2)
+	a.Equal("one", entries[0].Line)
+	a.Equal(map[string]string{"pod": "a-1"}, entries[0].Metadata)
+	a.Equal(map[string]string{"service_name": "b"}, entries[1].Labels)
+}
+
+func TestReadFileLabels(t *testing.T) {
+	a := assert.New(t)
+	path := writeFile(t, t.TempDir(), "prod/checkout/server.log", `2024-09-13T10:00:00Z region=eu-west-1 ok
+2024-09-13T10:00:01Z no region
+`)
+	entries := readAll(t, path, Options{
+		Labels:     model.LabelSet{"env": "staging", "team": "shop"},
+		PathLabels: regexp.MustCompile(`(?P<env>[a-z]+)/(?P<service_name>[a-z]+)/[^/]+$`),
+		LineLabels: regexp.MustCompile(`region=(?P<region>\S+)`),
+	})
+	require.Len(t, entries, 2)
+	a.Equal(map[string]string{"env": "prod", "team": "shop", "service_name": "checkout", "region": "eu-west-1"}, entries[0].Labels)
+	a.Equal(map[string]string{"env": "prod", "team": "shop", "service_name": "checkout"}, entries[1].Labels)
+}
+
+type collected struct {
+	labels   model.LabelSet
+	ts       time.Time
+	line     string
+	metadata push.LabelsAdapter
+}
+
+func TestRun(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	first := writeFile(t, dir, "a.log", `2024-09-13T10:00:00Z level=error boom
+2024-09-13T10:00:02Z WARN disk almost full
+`)
+	second := writeFile(t, dir, "b.jsonl", `{"ts":"2024-09-13T10:00:01Z","labels":{"service_name":"b","level":"info"},"metadata":{"pod":"b-1"},"line":"hello"}
+`)
+
+	var (
+		mtx     sync.Mutex
+		entries []collected
+	)
+	logger := log.LoggerFunc(func(labels model.LabelSet, ts time.Time, msg string, md push.LabelsAdapter) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		entries = append(entries, collected{labels, ts, msg, md})
+		return nil
+	})
+
+	start := time.Now()
+	count, err := Run(context.Background(), []string{first, second}, logger, Options{ShiftToNow: true})
+	require.NoError(t, err)
+	a.Equal(3, count)
+	require.Len(t, entries, 3)
+
+	a.Equal("2024-09-13T10:00:00Z level=error boom", entries[0].line)
+	a.Equal(model.LabelSet{"service_name": "a", "level": "error"}, entries[0].labels)
+	a.Equal("hello", entries[1].line)
+	a.Equal(model.LabelSet{"service_name": "b", "level": "info"}, entries[1].labels)
+	a.Equal(push.LabelsAdapter{{Name: "pod", Value: "b-1"}}, entries[1].metadata)
+	a.Equal(model.LabelSet{"service_name": "a", "level": "warn"}, entries[2].labels)
+
+	// Backfill keeps the spacing and ends now.
+	a.Equal(2*time.Second, entries[2].ts.Sub(entries[0].ts))
+	a.WithinDuration(start, entries[2].ts, time.Second)
+}
+
+func TestRunKeepsLevels(t *testing.T) {
+	a := assert.New(t)
+	path := writeFile(t, t.TempDir(), "levels.jsonl", `{"ts":"2024-09-13T10:00:00Z","labels":{"service_name":"a","level":"warning"},"line":"slow"}
+{"ts":"2024-09-13T10:00:01Z","labels":{"service_name":"a","level":"notice"},"line":"started"}
+{"ts":"2024-09-13T10:00:02Z","labels":{"service_name":"a"},"line":"GET /api 200"}
+`)
+	var levels []model.LabelValue
+	logger := log.LoggerFunc(func(labels model.LabelSet, _ time.Time, _ string, _ push.LabelsAdapter) error {
+		level, ok := labels["level"]
+		a.Equal(level != "", ok, "no empty level label")
+		levels = append(levels, level)
+		return nil
+	})
+	_, err := Run(context.Background(), []string{path}, logger, Options{})
+	require.NoError(t, err)
+	a.Equal([]model.LabelValue{"warning", "notice", ""}, levels)
+
+	failing := log.LoggerFunc(func(model.LabelSet, time.Time, string, push.LabelsAdapter) error {
+		return errors.New("push failed")
+	})
+	count, err := Run(context.Background(), []string{path}, failing, Options{})
+	a.EqualError(err, "push failed")
+	a.Equal(0, count)
+}
+
+func TestDetectLevel(t *testing.T) {
+	a := assert.New(t)
+	for line, level := range map[string]model.LabelValue{
+		`level=warning msg="slow"`:        log.WARN,
+		`{"severity":"ERROR","msg":"x"}`:  log.ERROR,
+		`2024-09-13 10:00:00 FATAL crash`: log.FATAL,
+		`level=crit disk failed`:          log.CRITICAL,
+		`TRACE enter handler`:             log.TRACE,
+		`[DEBUG] cache miss`:              log.DEBUG,
+		`lvl=info the error was handled`:  log.INFO,
+		`GET /api 200`:                    "",
+	} {
+		a.Equal(level, DetectLevel(line), line)
+	}
+}
+
+func TestDetectTimestamp(t *testing.T) {
+	a := assert.New(t)
+	for line, expected := range map[string]time.Time{
+		`2024-09-13 10:00:00,123 INFO x`:                   time.Date(2024, 9, 13, 10, 0, 0, 123000000, time.UTC),
+		`[Fri Sep 13 10:00:00.5 2024] [core:error] x`:      time.Date(2024, 9, 13, 10, 0, 0, 500000000, time.UTC),
+		`1.2.3.4 - - [13/Sep/2024:12:00:00 +0200] "GET /"`: time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC),
+		`Sep  3 10:00:00 host sshd[1]: Accepted publickey`: time.Date(time.Now().Year(), 9, 3, 10, 0, 0, 0, time.UTC),
+	} {
+		ts, ok := DetectTimestamp(line)
+		a.True(ok, line)
+		a.Equal(expected, ts.UTC(), line)
+	}
+	_, ok := DetectTimestamp("no time")
+	a.False(ok)
+
+	for v, expected := range map[any]time.Time{
+		"1726221600":          time.Unix(1726221600, 0),
+		"1726221600123":       time.UnixMilli(1726221600123),
+		"1726221600123456":    time.UnixMicro(1726221600123456),
+		"1726221600123456789": time.Unix(0, 1726221600123456789),
+		1726221600.5:          time.Unix(1726221600, 500000000),
+	} {
+		ts, ok := ParseTimestamp(v)
+		a.True(ok)
+		a.Equal(expected, ts, v)
+	}
+}
+
+func TestPacer(t *testing.T) {
+	a := assert.New(t)
+
+	first := time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC)
+	last := first.Add(time.Hour)
+
+	before := time.Now()
+	p := newPacer(Options{ShiftToNow: true}, first, last)
+	ts, err := p.wait(context.Background(), last)
+	require.NoError(t, err)
+	a.WithinDuration(before, ts, time.Second)
+
+	ts, err = p.wait(context.Background(), first)
+	require.NoError(t, err)
+	a.WithinDuration(before.Add(-time.Hour), ts, time.Second)
+
+	p = newPacer(Options{}, first, last)
+	ts, err = p.wait(context.Background(), first)
+	require.NoError(t, err)
+	a.Equal(first, ts)
+
+	// Paced imports start now.
+	p = newPacer(Options{ShiftToNow: true, Speed: 3600}, first, last)
+	ts, err = p.wait(context.Background(), first.Add(time.Second))
+	require.NoError(t, err)
+	a.WithinDuration(before, ts, time.Second)
+}
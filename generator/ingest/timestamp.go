@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampPattern pairs a regular expression finding a timestamp in a line with the layouts to parse it.
+type timestampPattern struct {
+	re      *regexp.Regexp
+	layouts []string
+}
+
+// timestampPatterns are tried in order on plain text lines.
+var timestampPatterns = []timestampPattern{
+	{
+		re:      regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`),
+		layouts: []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z0700", "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"},
+	},
+	{
+		// Apache and common log format
+		re:      regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`),
+		layouts: []string{"02/Jan/2006:15:04:05 -0700"},
+	},
+	{
+		// Apache error log
+		re:      regexp.MustCompile(`[A-Z][a-z]{2} [A-Z][a-z]{2} \d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)? \d{4}`),
+		layouts: []string{"Mon Jan 02 15:04:05.999999999 2006"},
+	},
+	{
+		// RFC3164 syslog, without a year
+		re:      regexp.MustCompile(`^[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}`),
+		layouts: []string{time.Stamp},
+	},
+}
+
+// DetectTimestamp finds and parses the first known timestamp format in a line.
+func DetectTimestamp(line string) (time.Time, bool) {
+	for _, p := range timestampPatterns {
+		match := p.re.FindString(line)
+		if match == "" {
+			continue
+		}
+		match = strings.Replace(match, ",", ".", 1)
+		for _, layout := range p.layouts {
+			ts, err := time.Parse(layout, match)
+			if err != nil {
+				continue
+			}
+			if ts.Year() == 0 {
+				ts = ts.AddDate(time.Now().Year(), 0, 0)
+			}
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseTimestamp parses a timestamp value from structured logs: RFC3339 strings or
+// unix epochs in seconds, milliseconds, microseconds or nanoseconds.
+func ParseTimestamp(v any) (time.Time, bool) {
+	var s string
+	switch ts := v.(type) {
+	case string:
+		s = ts
+	case json.Number:
+		s = ts.String()
+	case float64:
+		return epoch(ts), true
+	default:
+		return time.Time{}, false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return epochInt(n), true
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return epoch(n), true
+	}
+	return DetectTimestamp(s)
+}
+
+func epochInt(n int64) time.Time {
+	switch {
+	case n > 1e17:
+		return time.Unix(0, n)
+	case n > 1e14:
+		return time.UnixMicro(n)
+	case n > 1e11:
+		return time.UnixMilli(n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
+
+func epoch(n float64) time.Time {
+	if n > 1e11 {
+		return epochInt(int64(n))
+	}
+	sec := int64(n)
+	return time.Unix(sec, int64((n-float64(sec))*1e9))
+}
@@ -0,0 +1,399 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// MicroTime is a timestamp written with microsecond precision, like the timestamps of audit events.
+type MicroTime time.Time
+
+func (t MicroTime) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + time.Time(t).UTC().Format("2006-01-02T15:04:05.000000Z07:00") + `"`), nil
+}
+
+// UserInfo is the user making a request.
+type UserInfo struct {
+	Username string   `json:"username"`
+	UID      string   `json:"uid,omitempty"`
+	Groups   []string `json:"groups"`
+}
+
+// AuditObjectRef is the object of a request.
+type AuditObjectRef struct {
+	Resource    string `json:"resource"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+	APIGroup    string `json:"apiGroup,omitempty"`
+	APIVersion  string `json:"apiVersion"`
+	Subresource string `json:"subresource,omitempty"`
+}
+
+// Status is the status of a response.
+type Status struct {
+	Metadata struct{} `json:"metadata"`
+	Status   string   `json:"status,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Code     int      `json:"code"`
+}
+
+// AuditEvent is an audit.k8s.io/v1 Event logged at the Metadata level.
+type AuditEvent struct {
+	Kind                     string            `json:"kind"`
+	APIVersion               string            `json:"apiVersion"`
+	Level                    string            `json:"level"`
+	AuditID                  string            `json:"auditID"`
+	Stage                    string            `json:"stage"`
+	RequestURI               string            `json:"requestURI"`
+	Verb                     string            `json:"verb"`
+	User                     UserInfo          `json:"user"`
+	SourceIPs                []string          `json:"sourceIPs"`
+	UserAgent                string            `json:"userAgent"`
+	ObjectRef                *AuditObjectRef   `json:"objectRef,omitempty"`
+	ResponseStatus           Status            `json:"responseStatus"`
+	RequestReceivedTimestamp MicroTime         `json:"requestReceivedTimestamp"`
+	StageTimestamp           MicroTime         `json:"stageTimestamp"`
+	Annotations              map[string]string `json:"annotations"`
+}
+
+// LogLevel returns the level of the event: warn for client errors, error for server errors.
+func (e AuditEvent) LogLevel() model.LabelValue {
+	switch {
+	case e.ResponseStatus.Code >= 500:
+		return log.ERROR
+	case e.ResponseStatus.Code >= 400:
+		return log.WARN
+	}
+	return log.INFO
+}
+
+// Time returns when the stage of the event was reached.
+func (e AuditEvent) Time() time.Time {
+	return time.Time(e.StageTimestamp)
+}
+
+func (e AuditEvent) String() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+// kubeVersion is the version of the simulated clusters.
+const kubeVersion = "v1.30.4"
+
+// request is something an actor does to a resource.
+type request struct {
+	verb, resource, subresource string
+	// group is the API group, empty for the core group.
+	group string
+	// clusterScoped resources have no namespace, like nodes.
+	clusterScoped bool
+	// name and namespace are those of the object when they don't come from the pod.
+	name, namespace string
+}
+
+// actor is a user of the API server.
+type actor struct {
+	// username can refer to the node of the actor with %s.
+	username string
+	groups   []string
+	agent    string
+	weight   int
+	requests []request
+	// forbidden are requests the actor isn't allowed to make.
+	forbidden []request
+	// binding is the role binding allowing the requests.
+	binding string
+}
+
+var actors = []actor{
+	{
+		username: "system:node:%s",
+		groups:   []string{"system:nodes", "system:authenticated"},
+		agent:    "kubelet/" + kubeVersion + " (linux/amd64) kubernetes/7f3a8f5",
+		weight:   40,
+		requests: []request{
+			{verb: "get", resource: "pods"},
+			{verb: "patch", resource: "pods", subresource: "status"},
+			{verb: "patch", resource: "nodes", subresource: "status", clusterScoped: true},
+			{verb: "update", resource: "leases", group: "coordination.k8s.io", namespace: "kube-node-lease"},
+			{verb: "watch", resource: "configmaps"},
+			{verb: "watch", resource: "secrets"},
+		},
+		binding: `RBAC: allowed by ClusterRoleBinding "system:node" of ClusterRole "system:node" to Group "system:nodes"`,
+	},
+	{
+		username: "system:serviceaccount:kube-system:replicaset-controller",
+		groups:   []string{"system:serviceaccounts", "system:serviceaccounts:kube-system", "system:authenticated"},
+		agent:    "kube-controller-manager/" + kubeVersion + " (linux/amd64) kubernetes/7f3a8f5/system:serviceaccount:kube-system:replicaset-controller",
+		weight:   10,
+		requests: []request{
+			{verb: "create", resource: "pods"},
+			{verb: "delete", resource: "pods"},
+			{verb: "update", resource: "replicasets", subresource: "status", group: "apps"},
+		},
+		binding: `RBAC: allowed by ClusterRoleBinding "system:controller:replicaset-controller" of ClusterRole "system:controller:replicaset-controller" to ServiceAccount "replicaset-controller/kube-system"`,
+	},
+	{
+		username: "system:serviceaccount:kube-system:endpointslice-controller",
+		groups:   []string{"system:serviceaccounts", "system:serviceaccounts:kube-system", "system:authenticated"},
+		agent:    "kube-controller-manager/" + kubeVersion + " (linux/amd64) kubernetes/7f3a8f5/system:serviceaccount:kube-system:endpointslice-controller",
+		weight:   10,
+		requests: []request{
+			{verb: "update", resource: "endpointslices", group: "discovery.k8s.io"},
+			{verb: "list", resource: "endpointslices", group: "discovery.k8s.io"},
+		},
+		binding: `RBAC: allowed by ClusterRoleBinding "system:controller:endpointslice-controller" of ClusterRole "system:controller:endpointslice-controller" to ServiceAccount "endpointslice-controller/kube-system"`,
+	},
+	{
+		username: "system:kube-scheduler",
+		groups:   []string{"system:authenticated"},
+		agent:    "kube-scheduler/" + kubeVersion + " (linux/amd64) kubernetes/7f3a8f5/scheduler",
+		weight:   8,
+		requests: []request{
+			{verb: "create", resource: "pods", subresource: "binding"},
+			{verb: "watch", resource: "pods"},
+			{verb: "update", resource: "leases", group: "coordination.k8s.io", name: "kube-scheduler", namespace: "kube-system"},
+		},
+		binding: `RBAC: allowed by ClusterRoleBinding "system:kube-scheduler" of ClusterRole "system:kube-scheduler" to User "system:kube-scheduler"`,
+	},
+	{
+		username: "system:serviceaccount:argocd:argocd-application-controller",
+		groups:   []string{"system:serviceaccounts", "system:serviceaccounts:argocd", "system:authenticated"},
+		agent:    "argocd-application-controller/v2.12.3 (linux/amd64)",
+		weight:   5,
+		requests: []request{
+			{verb: "get", resource: "deployments", group: "apps"},
+			{verb: "patch", resource: "deployments", group: "apps"},
+			{verb: "list", resource: "configmaps"},
+		},
+		binding: `RBAC: allowed by ClusterRoleBinding "argocd-application-controller" of ClusterRole "argocd-application-controller" to ServiceAccount "argocd-application-controller/argocd"`,
+	},
+	{
+		username: "alice@example.com",
+		groups:   []string{"sre", "system:authenticated"},
+		agent:    "kubectl/v1.30.2 (darwin/arm64) kubernetes/3968350",
+		weight:   3,
+		requests: []request{
+			{verb: "get", resource: "pods"},
+			{verb: "list", resource: "pods"},
+			{verb: "get", resource: "pods", subresource: "log"},
+			{verb: "create", resource: "pods", subresource: "exec"},
+			{verb: "delete", resource: "pods"},
+			{verb: "patch", resource: "deployments", subresource: "scale", group: "apps"},
+		},
+		binding: `RBAC: allowed by ClusterRoleBinding "sre-admin" of ClusterRole "cluster-admin" to Group "sre"`,
+	},
+	{
+		username: "bob@example.com",
+		groups:   []string{"developers", "system:authenticated"},
+		agent:    "kubectl/v1.29.7 (linux/amd64) kubernetes/4c9411a",
+		weight:   2,
+		requests: []request{
+			{verb: "get", resource: "pods"},
+			{verb: "list", resource: "pods"},
+			{verb: "get", resource: "pods", subresource: "log"},
+		},
+		forbidden: []request{
+			{verb: "get", resource: "secrets"},
+			{verb: "create", resource: "pods", subresource: "exec"},
+			{verb: "delete", resource: "pods"},
+		},
+		binding: `RBAC: allowed by RoleBinding "developers-view" of ClusterRole "view" to Group "developers"`,
+	},
+}
+
+var totalActorWeight = func() int {
+	total := 0
+	for _, a := range actors {
+		total += a.weight
+	}
+	return total
+}()
+
+func randActor() actor {
+	r := rand.Intn(totalActorWeight)
+	for _, a := range actors {
+		if r -= a.weight; r < 0 {
+			return a
+		}
+	}
+	return actors[len(actors)-1]
+}
+
+// NewAuditEvent creates the audit event of a random request to the API server of a cluster.
+// Requests refer to the pods of log.Pods and to the nodes of the cluster.
+func NewAuditEvent(t time.Time, cluster string) AuditEvent {
+	a := randActor()
+	nodes := log.Nodes(cluster)
+	node := nodes[rand.Intn(len(nodes))]
+	pod, ok := log.Pods.Rand(cluster)
+	if !ok {
+		pod = log.Pod{Cluster: cluster, Namespace: "default", Service: "app", Name: "app-" + log.RandSeq(5), Node: node}
+	}
+	if strings.Contains(a.username, "%s") {
+		// Nodes only get the pods scheduled on them.
+		node = pod.Node
+	}
+
+	req := a.requests[rand.Intn(len(a.requests))]
+	forbidden := len(a.forbidden) > 0 && rand.Intn(4) == 0
+	if forbidden {
+		req = a.forbidden[rand.Intn(len(a.forbidden))]
+	}
+	ref := objectRef(req, pod, node)
+	username := strings.ReplaceAll(a.username, "%s", node)
+
+	latency := time.Duration(latency(req.verb)) * time.Microsecond
+	e := AuditEvent{
+		Kind:                     "Event",
+		APIVersion:               "audit.k8s.io/v1",
+		Level:                    "Metadata",
+		AuditID:                  gofakeit.UUID(),
+		Stage:                    "ResponseComplete",
+		RequestURI:               requestURI(req, ref, pod.Service),
+		Verb:                     req.verb,
+		User:                     UserInfo{Username: username, Groups: a.groups},
+		SourceIPs:                []string{sourceIP(a, node)},
+		UserAgent:                a.agent,
+		ObjectRef:                &ref,
+		ResponseStatus:           Status{Code: 200},
+		RequestReceivedTimestamp: MicroTime(t.Add(-latency)),
+		StageTimestamp:           MicroTime(t),
+		Annotations: map[string]string{
+			"authorization.k8s.io/decision": "allow",
+			"authorization.k8s.io/reason":   a.binding,
+		},
+	}
+	if req.verb == "create" && req.subresource == "" {
+		e.ResponseStatus.Code = 201
+	}
+	if req.verb == "watch" && rand.Intn(2) == 0 {
+		e.Stage = "ResponseStarted"
+	}
+	switch r := rand.Float64(); {
+	case forbidden:
+		e.Annotations = map[string]string{"authorization.k8s.io/decision": "forbid", "authorization.k8s.io/reason": ""}
+		e.ResponseStatus = Status{Status: "Failure", Reason: "Forbidden", Code: 403, Message: forbiddenMessage(req, ref, username)}
+	case r < 0.02 && (req.verb == "get" || req.verb == "delete"):
+		e.ResponseStatus = Status{Status: "Failure", Reason: "NotFound", Code: 404, Message: fmt.Sprintf("%s %q not found", resourceName(req), ref.Name)}
+	case r < 0.04 && (req.verb == "update" || req.verb == "patch"):
+		e.ResponseStatus = Status{Status: "Failure", Reason: "Conflict", Code: 409, Message: fmt.Sprintf(`Operation cannot be fulfilled on %s %q: the object has been modified; please apply your changes to the latest version and try again`, resourceName(req), ref.Name)}
+	case r < 0.045:
+		e.ResponseStatus = Status{Status: "Failure", Reason: "Timeout", Code: 504, Message: "Timeout: request did not complete within requested timeout - context deadline exceeded"}
+	}
+	return e
+}
+
+// latency returns the latency of a request in microseconds.
+func latency(verb string) float64 {
+	switch verb {
+	case "list":
+		return 2000 + rand.ExpFloat64()*20000
+	case "watch":
+		return 300 + rand.ExpFloat64()*1000
+	}
+	return 500 + rand.ExpFloat64()*4000
+}
+
+func objectRef(req request, pod log.Pod, node string) AuditObjectRef {
+	ref := AuditObjectRef{Resource: req.resource, APIGroup: req.group, APIVersion: "v1", Subresource: req.subresource}
+	if !req.clusterScoped {
+		ref.Namespace = pod.Namespace
+	}
+	switch req.resource {
+	case "pods":
+		ref.Name = pod.Name
+	case "nodes", "leases":
+		ref.Name = node
+	case "replicasets":
+		ref.Name = pod.Name
+		if i := strings.LastIndex(pod.Name, "-"); i > 0 {
+			ref.Name = pod.Name[:i]
+		}
+	case "deployments", "endpointslices":
+		ref.Name = pod.Service
+	case "configmaps":
+		ref.Name = pod.Service + "-config"
+	case "secrets":
+		ref.Name = pod.Service + "-credentials"
+	}
+	if req.name != "" {
+		ref.Name = req.name
+	}
+	if req.namespace != "" {
+		ref.Namespace = req.namespace
+	}
+	if req.verb == "list" || req.verb == "watch" {
+		ref.Name = ""
+	}
+	return ref
+}
+
+// requestURI returns the path and query of a request.
+func requestURI(req request, ref AuditObjectRef, container string) string {
+	path := "/api/v1"
+	if req.group != "" {
+		path = "/apis/" + req.group + "/v1"
+	}
+	if ref.Namespace != "" {
+		path += "/namespaces/" + ref.Namespace
+	}
+	path += "/" + req.resource
+	if ref.Name != "" {
+		path += "/" + ref.Name
+	}
+	if req.subresource != "" {
+		path += "/" + req.subresource
+	}
+	switch req.verb {
+	case "list":
+		path += "?limit=500&resourceVersion=0"
+	case "watch":
+		path += fmt.Sprintf("?allowWatchBookmarks=true&resourceVersion=%s&timeout=%dm%ds&timeoutSeconds=%d&watch=true", resourceVersion(), 5+rand.Intn(5), rand.Intn(60), 300+rand.Intn(300))
+	case "get":
+		if req.subresource == "log" {
+			path += "?container=" + container + "&follow=true&tailLines=100"
+		}
+	case "create":
+		if req.subresource == "exec" {
+			path += "?command=sh&container=" + container + "&stdin=true&stdout=true&tty=true"
+		}
+	}
+	return path
+}
+
+// sourceIP returns the address of the actor, nodes call from their own.
+func sourceIP(a actor, node string) string {
+	if strings.Contains(a.username, "%s") {
+		ip, _, _ := strings.Cut(strings.TrimPrefix(node, "ip-"), ".")
+		return strings.ReplaceAll(ip, "-", ".")
+	}
+	if strings.Contains(a.username, "@") {
+		return fmt.Sprintf("192.168.%d.%d", rand.Intn(4), 10+rand.Intn(200))
+	}
+	return "172.20.0.1"
+}
+
+func resourceName(req request) string {
+	if req.group == "" {
+		return req.resource
+	}
+	return req.resource + "." + req.group
+}
+
+func forbiddenMessage(req request, ref AuditObjectRef, username string) string {
+	resource := req.resource
+	if req.subresource != "" {
+		resource += "/" + req.subresource
+	}
+	return fmt.Sprintf(`%s %q is forbidden: User %q cannot %s resource %q in API group %q in the namespace %q`,
+		req.resource, ref.Name, username, req.verb, resource, req.group, ref.Namespace)
+}
@@ -0,0 +1,323 @@
+// Package kube generates the cluster level logs of Kubernetes: the events of the simulated pods,
+// as written by event exporters, and the audit logs of the API server.
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// Time is a timestamp written with second precision, like the timestamps of Kubernetes objects.
+type Time time.Time
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + time.Time(t).UTC().Format(time.RFC3339) + `"`), nil
+}
+
+// Event types.
+const (
+	Normal  = "Normal"
+	Warning = "Warning"
+)
+
+// ObjectMeta is the metadata of an event.
+type ObjectMeta struct {
+	Name              string `json:"name"`
+	Namespace         string `json:"namespace"`
+	UID               string `json:"uid"`
+	ResourceVersion   string `json:"resourceVersion"`
+	CreationTimestamp Time   `json:"creationTimestamp"`
+}
+
+// ObjectReference is the object an event is about.
+type ObjectReference struct {
+	Kind            string `json:"kind"`
+	Namespace       string `json:"namespace,omitempty"`
+	Name            string `json:"name"`
+	UID             string `json:"uid,omitempty"`
+	APIVersion      string `json:"apiVersion"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	FieldPath       string `json:"fieldPath,omitempty"`
+}
+
+// EventSource is the component reporting an event.
+type EventSource struct {
+	Component string `json:"component,omitempty"`
+	Host      string `json:"host,omitempty"`
+}
+
+// Event is a core/v1 Event object.
+type Event struct {
+	Kind               string          `json:"kind"`
+	APIVersion         string          `json:"apiVersion"`
+	Metadata           ObjectMeta      `json:"metadata"`
+	InvolvedObject     ObjectReference `json:"involvedObject"`
+	Reason             string          `json:"reason"`
+	Message            string          `json:"message"`
+	Source             EventSource     `json:"source"`
+	FirstTimestamp     Time            `json:"firstTimestamp"`
+	LastTimestamp      Time            `json:"lastTimestamp"`
+	Count              int             `json:"count"`
+	Type               string          `json:"type"`
+	EventTime          *Time           `json:"eventTime"`
+	ReportingComponent string          `json:"reportingComponent"`
+	ReportingInstance  string          `json:"reportingInstance"`
+}
+
+// Level returns the level of the event, warn for warnings.
+func (e Event) Level() model.LabelValue {
+	if e.Type == Warning {
+		return log.WARN
+	}
+	return log.INFO
+}
+
+// Time returns when the event last happened.
+func (e Event) Time() time.Time {
+	return time.Time(e.LastTimestamp)
+}
+
+func (e Event) String() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+// Image returns the container image of a pod.
+func Image(p log.Pod) string {
+	return fmt.Sprintf("registry.example.com/%s/%s:v1.%d.%d", p.Namespace, p.Service, len(p.Service)%7, len(p.Namespace)%11)
+}
+
+// NewEvent creates an event about a pod with one of the reasons of Reasons.
+func NewEvent(t time.Time, p log.Pod, reason string) Event {
+	r, ok := Reasons[reason]
+	if !ok {
+		panic(fmt.Sprintf("unknown event reason %q", reason))
+	}
+	source := EventSource{Component: r.Component}
+	if r.Component == "kubelet" {
+		source.Host = p.Node
+	}
+	object := ObjectReference{
+		Kind:            "Pod",
+		Namespace:       p.Namespace,
+		Name:            p.Name,
+		UID:             p.UID(),
+		APIVersion:      "v1",
+		ResourceVersion: resourceVersion(),
+	}
+	if r.Container {
+		object.FieldPath = "spec.containers{" + p.Service + "}"
+	}
+	return Event{
+		Kind:       "Event",
+		APIVersion: "v1",
+		Metadata: ObjectMeta{
+			// Events are named after their object and a hex timestamp.
+			Name:              fmt.Sprintf("%s.%x", p.Name, t.UnixNano()),
+			Namespace:         p.Namespace,
+			UID:               log.PodUID(p.Cluster, p.Namespace, fmt.Sprintf("%s.%s.%d", p.Name, reason, t.UnixNano())),
+			ResourceVersion:   resourceVersion(),
+			CreationTimestamp: Time(t),
+		},
+		InvolvedObject:     object,
+		Reason:             reason,
+		Message:            r.Message(p),
+		Source:             source,
+		FirstTimestamp:     Time(t),
+		LastTimestamp:      Time(t),
+		Count:              1,
+		Type:               r.Type,
+		ReportingComponent: r.Component,
+		ReportingInstance:  source.Host,
+	}
+}
+
+// Reason describes the events with a given reason.
+type Reason struct {
+	Type      string
+	Component string
+	// Container is true for events about the container of the pod rather than the pod.
+	Container bool
+	Message   func(p log.Pod) string
+}
+
+// Reasons are the reasons of the generated events.
+var Reasons = map[string]Reason{
+	"Scheduled": {Type: Normal, Component: "default-scheduler", Message: func(p log.Pod) string {
+		return fmt.Sprintf("Successfully assigned %s/%s to %s", p.Namespace, p.Name, p.Node)
+	}},
+	"FailedScheduling": {Type: Warning, Component: "default-scheduler", Message: func(p log.Pod) string {
+		nodes := len(log.Nodes(p.Cluster))
+		cpu := 1 + rand.Intn(nodes-1)
+		return fmt.Sprintf("0/%d nodes are available: %d Insufficient cpu, %d node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }. preemption: 0/%d nodes are available: %d No preemption victims found for incoming pod, %d Preemption is not helpful for scheduling.",
+			nodes, cpu, nodes-cpu, nodes, cpu, nodes-cpu)
+	}},
+	"Pulling": {Type: Normal, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		return fmt.Sprintf(`Pulling image "%s"`, Image(p))
+	}},
+	"Pulled": {Type: Normal, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		if rand.Intn(3) == 0 {
+			return fmt.Sprintf(`Container image "%s" already present on machine`, Image(p))
+		}
+		pull := time.Duration(200+rand.Intn(8000)) * time.Millisecond
+		wait := pull + time.Duration(rand.Intn(500))*time.Millisecond
+		return fmt.Sprintf(`Successfully pulled image "%s" in %s (%s including waiting). Image size: %d bytes.`, Image(p), pull, wait, 20_000_000+rand.Intn(400_000_000))
+	}},
+	"Created": {Type: Normal, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		return "Created container " + p.Service
+	}},
+	"Started": {Type: Normal, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		return "Started container " + p.Service
+	}},
+	"Killing": {Type: Normal, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		return fmt.Sprintf("Stopping container %s", p.Service)
+	}},
+	"Unhealthy": {Type: Warning, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		if rand.Intn(4) == 0 {
+			return "Liveness probe failed: Get \"http://" + podIP(p) + ":8080/healthz\": context deadline exceeded (Client.Timeout exceeded while awaiting headers)"
+		}
+		return fmt.Sprintf("Readiness probe failed: HTTP probe failed with statuscode: %d", []int{500, 503}[rand.Intn(2)])
+	}},
+	"BackOff": {Type: Warning, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		return fmt.Sprintf("Back-off restarting failed container %s in pod %s_%s(%s)", p.Service, p.Name, p.Namespace, p.UID())
+	}},
+	"OOMKilled": {Type: Warning, Component: "kubelet", Container: true, Message: func(p log.Pod) string {
+		return fmt.Sprintf("Container %s exceeded its memory limit of %dMi and was OOMKilled (exit code 137)", p.Service, 128<<rand.Intn(4))
+	}},
+}
+
+// podIP returns the IP of a pod, the same for every event.
+func podIP(p log.Pod) string {
+	uid := p.UID()
+	return fmt.Sprintf("10.%d.%d.%d", 100+int(uid[0])%28, int(uid[1])*int(uid[2])%256, 1+int(uid[3])*7%254)
+}
+
+func resourceVersion() string {
+	return strconv.Itoa(10_000_000 + rand.Intn(90_000_000))
+}
+
+// startup are the events of a new pod, with the time it takes to get to each of them.
+var startup = []struct {
+	reason string
+	after  time.Duration
+}{
+	{"Scheduled", 0},
+	{"Pulling", 300 * time.Millisecond},
+	{"Pulled", 3 * time.Second},
+	{"Created", 200 * time.Millisecond},
+	{"Started", 500 * time.Millisecond},
+}
+
+// Events generates the events of the pods of a cluster. It watches the pods of log.Pods and
+// reports the pods started, stopped and crash looping since its previous call, along with
+// random probe failures, scheduling failures and OOM kills.
+type Events struct {
+	cluster string
+	pods    map[string]log.Pod
+	synced  bool
+	// backOffs are the back off events of crash looping pods, repeated with a higher count.
+	backOffs map[string]*Event
+}
+
+// NewEvents creates the events of a cluster.
+func NewEvents(cluster string) *Events {
+	return &Events{cluster: cluster, pods: map[string]log.Pod{}, backOffs: map[string]*Event{}}
+}
+
+// Next returns the events that happened since the previous call, sorted by time and ending at t.
+// Pods running at the first call don't have startup events.
+func (e *Events) Next(t time.Time) []Event {
+	var events []Event
+	current := log.Pods.List(e.cluster)
+	seen := make(map[string]bool, len(current))
+	for _, p := range current {
+		seen[p.Name] = true
+		if _, ok := e.pods[p.Name]; !ok && e.synced {
+			events = append(events, e.started(t, p)...)
+		}
+		e.pods[p.Name] = p
+		if p.Phase == log.PodCrashLoopBackOff {
+			events = append(events, e.backOff(t, p)...)
+		} else {
+			delete(e.backOffs, p.Name)
+		}
+	}
+	for name, p := range e.pods {
+		if !seen[name] {
+			events = append(events, NewEvent(t, p, "Killing"))
+			delete(e.pods, name)
+			delete(e.backOffs, name)
+		}
+	}
+	e.synced = true
+
+	if len(current) > 0 {
+		p := current[rand.Intn(len(current))]
+		switch r := rand.Float64(); {
+		case r < 0.05:
+			events = append(events, NewEvent(t, p, "Unhealthy"))
+		case r < 0.06:
+			events = append(events, NewEvent(t, p, "OOMKilled"))
+		case r < 0.08:
+			// A pod of a scaled up deployment waits for a node.
+			pending := p
+			pending.Name = pendingName(p)
+			events = append(events, NewEvent(t, pending, "FailedScheduling"))
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time().Before(events[j].Time()) })
+	return events
+}
+
+// started returns the startup events of a pod, the last one at t.
+func (e *Events) started(t time.Time, p log.Pod) []Event {
+	var total time.Duration
+	for _, s := range startup {
+		total += s.after
+	}
+	events := make([]Event, 0, len(startup)+1)
+	at := t.Add(-total)
+	if rand.Intn(10) == 0 {
+		events = append(events, NewEvent(at.Add(-time.Duration(5+rand.Intn(60))*time.Second), p, "FailedScheduling"))
+	}
+	for _, s := range startup {
+		at = at.Add(s.after)
+		events = append(events, NewEvent(at, p, s.reason))
+	}
+	return events
+}
+
+// backOff returns the back off event of a crash looping pod, which is counted again on every
+// call, and its OOM kill the first time for some pods.
+func (e *Events) backOff(t time.Time, p log.Pod) []Event {
+	ev, ok := e.backOffs[p.Name]
+	if !ok {
+		first := NewEvent(t, p, "BackOff")
+		e.backOffs[p.Name] = &first
+		if rand.Intn(3) == 0 {
+			return []Event{NewEvent(t, p, "OOMKilled"), first}
+		}
+		return []Event{first}
+	}
+	ev.Count++
+	ev.LastTimestamp = Time(t)
+	ev.Metadata.ResourceVersion = resourceVersion()
+	return []Event{*ev}
+}
+
+// pendingName returns the name of a new pod of the deployment of p.
+func pendingName(p log.Pod) string {
+	name := p.Name
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		name = name[:i]
+	}
+	return name + "-" + log.RandSeq(5)
+}
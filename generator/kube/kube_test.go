@@ -0,0 +1,127 @@
+package kube
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent(t *testing.T) {
+	a := assert.New(t)
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 123, time.UTC)
+	p := log.Pod{Cluster: "eu-west-1", Namespace: "shop", Service: "cart", Name: "cart-x7k2p", Node: "node-1"}
+
+	e := NewEvent(ts, p, "BackOff")
+	a.Equal(log.WARN, e.Level())
+	var obj map[string]any
+	require.NoError(t, json.Unmarshal([]byte(e.String()), &obj))
+	a.Equal("Event", obj["kind"])
+	a.Equal("BackOff", obj["reason"])
+	a.Equal("Warning", obj["type"])
+	a.Equal("2024-05-01T10:00:00Z", obj["lastTimestamp"])
+	a.Nil(obj["eventTime"])
+	involved := obj["involvedObject"].(map[string]any)
+	a.Equal("cart-x7k2p", involved["name"])
+	a.Equal(p.UID(), involved["uid"])
+	a.Equal("spec.containers{cart}", involved["fieldPath"])
+	a.Equal(map[string]any{"component": "kubelet", "host": "node-1"}, obj["source"])
+	a.Equal("Back-off restarting failed container cart in pod cart-x7k2p_shop("+p.UID()+")", obj["message"])
+
+	a.Equal(log.INFO, NewEvent(ts, p, "Scheduled").Level())
+	a.Equal("Successfully assigned shop/cart-x7k2p to node-1", NewEvent(ts, p, "Scheduled").Message)
+	a.Panics(func() { NewEvent(ts, p, "Nope") })
+}
+
+func TestEvents(t *testing.T) {
+	a := assert.New(t)
+	const cluster = "kube-test"
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	log.Pods.Add(log.Pod{Cluster: cluster, Namespace: "shop", Service: "cart", Name: "cart-aaaaa"})
+	events := NewEvents(cluster)
+
+	reasons := func(events []Event) []string {
+		var out []string
+		for _, e := range events {
+			if e.InvolvedObject.Name != "cart-aaaaa" || (e.Reason != "Unhealthy" && e.Reason != "OOMKilled" && e.Reason != "FailedScheduling") {
+				out = append(out, e.InvolvedObject.Name+" "+e.Reason)
+			}
+		}
+		return out
+	}
+	a.Empty(reasons(events.Next(ts)), "running pods have no startup events")
+
+	p := log.Pods.Add(log.Pod{Cluster: cluster, Namespace: "shop", Service: "cart", Name: "cart-bbbbb"})
+	next := events.Next(ts.Add(time.Minute))
+	var started []string
+	for _, e := range next {
+		if e.InvolvedObject.Name == p.Name && e.Reason != "FailedScheduling" && e.Reason != "Unhealthy" && e.Reason != "OOMKilled" {
+			started = append(started, e.Reason)
+		}
+		a.False(e.Time().After(ts.Add(time.Minute)))
+	}
+	a.Equal([]string{"Scheduled", "Pulling", "Pulled", "Created", "Started"}, started)
+	for i := 1; i < len(next); i++ {
+		a.False(next[i].Time().Before(next[i-1].Time()), "events are sorted")
+	}
+
+	log.Pods.SetPhase(cluster, p.Name, log.PodCrashLoopBackOff)
+	backOff := func(events []Event) *Event {
+		for _, e := range events {
+			if e.InvolvedObject.Name == p.Name && e.Reason == "BackOff" {
+				return &e
+			}
+		}
+		return nil
+	}
+	first := backOff(events.Next(ts.Add(2 * time.Minute)))
+	require.NotNil(t, first)
+	second := backOff(events.Next(ts.Add(3 * time.Minute)))
+	require.NotNil(t, second)
+	a.Equal(first.Metadata.Name, second.Metadata.Name, "back offs are counted on the same event")
+	a.Equal(2, second.Count)
+	a.Equal(Time(ts.Add(2*time.Minute)), second.FirstTimestamp)
+
+	log.Pods.Remove(cluster, p.Name)
+	a.Contains(reasons(events.Next(ts.Add(4*time.Minute))), "cart-bbbbb Killing")
+}
+
+func TestAuditEvent(t *testing.T) {
+	a := assert.New(t)
+	const cluster = "audit-test"
+	log.Pods.Add(log.Pod{Cluster: cluster, Namespace: "shop", Service: "cart", Name: "cart-ccccc"})
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	codes := map[int]bool{}
+	for i := 0; i < 500; i++ {
+		e := NewAuditEvent(ts, cluster)
+		codes[e.ResponseStatus.Code] = true
+		a.Equal(ts, e.Time())
+		a.True(time.Time(e.RequestReceivedTimestamp).Before(ts))
+
+		var obj map[string]any
+		require.NoError(t, json.Unmarshal([]byte(e.String()), &obj))
+		a.Equal("audit.k8s.io/v1", obj["apiVersion"])
+		a.Equal("Metadata", obj["level"])
+		a.Regexp(`^2024-05-01T10:00:00\.000000Z$`, obj["stageTimestamp"])
+		a.True(strings.HasPrefix(e.RequestURI, "/api/v1/") || strings.HasPrefix(e.RequestURI, "/apis/"), e.RequestURI)
+		if e.ObjectRef.Resource == "pods" && e.ObjectRef.Name != "" {
+			a.Equal("cart-ccccc", e.ObjectRef.Name)
+			a.Contains(e.RequestURI, "/namespaces/shop/pods/cart-ccccc")
+		}
+		if e.ResponseStatus.Code == 403 {
+			a.Equal("forbid", e.Annotations["authorization.k8s.io/decision"])
+			a.Equal(log.WARN, e.LogLevel())
+			a.Contains(e.ResponseStatus.Message, "is forbidden: User \"bob@example.com\"")
+		}
+		if strings.HasPrefix(e.User.Username, "system:node:") {
+			a.Contains(log.Nodes(cluster), strings.TrimPrefix(e.User.Username, "system:node:"))
+		}
+	}
+	a.True(codes[200])
+	a.True(codes[403])
+}
@@ -0,0 +1,256 @@
+// Package lifecycle simulates the pods of Kubernetes deployments. Pods keep their name for their
+// lifetime, are replaced by rollouts, scaled with the load curve like a horizontal pod
+// autoscaler would, and some of them crash loop. Every pod is a stream of its own, so running
+// services churn streams the way real clusters do.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/load"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// Options configures a deployment.
+type Options struct {
+	// Replicas is the number of pods at the base load, 3 by default. The load curve scales it
+	// between MinReplicas and MaxReplicas.
+	Replicas, MinReplicas, MaxReplicas int
+	// RolloutInterval is the time between two rollouts in seconds, every 6h on average by default.
+	RolloutInterval dist.Distribution
+	// CrashRate is the probability for a new pod to crash loop, 0.05 by default.
+	CrashRate float64
+	// SyncPeriod is the time between two autoscaling decisions, 30s by default.
+	SyncPeriod time.Duration
+	// ScaleDownDelay is how long the load must stay lower before pods are removed, 5m by default.
+	ScaleDownDelay time.Duration
+}
+
+func (o *Options) defaults() {
+	if o.Replicas <= 0 {
+		o.Replicas = 3
+	}
+	if o.MinReplicas <= 0 {
+		o.MinReplicas = 1
+	}
+	if o.MaxReplicas < o.MinReplicas {
+		o.MaxReplicas = max(o.MinReplicas, 3*o.Replicas)
+	}
+	if o.RolloutInterval == nil {
+		o.RolloutInterval = dist.Exponential{Mean: 6 * 3600}
+	}
+	if o.CrashRate == 0 {
+		o.CrashRate = 0.05
+	}
+	if o.SyncPeriod <= 0 {
+		o.SyncPeriod = 30 * time.Second
+	}
+	if o.ScaleDownDelay <= 0 {
+		o.ScaleDownDelay = 5 * time.Minute
+	}
+}
+
+// Pod is a running pod.
+type Pod struct {
+	Name string
+	// Revision is the pod template hash of the rollout that created the pod.
+	Revision string
+	// Restarts is the number of times the container restarted.
+	Restarts int
+}
+
+// StartFunc starts the container of a pod, logging to app until ctx is done.
+type StartFunc func(ctx context.Context, app *log.AppLogger, pod Pod)
+
+// Deployment runs the pods of a service.
+type Deployment struct {
+	// Labels are the stream labels of the service, each pod adds its pod label.
+	Labels model.LabelSet
+	Logger log.Logger
+	Levels log.LevelProfile
+	Options
+
+	name  string
+	start StartFunc
+
+	mtx      sync.Mutex
+	revision string
+	pods     []*pod
+	// lowSince is when the load went below the current number of pods.
+	lowSince time.Time
+}
+
+type pod struct {
+	Pod
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// Start runs the deployment until ctx is done.
+func (d *Deployment) Start(ctx context.Context, start StartFunc) {
+	d.Options.defaults()
+	d.name = string(d.Labels["service_name"])
+	d.start = start
+	d.revision = hash(10)
+	go d.run(ctx)
+}
+
+func (d *Deployment) run(ctx context.Context) {
+	now := load.Now()
+	d.scale(ctx, d.desired(now), now)
+	nextRollout := now.Add(seconds(d.RolloutInterval))
+	for load.Wait(ctx, now.Add(d.SyncPeriod)) {
+		now = load.Now()
+		if !now.Before(nextRollout) {
+			go d.rollout(ctx)
+			nextRollout = now.Add(seconds(d.RolloutInterval))
+		}
+		d.scale(ctx, d.desired(now), now)
+	}
+}
+
+// desired returns the replicas needed at t, like an autoscaler targeting the load per pod.
+func (d *Deployment) desired(t time.Time) int {
+	replicas := int(math.Ceil(float64(d.Replicas) * load.Default.Factor(t)))
+	return min(max(replicas, d.MinReplicas), d.MaxReplicas)
+}
+
+// scale adds pods right away and removes them once the load stayed lower for ScaleDownDelay.
+func (d *Deployment) scale(ctx context.Context, desired int, now time.Time) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if desired >= len(d.pods) {
+		d.lowSince = time.Time{}
+	}
+	for len(d.pods) < desired {
+		d.pods = append(d.pods, d.newPod(ctx))
+	}
+	if desired < len(d.pods) {
+		if d.lowSince.IsZero() {
+			d.lowSince = now
+		}
+		if now.Sub(d.lowSince) >= d.ScaleDownDelay {
+			for _, p := range d.pods[desired:] {
+				p.stop()
+			}
+			d.pods = d.pods[:desired]
+			d.lowSince = time.Time{}
+		}
+	}
+}
+
+// rollout replaces the pods of older revisions one by one with pods of a new revision. It runs
+// alongside scale, which keeps adding and removing pods meanwhile, and stops when a newer
+// rollout supersedes it.
+func (d *Deployment) rollout(ctx context.Context) {
+	d.mtx.Lock()
+	d.revision = hash(10)
+	revision := d.revision
+	d.mtx.Unlock()
+	for ctx.Err() == nil {
+		d.mtx.Lock()
+		i := slices.IndexFunc(d.pods, func(p *pod) bool { return p.Revision != revision })
+		if d.revision != revision || i < 0 {
+			d.mtx.Unlock()
+			return
+		}
+		old := d.pods[i]
+		next := d.newPod(ctx)
+		d.pods = append(slices.Delete(d.pods, i, i+1), next)
+		d.mtx.Unlock()
+		// The old pod terminates once the new one is ready.
+		if !load.Wait(ctx, load.Now().Add(time.Duration(10+rand.Intn(20))*time.Second)) {
+			return
+		}
+		old.stop()
+		<-old.done
+	}
+}
+
+// Pods returns the running pods.
+func (d *Deployment) Pods() []Pod {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	out := make([]Pod, len(d.pods))
+	for i, p := range d.pods {
+		out[i] = p.Pod
+	}
+	return out
+}
+
+func (d *Deployment) newPod(ctx context.Context) *pod {
+	ctx, stop := context.WithCancel(ctx)
+	p := &pod{
+		Pod:  Pod{Name: fmt.Sprintf("%s-%s-%s", d.name, d.revision, hash(5)), Revision: d.revision},
+		stop: stop,
+		done: make(chan struct{}),
+	}
+	app := log.NewAppLogger(d.Labels.Merge(model.LabelSet{"pod": model.LabelValue(p.Name)}), d.Logger).WithLevels(d.Levels)
+	log.Pods.Add(log.Pod{Cluster: d.cluster(), Namespace: string(d.Labels["namespace"]), Service: d.name, Name: p.Name})
+	go d.runPod(ctx, p, app, rand.Float64() < d.CrashRate)
+	return p
+}
+
+// runPod runs the container of a pod and restarts it when it crashes, with the exponential back
+// off of Kubernetes.
+func (d *Deployment) runPod(ctx context.Context, p *pod, app *log.AppLogger, crashing bool) {
+	defer close(p.done)
+	defer log.Pods.Remove(d.cluster(), p.Name)
+	for ctx.Err() == nil {
+		containerCtx, stop := context.WithCancel(ctx)
+		logBanner(app, d.name, p.Pod)
+		d.start(containerCtx, app, p.Pod)
+		if !crashing {
+			<-ctx.Done()
+			stop()
+			logShutdown(app)
+			return
+		}
+		uptime := time.Duration(dist.Between(dist.LogNormal{Median: 20, Sigma: 1}, 1, 600) * float64(time.Second))
+		if !load.Wait(ctx, load.Now().Add(uptime)) {
+			stop()
+			logShutdown(app)
+			return
+		}
+		stop()
+		logPanic(app, d.name)
+		log.Pods.SetPhase(d.cluster(), p.Name, log.PodCrashLoopBackOff)
+		backoff := min(10*time.Second<<min(p.Restarts, 5), 5*time.Minute)
+		d.mtx.Lock()
+		p.Restarts++
+		d.mtx.Unlock()
+		// Some crash loops heal, e.g. once a dependency is back.
+		crashing = rand.Float64() > 0.2
+		if !load.Wait(ctx, load.Now().Add(backoff)) {
+			return
+		}
+		log.Pods.SetPhase(d.cluster(), p.Name, log.PodRunning)
+	}
+}
+
+func (d *Deployment) cluster() string {
+	return string(d.Labels["cluster"])
+}
+
+// hashAlphabet is the alphabet of Kubernetes generated names.
+const hashAlphabet = "bcdfghjklmnpqrstvwxz2456789"
+
+func hash(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = hashAlphabet[rand.Intn(len(hashAlphabet))]
+	}
+	return string(b)
+}
+
+func seconds(d dist.Distribution) time.Duration {
+	return time.Duration(math.Max(d.Sample(), 1) * float64(time.Second))
+}
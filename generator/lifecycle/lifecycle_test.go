@@ -0,0 +1,115 @@
+package lifecycle
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lines struct {
+	mtx  sync.Mutex
+	pods map[model.LabelValue][]string
+}
+
+func (l *lines) logger() log.Logger {
+	l.pods = map[model.LabelValue][]string{}
+	return log.LoggerFunc(func(labels model.LabelSet, _ time.Time, message string, _ push.LabelsAdapter) error {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		l.pods[labels["pod"]] = append(l.pods[labels["pod"]], message)
+		return nil
+	})
+}
+
+func (l *lines) get(pod string) []string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return append([]string(nil), l.pods[model.LabelValue(pod)]...)
+}
+
+func newDeployment(logger log.Logger, opts Options) *Deployment {
+	d := &Deployment{
+		Labels:  model.LabelSet{"service_name": "checkout", "cluster": "eu-west-1"},
+		Logger:  logger,
+		Options: opts,
+	}
+	d.Options.defaults()
+	d.name = "checkout"
+	d.revision = hash(10)
+	d.start = func(context.Context, *log.AppLogger, Pod) {}
+	return d
+}
+
+func TestScale(t *testing.T) {
+	a := assert.New(t)
+	var l lines
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newDeployment(l.logger(), Options{Replicas: 2, MaxReplicas: 4})
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	d.scale(ctx, 3, now)
+	pods := d.Pods()
+	require.Len(t, pods, 3)
+	for _, p := range pods {
+		a.True(strings.HasPrefix(p.Name, "checkout-"+d.revision+"-"), p.Name)
+	}
+	a.Len(log.Pods.List("eu-west-1"), 3, "pods are registered")
+
+	// Pods are only removed once the load stayed lower for the delay.
+	d.scale(ctx, 1, now)
+	a.Len(d.Pods(), 3)
+	d.scale(ctx, 1, now.Add(time.Minute))
+	a.Len(d.Pods(), 3)
+	d.scale(ctx, 1, now.Add(5*time.Minute))
+	a.Equal(pods[:1], d.Pods(), "the oldest pods keep their names")
+	assert.Eventually(t, func() bool { return len(log.Pods.List("eu-west-1")) == 1 }, time.Second, time.Millisecond)
+
+	a.Equal(2, d.desired(now))
+}
+
+func TestPodLines(t *testing.T) {
+	a := assert.New(t)
+	var l lines
+	ctx, cancel := context.WithCancel(context.Background())
+	d := newDeployment(l.logger(), Options{Replicas: 1})
+	p := &pod{Pod: Pod{Name: "checkout-abc-x", Revision: "abc"}, done: make(chan struct{})}
+	app := log.NewAppLogger(d.Labels.Merge(model.LabelSet{"pod": model.LabelValue(p.Name)}), d.Logger)
+	go d.runPod(ctx, p, app, false)
+
+	assert.Eventually(t, func() bool { return len(l.get(p.Name)) == 3 }, time.Second, time.Millisecond)
+	a.Contains(l.get(p.Name)[0], `msg="Starting application" app=checkout revision=abc restarts=0`)
+	cancel()
+	<-p.done
+	lines := l.get(p.Name)
+	require.Len(t, lines, 5)
+	a.Contains(lines[3], "shutting down")
+}
+
+func TestPanic(t *testing.T) {
+	var message string
+	app := log.NewAppLogger(model.LabelSet{}, log.LoggerFunc(func(_ model.LabelSet, _ time.Time, m string, _ push.LabelsAdapter) error {
+		message = m
+		return nil
+	}))
+	logPanic(app, "checkout-api")
+	assert.True(t, strings.HasPrefix(message, "panic: "), message)
+	assert.Contains(t, message, "goroutine ")
+	assert.Contains(t, message, "github.com/example/checkoutapi/internal/")
+}
+
+func TestHash(t *testing.T) {
+	h := hash(10)
+	assert.Len(t, h, 10)
+	for _, c := range h {
+		assert.Contains(t, hashAlphabet, string(c))
+	}
+}
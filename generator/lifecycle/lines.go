@@ -0,0 +1,38 @@
+package lifecycle
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/load"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/explore-logs/generator/stacktrace"
+)
+
+var goVersions = []string{"go1.22.5", "go1.23.2", "go1.24.1"}
+
+// logBanner logs the lines of a container starting.
+func logBanner(app *log.AppLogger, service string, p Pod) {
+	t := load.Now()
+	app.Log(log.INFO, t, fmt.Sprintf(`level=info ts=%s caller=main.go:%d msg="Starting application" app=%s revision=%s restarts=%d go_version=%s`,
+		t.UTC().Format(time.RFC3339Nano), 60+len(service)%20, service, p.Revision, p.Restarts, goVersions[len(p.Revision)%len(goVersions)]))
+	t = t.Add(time.Duration(50+rand.Intn(200)) * time.Millisecond)
+	app.Log(log.INFO, t, fmt.Sprintf(`level=info ts=%s caller=config.go:112 msg="loaded configuration" file=/etc/%s/config.yaml`, t.UTC().Format(time.RFC3339Nano), service))
+	t = t.Add(time.Duration(100+rand.Intn(900)) * time.Millisecond)
+	app.Log(log.INFO, t, fmt.Sprintf(`level=info ts=%s caller=server.go:336 msg="server listening on addresses" http=[::]:8080 grpc=[::]:9095`, t.UTC().Format(time.RFC3339Nano)))
+}
+
+// logShutdown logs the lines of a container stopping gracefully.
+func logShutdown(app *log.AppLogger) {
+	t := load.Now()
+	app.Log(log.INFO, t, fmt.Sprintf(`level=info ts=%s caller=signals.go:55 msg="received signal, shutting down" signal=terminated`, t.UTC().Format(time.RFC3339Nano)))
+	t = t.Add(time.Duration(10+rand.Intn(2000)) * time.Millisecond)
+	app.Log(log.INFO, t, fmt.Sprintf(`level=info ts=%s caller=server.go:402 msg="server stopped"`, t.UTC().Format(time.RFC3339Nano)))
+}
+
+// logPanic logs the panic of a crashing Go container. Pods of a service crash with the few
+// panics of its bugs.
+func logPanic(app *log.AppLogger, service string) {
+	app.Log(log.FATAL, load.Now(), stacktrace.New(stacktrace.Go, service, fmt.Sprint(rand.Intn(3))).String())
+}
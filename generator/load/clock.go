@@ -0,0 +1,150 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock is the time of the generators. Live, it is the wall clock. When backfilling, it starts
+// in the past and runs faster than the wall clock until it catches up, so generators write
+// past data at the pace they would have written it, then continue live.
+type Clock struct {
+	mtx   sync.RWMutex
+	curve Curve
+	// from is the virtual time at start, speed how much faster than the wall clock it runs.
+	from, start time.Time
+	speed       float64
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// Default is the clock used by Now and Sleep.
+var Default = NewClock()
+
+// NewClock creates a live clock with a flat curve.
+func NewClock() *Clock {
+	return &Clock{now: time.Now, sleep: time.Sleep}
+}
+
+// SetCurve sets the load curve applied by Sleep.
+func (c *Clock) SetCurve(curve Curve) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.curve = curve
+}
+
+// Backfill moves the clock back to from, it then runs speed times faster than the wall clock
+// until it catches up.
+func (c *Clock) Backfill(from time.Time, speed float64) error {
+	if speed <= 1 {
+		return fmt.Errorf("backfill speed must be greater than 1, got %v", speed)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.from, c.start, c.speed = from, c.now(), speed
+	return nil
+}
+
+// Now returns the current time of the generators.
+func (c *Clock) Now() time.Time {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.virtual(c.now())
+}
+
+// virtual converts a wall clock time.
+func (c *Clock) virtual(real time.Time) time.Time {
+	if c.speed == 0 {
+		return real
+	}
+	v := c.from.Add(time.Duration(float64(real.Sub(c.start)) * c.speed))
+	if v.After(real) {
+		return real
+	}
+	return v
+}
+
+// real converts a virtual time to the wall clock time it happens at.
+func (c *Clock) real(virtual time.Time) time.Time {
+	if c.speed == 0 {
+		return virtual
+	}
+	r := c.start.Add(time.Duration(float64(virtual.Sub(c.from)) / c.speed))
+	if r.Before(virtual) {
+		// The clock catches up with the wall clock before.
+		return virtual
+	}
+	return r
+}
+
+// Sleep waits for d of virtual time divided by the load at the current time: generators
+// sleeping between lines write more when the load is high.
+func (c *Clock) Sleep(d time.Duration) {
+	c.mtx.RLock()
+	real := c.now()
+	now := c.virtual(real)
+	target := now.Add(time.Duration(float64(d) / c.curve.Factor(now)))
+	wait := c.real(target).Sub(real)
+	c.mtx.RUnlock()
+	if wait > 0 {
+		c.sleep(wait)
+	}
+}
+
+// SleepUntil waits until the clock reaches t, regardless of the load.
+func (c *Clock) SleepUntil(t time.Time) {
+	c.mtx.RLock()
+	wait := c.real(t).Sub(c.now())
+	c.mtx.RUnlock()
+	if wait > 0 {
+		c.sleep(wait)
+	}
+}
+
+// Wait is like SleepUntil but returns false as soon as ctx is done.
+func (c *Clock) Wait(ctx context.Context, t time.Time) bool {
+	c.mtx.RLock()
+	wait := c.real(t).Sub(c.now())
+	c.mtx.RUnlock()
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Factor returns the load at t relative to the base rate.
+func (c *Clock) Factor(t time.Time) float64 {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.curve.Factor(t)
+}
+
+// Now returns the current time of the Default clock.
+func Now() time.Time {
+	return Default.Now()
+}
+
+// Sleep sleeps on the Default clock.
+func Sleep(d time.Duration) {
+	Default.Sleep(d)
+}
+
+// SleepUntil sleeps on the Default clock.
+func SleepUntil(t time.Time) {
+	Default.SleepUntil(t)
+}
+
+// Wait waits on the Default clock.
+func Wait(ctx context.Context, t time.Time) bool {
+	return Default.Wait(ctx, t)
+}
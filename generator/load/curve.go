@@ -0,0 +1,133 @@
+// Package load shapes the volume of generated logs over time. A Curve gives the traffic of a
+// moment relative to the base rate of the services, with daily and weekly seasonality, noise
+// and growth, and the Clock applies it while generating live or backfilling the past.
+package load
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Curve is a load curve. Its zero value is flat.
+type Curve struct {
+	// Amplitude of the daily sine, between 0 and 1. Traffic goes from 1-Amplitude at night to
+	// 1+Amplitude at Peak.
+	Amplitude float64
+	// Peak is the hour of the day with the most traffic, e.g. 14.5 for 2:30 PM.
+	Peak float64
+	// Location is the time zone of Peak and of weekends, UTC when nil.
+	Location *time.Location
+	// Weekday and Weekend multiply the traffic of Monday to Friday and of Saturday and Sunday,
+	// zero means 1.
+	Weekday, Weekend float64
+	// Noise is the relative amplitude of slow random variations, e.g. 0.1 for ±10%.
+	Noise float64
+	// Growth is the relative growth per day since Epoch, e.g. 0.01 for 1% a day. It is ignored
+	// without Epoch.
+	Growth float64
+	Epoch  time.Time
+}
+
+// DefaultCurve peaks in the afternoon and halves on weekends.
+var DefaultCurve = Curve{Amplitude: 0.6, Peak: 14, Weekday: 1, Weekend: 0.5, Noise: 0.1}
+
+// minFactor keeps quiet periods from stopping the generators.
+const minFactor = 0.01
+
+// noisePeriod is the time between two random points of the noise.
+const noisePeriod = 10 * time.Minute
+
+// Factor returns the traffic at t relative to the base rate.
+func (c Curve) Factor(t time.Time) float64 {
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+
+	hour := float64(local.Hour()) + float64(local.Minute())/60 + float64(local.Second())/3600
+	f := 1 + c.Amplitude*math.Cos(2*math.Pi*(hour-c.Peak)/24)
+
+	day := c.Weekday
+	if wd := local.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		day = c.Weekend
+	}
+	if day > 0 {
+		f *= day
+	}
+	if c.Noise > 0 {
+		f *= 1 + c.Noise*noise(t)
+	}
+	if c.Growth != 0 && !c.Epoch.IsZero() {
+		f *= math.Pow(1+c.Growth, t.Sub(c.Epoch).Hours()/24)
+	}
+	return math.Max(f, minFactor)
+}
+
+// noise returns a value between -1 and 1 that changes smoothly over time. It only depends on
+// t, so live and backfilled data look alike.
+func noise(t time.Time) float64 {
+	pos := float64(t.UnixNano()) / float64(noisePeriod)
+	k := math.Floor(pos)
+	frac := pos - k
+	// Cosine interpolation between the points around t.
+	w := (1 - math.Cos(frac*math.Pi)) / 2
+	return point(int64(k))*(1-w) + point(int64(k)+1)*w
+}
+
+func point(k int64) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(k, 10)))
+	return float64(h.Sum64()%2001)/1000 - 1
+}
+
+// Parse parses a curve written as comma separated settings starting from DefaultCurve, e.g.
+// "amplitude=0.8,peak=10,tz=Europe/Paris,weekend=0.3,noise=0.05,growth=0.02", or "default".
+// Growth starts at epoch.
+func Parse(spec string, epoch time.Time) (Curve, error) {
+	c := DefaultCurve
+	c.Epoch = epoch
+	for _, setting := range strings.Split(spec, ",") {
+		if s := strings.TrimSpace(setting); s == "" || s == "default" {
+			continue
+		}
+		name, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid load curve setting %q, expected name=value", setting)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "tz" {
+			loc, err := time.LoadLocation(value)
+			if err != nil {
+				return c, fmt.Errorf("load curve: %w", err)
+			}
+			c.Location = loc
+			continue
+		}
+		fields := map[string]*float64{
+			"amplitude": &c.Amplitude,
+			"peak":      &c.Peak,
+			"weekday":   &c.Weekday,
+			"weekend":   &c.Weekend,
+			"noise":     &c.Noise,
+			"growth":    &c.Growth,
+		}
+		field, ok := fields[name]
+		if !ok {
+			return c, fmt.Errorf("unknown load curve setting %q", name)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return c, fmt.Errorf("load curve: invalid %s %q", name, value)
+		}
+		*field = v
+	}
+	if c.Amplitude < 0 || c.Amplitude > 1 {
+		return c, fmt.Errorf("load curve: amplitude must be between 0 and 1")
+	}
+	return c, nil
+}
@@ -0,0 +1,135 @@
+package load
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monday is a Monday at midnight UTC.
+var monday = time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)
+
+func TestCurveFactor(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(1.0, Curve{}.Factor(monday.Add(5*time.Hour)), "the zero curve is flat")
+
+	c := Curve{Amplitude: 0.5, Peak: 14, Weekend: 0.4}
+	a.InDelta(1.5, c.Factor(monday.Add(14*time.Hour)), 1e-9)
+	a.InDelta(0.5, c.Factor(monday.Add(2*time.Hour)), 1e-9)
+	a.InDelta(1, c.Factor(monday.Add(8*time.Hour)), 1e-9)
+	a.InDelta(0.6, c.Factor(monday.Add(5*24*time.Hour+14*time.Hour)), 1e-9, "saturday")
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	c.Location = paris
+	a.InDelta(1.5, c.Factor(monday.Add(12*time.Hour)), 1e-9, "14:00 in Paris is 12:00 UTC in summer")
+
+	c = Curve{Growth: 0.1, Epoch: monday}
+	a.InDelta(1.21, c.Factor(monday.Add(48*time.Hour)), 1e-9)
+	a.Equal(1.0, Curve{Growth: 0.1}.Factor(monday), "growth needs an epoch")
+
+	a.Equal(minFactor, Curve{Amplitude: 1, Peak: 12}.Factor(monday))
+}
+
+func TestNoise(t *testing.T) {
+	a := assert.New(t)
+	c := Curve{Noise: 0.2}
+	lo, hi := 2.0, 0.0
+	prev := c.Factor(monday)
+	for i := 1; i < 24*60; i++ {
+		f := c.Factor(monday.Add(time.Duration(i) * time.Minute))
+		lo, hi = min(lo, f), max(hi, f)
+		a.InDelta(prev, f, 0.1, "noise is smooth")
+		prev = f
+	}
+	a.GreaterOrEqual(lo, 0.8)
+	a.LessOrEqual(hi, 1.2)
+	a.Greater(hi-lo, 0.2)
+	a.Equal(c.Factor(monday.Add(time.Hour)), c.Factor(monday.Add(time.Hour)))
+}
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+	c, err := Parse("default", monday)
+	require.NoError(t, err)
+	expected := DefaultCurve
+	expected.Epoch = monday
+	a.Equal(expected, c)
+
+	c, err = Parse("amplitude=0.8, peak=10.5,tz=UTC,weekday=1.2,weekend=0.3,noise=0,growth=0.02", monday)
+	require.NoError(t, err)
+	a.Equal(Curve{Amplitude: 0.8, Peak: 10.5, Location: time.UTC, Weekday: 1.2, Weekend: 0.3, Growth: 0.02, Epoch: monday}, c)
+
+	for spec, msg := range map[string]string{
+		"peak":          "expected name=value",
+		"peak=noon":     `invalid peak "noon"`,
+		"tz=Nowhere/X":  "unknown time zone",
+		"season=summer": `unknown load curve setting "season"`,
+		"amplitude=2":   "amplitude must be between 0 and 1",
+	} {
+		_, err := Parse(spec, monday)
+		a.ErrorContains(err, msg, spec)
+	}
+}
+
+// fakeClock returns a clock whose wall clock only moves when it sleeps.
+func fakeClock(start time.Time) (*Clock, *time.Time) {
+	wall := start
+	c := NewClock()
+	c.now = func() time.Time { return wall }
+	c.sleep = func(d time.Duration) { wall = wall.Add(d) }
+	return c, &wall
+}
+
+func TestClockLive(t *testing.T) {
+	a := assert.New(t)
+	c, wall := fakeClock(monday.Add(14 * time.Hour))
+	a.Equal(*wall, c.Now())
+	c.Sleep(time.Second)
+	a.Equal(monday.Add(14*time.Hour+time.Second), *wall)
+
+	c.SetCurve(Curve{Amplitude: 0.5, Peak: 14})
+	c.Sleep(3 * time.Second)
+	a.WithinDuration(monday.Add(14*time.Hour+3*time.Second), *wall, time.Microsecond, "sleeps are shorter at the peak")
+}
+
+func TestClockBackfill(t *testing.T) {
+	a := assert.New(t)
+	start := monday.Add(24 * time.Hour)
+	c, wall := fakeClock(start)
+	a.Error(c.Backfill(monday, 1))
+	require.NoError(t, c.Backfill(monday, 25))
+	a.Equal(monday, c.Now())
+
+	// An hour of data is written in 144s.
+	c.Sleep(time.Hour)
+	a.Equal(monday.Add(time.Hour), c.Now())
+	a.Equal(start.Add(144*time.Second), *wall)
+
+	// The clock catches up after 1h, when it reached the wall clock.
+	for c.Now().Before(*wall) {
+		c.Sleep(time.Minute)
+	}
+	a.InDelta(time.Hour, wall.Sub(start), float64(time.Minute))
+	now := *wall
+	c.Sleep(time.Minute)
+	a.Equal(now.Add(time.Minute), *wall, "live once caught up")
+	a.Equal(*wall, c.Now())
+}
+
+func TestClockWait(t *testing.T) {
+	a := assert.New(t)
+	c := NewClock()
+	c.SetCurve(Curve{Amplitude: 0.5, Peak: 12})
+	a.InDelta(0.5, c.Factor(time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)), 1e-9)
+
+	a.True(c.Wait(context.Background(), time.Now().Add(time.Millisecond)))
+	a.True(c.Wait(context.Background(), time.Now().Add(-time.Hour)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+	a.False(c.Wait(ctx, time.Now().Add(time.Hour)))
+}
@@ -8,17 +8,16 @@ import (
 )
 
 type AppLogger struct {
-	labels model.LabelSet
-	levels map[model.LabelValue]model.LabelSet
-	logger Logger
+	labels  model.LabelSet
+	levels  map[model.LabelValue]model.LabelSet
+	logger  Logger
+	profile LevelProfile
 }
 
 func NewAppLogger(labels model.LabelSet, logger Logger) *AppLogger {
-	levels := map[model.LabelValue]model.LabelSet{
-		DEBUG: labels.Merge(model.LabelSet{"level": DEBUG}),
-		INFO:  labels.Merge(model.LabelSet{"level": INFO}),
-		WARN:  labels.Merge(model.LabelSet{"level": WARN}),
-		ERROR: labels.Merge(model.LabelSet{"level": ERROR}),
+	levels := make(map[model.LabelValue]model.LabelSet, len(Levels))
+	for _, level := range Levels {
+		levels[level] = labels.Merge(model.LabelSet{"level": level})
 	}
 	return &AppLogger{
 		labels: labels,
@@ -27,6 +26,32 @@ func NewAppLogger(labels model.LabelSet, logger Logger) *AppLogger {
 	}
 }
 
+// Labels returns the stream labels of the app.
+func (app *AppLogger) Labels() model.LabelSet {
+	return app.labels
+}
+
+// WithLabels returns an app logging with more stream labels.
+func (app *AppLogger) WithLabels(labels model.LabelSet) *AppLogger {
+	return NewAppLogger(app.labels.Merge(labels), app.logger).WithLevels(app.profile)
+}
+
+// WithLevels sets the level profile used by RandLevel and Status.
+func (app *AppLogger) WithLevels(profile LevelProfile) *AppLogger {
+	app.profile = profile
+	return app
+}
+
+// RandLevel returns a random level following the app's level profile.
+func (app *AppLogger) RandLevel() model.LabelValue {
+	return app.profile.RandLevel()
+}
+
+// Status returns an HTTP status code for a level following the app's level profile.
+func (app *AppLogger) Status(level model.LabelValue) int {
+	return app.profile.Status(level)
+}
+
 func (app *AppLogger) Log(level model.LabelValue, t time.Time, message string) {
 	labels, ok := app.levels[level]
 	if !ok {
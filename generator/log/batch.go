@@ -0,0 +1,138 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var errLoggerStopped = errors.New("logger is stopped")
+
+// BatchConfig controls how HTTP sinks group entries into requests.
+type BatchConfig struct {
+	// Size is the maximum number of entries per request.
+	Size int
+	// Wait is the maximum time an entry waits before a partial batch is sent.
+	Wait time.Duration
+	// Concurrency is the number of requests in flight at the same time.
+	Concurrency int
+}
+
+func (cfg BatchConfig) withDefaults() BatchConfig {
+	if cfg.Size <= 0 {
+		cfg.Size = 500
+	}
+	if cfg.Wait <= 0 {
+		cfg.Wait = time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return cfg
+}
+
+// batcher groups entries and hands full batches to a pool of senders.
+type batcher[T any] struct {
+	name    string
+	cfg     BatchConfig
+	send    func([]T) error
+	entries chan T
+	batches chan []T
+	quit    chan struct{}
+	wg      sync.WaitGroup
+
+	// mtx is held by add while it checks stopped and queues its entry, so that stop closes quit
+	// only after the entries added before it are queued: the final flush can't miss them.
+	mtx     sync.RWMutex
+	stopped bool
+}
+
+func newBatcher[T any](name string, cfg BatchConfig, send func([]T) error) *batcher[T] {
+	cfg = cfg.withDefaults()
+	b := &batcher[T]{
+		name:    name,
+		cfg:     cfg,
+		send:    send,
+		entries: make(chan T, cfg.Size),
+		batches: make(chan []T, cfg.Concurrency),
+		quit:    make(chan struct{}),
+	}
+	b.wg.Add(cfg.Concurrency)
+	for i := 0; i < cfg.Concurrency; i++ {
+		go b.sender()
+	}
+	go b.run()
+	return b
+}
+
+func (b *batcher[T]) add(entry T) error {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	if b.stopped {
+		return errLoggerStopped
+	}
+	b.entries <- entry
+	return nil
+}
+
+func (b *batcher[T]) run() {
+	ticker := time.NewTicker(b.cfg.Wait)
+	defer ticker.Stop()
+	defer close(b.batches)
+
+	batch := make([]T, 0, b.cfg.Size)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		b.batches <- batch
+		batch = make([]T, 0, b.cfg.Size)
+	}
+
+	for {
+		select {
+		case entry := <-b.entries:
+			batch = append(batch, entry)
+			if len(batch) >= b.cfg.Size {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-b.quit:
+			for {
+				select {
+				case entry := <-b.entries:
+					batch = append(batch, entry)
+					if len(batch) >= b.cfg.Size {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *batcher[T]) sender() {
+	defer b.wg.Done()
+	for batch := range b.batches {
+		if err := b.send(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: failed to send batch of %d entries: %v\n", b.name, len(batch), err)
+		}
+	}
+}
+
+// stop flushes pending entries and waits for in-flight requests.
+func (b *batcher[T]) stop() {
+	b.mtx.Lock()
+	if !b.stopped {
+		b.stopped = true
+		close(b.quit)
+	}
+	b.mtx.Unlock()
+	b.wg.Wait()
+}
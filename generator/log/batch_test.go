@@ -0,0 +1,40 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatcherStopKeepsAddedEntries(t *testing.T) {
+	a := assert.New(t)
+
+	var sent atomic.Int64
+	b := newBatcher("test", BatchConfig{Size: 10, Wait: time.Hour}, func(entries []int) error {
+		sent.Add(int64(len(entries)))
+		return nil
+	})
+
+	var added atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if b.add(j) == nil {
+					added.Add(1)
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	b.stop()
+	wg.Wait()
+
+	a.Equal(added.Load(), sent.Load(), "every accepted entry is sent")
+	a.ErrorIs(b.add(1), errLoggerStopped)
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// DryFormat is the encoding used by the dry run logger.
+type DryFormat string
+
+const (
+	// DryDefault is Go's default formatting of labels, timestamp, message and metadata.
+	DryDefault DryFormat = "default"
+	// DryLogcli matches the default output of `logcli query`.
+	DryLogcli DryFormat = "logcli"
+	// DryJSON writes one JSON object per line with separate labels, metadata and timestamp.
+	DryJSON DryFormat = "json"
+	// DryPush writes one Loki push request JSON body per line.
+	DryPush DryFormat = "push"
+)
+
+// DryFormats lists the supported dry run formats.
+var DryFormats = []DryFormat{DryDefault, DryLogcli, DryJSON, DryPush}
+
+// pushRequest mirrors the JSON body accepted by /loki/api/v1/push.
+type pushRequest struct {
+	Streams []pushStream `json:"streams"`
+}
+
+type pushStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][]any           `json:"values"`
+}
+
+// DryLogger implements the Logger interface and writes entries to a writer instead of Loki.
+type DryLogger struct {
+	format DryFormat
+	mtx    sync.Mutex
+	w      io.Writer
+}
+
+// NewDryLogger creates a dry run logger for the given format.
+func NewDryLogger(w io.Writer, format DryFormat) (*DryLogger, error) {
+	for _, f := range DryFormats {
+		if f == format {
+			return &DryLogger{format: format, w: w}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported dry run format %q", format)
+}
+
+// Handle implements the Logger interface
+func (d *DryLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return d.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface
+func (d *DryLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	line, err := d.encode(labels, timestamp, message, metadata)
+	if err != nil {
+		return err
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	_, err = io.WriteString(d.w, line)
+	return err
+}
+
+func (d *DryLogger) encode(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) (string, error) {
+	switch d.format {
+	case DryLogcli:
+		// logcli shows structured metadata as part of the stream labels.
+		stream := labels.Clone()
+		for _, m := range metadata {
+			stream[model.LabelName(m.Name)] = model.LabelValue(m.Value)
+		}
+		return fmt.Sprintf("%s %s %s\n", timestamp.Format(time.RFC3339Nano), stream, message), nil
+	case DryJSON:
+		line, err := json.Marshal(NewEntry(labels, timestamp, message, metadata))
+		return string(line) + "\n", err
+	case DryPush:
+		entry := NewEntry(labels, timestamp, message, metadata)
+		value := []any{strconv.FormatInt(timestamp.UnixNano(), 10), message}
+		if len(entry.Metadata) > 0 {
+			value = append(value, entry.Metadata)
+		}
+		line, err := json.Marshal(pushRequest{Streams: []pushStream{{Stream: entry.Labels, Values: [][]any{value}}}})
+		return string(line) + "\n", err
+	default:
+		return fmt.Sprintln(labels, timestamp, message, metadata), nil
+	}
+}
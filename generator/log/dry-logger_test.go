@@ -0,0 +1,47 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryLogger(t *testing.T) {
+	labels := model.LabelSet{"service_name": "nginx", "level": INFO}
+	metadata := push.LabelsAdapter{{Name: "pod", Value: "nginx-1"}}
+	ts := time.Date(2024, 9, 13, 10, 18, 49, 5, time.UTC)
+
+	for _, tc := range []struct {
+		format   DryFormat
+		expected string
+	}{
+		{DryDefault, `{level="info", service_name="nginx"} 2024-09-13 10:18:49.000000005 +0000 UTC GET /api 200 [{pod nginx-1}]` + "\n"},
+		{DryLogcli, `2024-09-13T10:18:49.000000005Z {level="info", pod="nginx-1", service_name="nginx"} GET /api 200` + "\n"},
+		{DryJSON, `{"ts":"2024-09-13T10:18:49.000000005Z","labels":{"level":"info","service_name":"nginx"},"metadata":{"pod":"nginx-1"},"line":"GET /api 200"}` + "\n"},
+		{DryPush, `{"streams":[{"stream":{"level":"info","service_name":"nginx"},"values":[["1726222729000000005","GET /api 200",{"pod":"nginx-1"}]]}]}` + "\n"},
+	} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := NewDryLogger(&buf, tc.format)
+			require.NoError(t, err)
+			assert.NoError(t, logger.HandleWithMetadata(labels, ts, "GET /api 200", metadata))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+
+	_, err := NewDryLogger(&bytes.Buffer{}, "yaml")
+	assert.Error(t, err)
+}
+
+func TestDryLoggerPushWithoutMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewDryLogger(&buf, DryPush)
+	require.NoError(t, err)
+	assert.NoError(t, logger.Handle(model.LabelSet{"service_name": "nginx"}, time.Unix(1, 0), "hello"))
+	assert.Equal(t, `{"streams":[{"stream":{"service_name":"nginx"},"values":[["1000000000","hello"]]}]}`+"\n", buf.String())
+}
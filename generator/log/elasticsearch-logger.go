@@ -0,0 +1,169 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// ElasticsearchConfig configures an ElasticsearchLogger.
+type ElasticsearchConfig struct {
+	// URL is the base URL of the cluster, the _bulk path is appended.
+	URL      string
+	Index    string
+	Username string
+	Password string
+	Batch    BatchConfig
+	Client   *http.Client
+}
+
+// ElasticsearchLogger implements the Logger interface and writes documents to an
+// Elasticsearch or OpenSearch _bulk endpoint.
+type ElasticsearchLogger struct {
+	cfg     ElasticsearchConfig
+	bulkURL string
+	batcher *batcher[ElasticsearchDocument]
+}
+
+// ElasticsearchDocument is the document indexed for every log line.
+type ElasticsearchDocument struct {
+	Timestamp string            `json:"@timestamp"`
+	Message   string            `json:"message"`
+	Level     string            `json:"level,omitempty"`
+	Labels    map[string]string `json:"labels"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// NewElasticsearchLogger creates a new Elasticsearch bulk logger.
+func NewElasticsearchLogger(cfg ElasticsearchConfig) (*ElasticsearchLogger, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("elasticsearch url is required")
+	}
+	if cfg.Index == "" {
+		cfg.Index = "explore-logs"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	e := &ElasticsearchLogger{
+		cfg:     cfg,
+		bulkURL: strings.TrimSuffix(cfg.URL, "/") + "/_bulk",
+	}
+	e.batcher = newBatcher("elasticsearch", cfg.Batch, e.send)
+	return e, nil
+}
+
+// Handle implements the Logger interface
+func (e *ElasticsearchLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return e.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface, the entry is sent with the next batch.
+func (e *ElasticsearchLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	doc := ElasticsearchDocument{
+		Timestamp: timestamp.Format(time.RFC3339Nano),
+		Message:   message,
+		Level:     string(labels["level"]),
+		Labels:    make(map[string]string, len(labels)),
+	}
+	for k, v := range labels {
+		doc.Labels[string(k)] = string(v)
+	}
+	if len(metadata) > 0 {
+		doc.Metadata = make(map[string]string, len(metadata))
+		for _, m := range metadata {
+			doc.Metadata[m.Name] = m.Value
+		}
+	}
+	return e.batcher.add(doc)
+}
+
+// Stop flushes pending documents and waits for in-flight bulk requests.
+func (e *ElasticsearchLogger) Stop() {
+	e.batcher.stop()
+}
+
+func (e *ElasticsearchLogger) send(docs []ElasticsearchDocument) error {
+	body, err := EncodeBulk(e.cfg.Index, docs)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.bulkURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.cfg.Username != "" {
+		req.SetBasicAuth(e.cfg.Username, e.cfg.Password)
+	}
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("bulk request failed with status %d: %s", resp.StatusCode, respBody)
+	}
+
+	var result bulkResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed, reason := 0, ""
+	for _, item := range result.Items {
+		for _, status := range item {
+			if status.Status/100 != 2 {
+				failed++
+				reason = status.Error.Type + ": " + status.Error.Reason
+			}
+		}
+	}
+	return fmt.Errorf("%d of %d documents were rejected, last error %s", failed, len(docs), reason)
+}
+
+// EncodeBulk renders documents as an NDJSON _bulk request body using create actions,
+// which works for both regular indices and data streams.
+func EncodeBulk(index string, docs []ElasticsearchDocument) ([]byte, error) {
+	action, err := json.Marshal(map[string]map[string]string{"create": {"_index": index}})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		line, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,105 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkStandIn is a minimal _bulk endpoint that validates the NDJSON body.
+type bulkStandIn struct {
+	mtx      sync.Mutex
+	requests int
+	docs     []ElasticsearchDocument
+	errs     []error
+}
+
+func (s *bulkStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.requests++
+
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		s.errs = append(s.errs, fmt.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type")))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || action["create"]["_index"] != "logs" {
+			s.errs = append(s.errs, fmt.Errorf("invalid action line %q", scanner.Text()))
+		}
+		if !scanner.Scan() {
+			s.errs = append(s.errs, fmt.Errorf("action line without document"))
+			break
+		}
+		var doc ElasticsearchDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			s.errs = append(s.errs, err)
+		}
+		s.docs = append(s.docs, doc)
+	}
+	_, _ = fmt.Fprintf(w, `{"took":1,"errors":false,"items":[]}`)
+}
+
+func TestElasticsearchLogger(t *testing.T) {
+	a := assert.New(t)
+
+	standIn := &bulkStandIn{}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	logger, err := NewElasticsearchLogger(ElasticsearchConfig{
+		URL:   server.URL,
+		Index: "logs",
+		Batch: BatchConfig{Size: 2, Wait: time.Hour, Concurrency: 2},
+	})
+	require.NoError(t, err)
+
+	labels := model.LabelSet{"service_name": "nginx", "level": INFO}
+	ts := time.Date(2024, 9, 13, 10, 18, 49, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		a.NoError(logger.HandleWithMetadata(labels, ts, fmt.Sprintf("line %d", i), push.LabelsAdapter{{Name: "pod", Value: "nginx-1"}}))
+	}
+	logger.Stop()
+	a.Equal(errLoggerStopped, logger.Handle(labels, ts, "after stop"))
+
+	a.Empty(standIn.errs)
+	a.Equal(3, standIn.requests)
+	require.Len(t, standIn.docs, 5)
+	// Batches are sent concurrently so they may arrive out of order.
+	sort.Slice(standIn.docs, func(i, j int) bool { return standIn.docs[i].Message < standIn.docs[j].Message })
+	a.Equal(ElasticsearchDocument{
+		Timestamp: "2024-09-13T10:18:49Z",
+		Message:   "line 0",
+		Level:     "info",
+		Labels:    map[string]string{"service_name": "nginx", "level": "info"},
+		Metadata:  map[string]string{"pod": "nginx-1"},
+	}, standIn.docs[0])
+}
+
+func TestElasticsearchLoggerRejectedDocuments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`)
+	}))
+	defer server.Close()
+
+	logger, err := NewElasticsearchLogger(ElasticsearchConfig{URL: server.URL})
+	require.NoError(t, err)
+	defer logger.Stop()
+
+	err = logger.send(make([]ElasticsearchDocument, 2))
+	assert.EqualError(t, err, "1 of 2 documents were rejected, last error mapper_parsing_exception: failed to parse")
+}
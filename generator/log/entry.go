@@ -0,0 +1,70 @@
+package log
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// Entry is a single log line with its stream labels and structured metadata.
+type Entry struct {
+	Timestamp time.Time         `json:"ts"`
+	Labels    map[string]string `json:"labels"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Line      string            `json:"line"`
+}
+
+// Line is a line logged at a level, for generators returning several lines at once like the
+// lines of a request spread over time.
+type Line struct {
+	Time  time.Time
+	Level model.LabelValue
+	Text  string
+}
+
+// NewEntry converts the Logger arguments to an Entry.
+func NewEntry(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) Entry {
+	e := Entry{
+		Timestamp: timestamp,
+		Labels:    make(map[string]string, len(labels)),
+		Line:      message,
+	}
+	for k, v := range labels {
+		e.Labels[string(k)] = string(v)
+	}
+	if len(metadata) > 0 {
+		e.Metadata = make(map[string]string, len(metadata))
+		for _, m := range metadata {
+			e.Metadata[m.Name] = m.Value
+		}
+	}
+	return e
+}
+
+// LabelSet returns the stream labels of the entry.
+func (e Entry) LabelSet() model.LabelSet {
+	ls := make(model.LabelSet, len(e.Labels))
+	for k, v := range e.Labels {
+		ls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return ls
+}
+
+// StructuredMetadata returns the metadata of the entry sorted by name.
+func (e Entry) StructuredMetadata() push.LabelsAdapter {
+	if len(e.Metadata) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(e.Metadata))
+	for name := range e.Metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metadata := make(push.LabelsAdapter, 0, len(names))
+	for _, name := range names {
+		metadata = append(metadata, push.LabelAdapter{Name: name, Value: e.Metadata[name]})
+	}
+	return metadata
+}
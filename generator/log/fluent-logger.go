@@ -0,0 +1,221 @@
+package log
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// FluentConfig configures a FluentLogger.
+type FluentConfig struct {
+	// Network is tcp or tls.
+	Network string
+	Address string
+	// TagPrefix is prepended to the service name to build the Fluent tag, e.g. explore-logs.nginx.
+	TagPrefix string
+	// RequireAck asks the receiver to acknowledge every chunk.
+	RequireAck bool
+	TLSConfig  *tls.Config
+	Timeout    time.Duration
+	Batch      BatchConfig
+}
+
+// FluentLogger implements the Logger interface and sends entries with the Fluent Forward protocol.
+// Batches are sent in Forward mode, one message per tag.
+type FluentLogger struct {
+	cfg     FluentConfig
+	batcher *batcher[fluentEntry]
+	// conns holds one connection per sender so batches can be sent concurrently.
+	conns chan *fluentConn
+}
+
+type fluentEntry struct {
+	tag    string
+	time   time.Time
+	record map[string]string
+}
+
+type fluentConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewFluentLogger creates a Fluent Forward logger, connections are established lazily.
+func NewFluentLogger(cfg FluentConfig) (*FluentLogger, error) {
+	switch cfg.Network {
+	case "":
+		cfg.Network = "tcp"
+	case "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported fluent network %q", cfg.Network)
+	}
+	if cfg.TagPrefix == "" {
+		cfg.TagPrefix = "explore-logs"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	cfg.Batch = cfg.Batch.withDefaults()
+
+	f := &FluentLogger{
+		cfg:   cfg,
+		conns: make(chan *fluentConn, cfg.Batch.Concurrency),
+	}
+	for i := 0; i < cfg.Batch.Concurrency; i++ {
+		f.conns <- &fluentConn{}
+	}
+	f.batcher = newBatcher("fluent", cfg.Batch, f.send)
+	return f, nil
+}
+
+// Handle implements the Logger interface
+func (f *FluentLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return f.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface, labels and metadata become record keys.
+func (f *FluentLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	record := make(map[string]string, len(labels)+len(metadata)+1)
+	for k, v := range labels {
+		record[string(k)] = string(v)
+	}
+	for _, m := range metadata {
+		record[m.Name] = m.Value
+	}
+	record["message"] = message
+
+	tag := f.cfg.TagPrefix
+	if svc, ok := labels["service_name"]; ok && svc != "" {
+		tag += "." + string(svc)
+	}
+	return f.batcher.add(fluentEntry{tag: tag, time: timestamp, record: record})
+}
+
+// Stop flushes pending entries and closes all connections.
+func (f *FluentLogger) Stop() {
+	f.batcher.stop()
+	close(f.conns)
+	for c := range f.conns {
+		c.close()
+	}
+}
+
+func (f *FluentLogger) send(entries []fluentEntry) error {
+	byTag := map[string][]fluentEntry{}
+	for _, e := range entries {
+		byTag[e.tag] = append(byTag[e.tag], e)
+	}
+
+	c := <-f.conns
+	defer func() { f.conns <- c }()
+
+	for tag, tagEntries := range byTag {
+		if err := f.sendForward(c, tag, tagEntries); err != nil {
+			c.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FluentLogger) sendForward(c *fluentConn, tag string, entries []fluentEntry) error {
+	if c.conn == nil {
+		if err := f.dial(c); err != nil {
+			return err
+		}
+	}
+
+	chunk := ""
+	if f.cfg.RequireAck {
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+	}
+
+	_ = c.conn.SetDeadline(time.Now().Add(f.cfg.Timeout))
+	if _, err := c.conn.Write(encodeFluentForward(tag, entries, chunk)); err != nil {
+		return fmt.Errorf("failed to write forward message: %w", err)
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	resp, err := decodeMsgpack(c.reader)
+	if err != nil {
+		return fmt.Errorf("failed to read ack: %w", err)
+	}
+	if m, ok := resp.(map[string]any); !ok || m["ack"] != chunk {
+		return fmt.Errorf("unexpected ack response %v", resp)
+	}
+	return nil
+}
+
+func (f *FluentLogger) dial(c *fluentConn) error {
+	dialer := &net.Dialer{Timeout: f.cfg.Timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if f.cfg.Network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", f.cfg.Address, f.cfg.TLSConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", f.cfg.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to fluent receiver: %w", err)
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (c *fluentConn) close() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+		c.reader = nil
+	}
+}
+
+// encodeFluentForward renders a Forward mode message: [tag, [[time, record], ...], option].
+// The chunk option is only set when an ack is requested.
+func encodeFluentForward(tag string, entries []fluentEntry, chunk string) []byte {
+	w := &msgpackWriter{}
+	w.writeArrayHeader(3)
+	w.writeString(tag)
+	w.writeArrayHeader(len(entries))
+	for _, e := range entries {
+		w.writeArrayHeader(2)
+		w.writeEventTime(e.time)
+
+		keys := make([]string, 0, len(e.record))
+		for k := range e.record {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.writeMapHeader(len(keys))
+		for _, k := range keys {
+			w.writeString(k)
+			w.writeString(e.record[k])
+		}
+	}
+
+	if chunk == "" {
+		w.writeMapHeader(1)
+	} else {
+		w.writeMapHeader(2)
+		w.writeString("chunk")
+		w.writeString(chunk)
+	}
+	w.writeString("size")
+	w.writeInt(int64(len(entries)))
+	return w.Bytes()
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type forwardMessage struct {
+	tag     string
+	entries []any
+	option  map[string]any
+}
+
+// listenForward is an in-process Forward receiver that decodes messages and acks chunks.
+func listenForward(t *testing.T) (string, chan forwardMessage) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	messages := make(chan forwardMessage, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					v, err := decodeMsgpack(r)
+					if err != nil {
+						return
+					}
+					arr := v.([]any)
+					msg := forwardMessage{tag: arr[0].(string), entries: arr[1].([]any), option: arr[2].(map[string]any)}
+					if chunk, ok := msg.option["chunk"].(string); ok {
+						w := &msgpackWriter{}
+						w.writeMapHeader(1)
+						w.writeString("ack")
+						w.writeString(chunk)
+						_, _ = conn.Write(w.Bytes())
+					}
+					messages <- msg
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), messages
+}
+
+func TestFluentLogger(t *testing.T) {
+	a := assert.New(t)
+
+	addr, messages := listenForward(t)
+	logger, err := NewFluentLogger(FluentConfig{Address: addr, RequireAck: true, Batch: BatchConfig{Wait: time.Hour}})
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 9, 13, 10, 18, 49, 123, time.UTC)
+	labels := model.LabelSet{"service_name": "payment", "level": ERROR}
+	a.NoError(logger.HandleWithMetadata(labels, ts, "card declined", push.LabelsAdapter{{Name: "traceID", Value: "abc"}}))
+	a.NoError(logger.Handle(labels, ts, "second"))
+	logger.Stop()
+
+	msg := <-messages
+	a.Equal("explore-logs.payment", msg.tag)
+	a.Equal(int64(2), msg.option["size"])
+	a.NotEmpty(msg.option["chunk"])
+	require.Len(t, msg.entries, 2)
+
+	entry := msg.entries[0].([]any)
+	eventTime := entry[0].(msgpackExt)
+	a.Equal(int8(0), eventTime.Type)
+	a.Equal(uint32(ts.Unix()), binary.BigEndian.Uint32(eventTime.Data[:4]))
+	a.Equal(uint32(123), binary.BigEndian.Uint32(eventTime.Data[4:]))
+	a.Equal(map[string]any{
+		"service_name": "payment",
+		"level":        "error",
+		"traceID":      "abc",
+		"message":      "card declined",
+	}, entry[1])
+}
+
+func TestFluentLoggerWithoutAck(t *testing.T) {
+	a := assert.New(t)
+
+	addr, messages := listenForward(t)
+	logger, err := NewFluentLogger(FluentConfig{Address: addr, TagPrefix: "gen"})
+	require.NoError(t, err)
+
+	a.NoError(logger.Handle(model.LabelSet{}, time.Now(), "no service"))
+	logger.Stop()
+
+	msg := <-messages
+	a.Equal("gen", msg.tag)
+	a.NotContains(msg.option, "chunk")
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	a := assert.New(t)
+
+	long := string(make([]byte, 70000))
+	w := &msgpackWriter{}
+	w.writeArrayHeader(5)
+	w.writeInt(-1)
+	w.writeInt(-1000)
+	w.writeInt(1 << 40)
+	w.writeString("short")
+	w.writeString(long)
+
+	v, err := decodeMsgpack(bufio.NewReader(&w.Buffer))
+	require.NoError(t, err)
+	a.Equal([]any{int64(-1), int64(-1000), int64(1 << 40), "short", long}, v)
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/prometheus/common/model"
+)
+
+// LevelWeights is the relative frequency of each level.
+type LevelWeights map[model.LabelValue]float64
+
+// DefaultLevelWeights logs 5% errors, 5% warnings and splits the rest between debug and info.
+var DefaultLevelWeights = LevelWeights{DEBUG: 45, INFO: 45, WARN: 5, ERROR: 5}
+
+// Pick returns a random level according to the weights.
+func (w LevelWeights) Pick() model.LabelValue {
+	levels := make([]model.LabelValue, 0, len(w))
+	var total float64
+	for level, weight := range w {
+		if weight > 0 {
+			levels = append(levels, level)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return INFO
+	}
+	// Map iteration order is random, sort so the same random number always gives the same level.
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	r := rand.Float64() * total
+	for _, level := range levels {
+		r -= w[level]
+		if r < 0 {
+			return level
+		}
+	}
+	return levels[len(levels)-1]
+}
+
+// LevelStatuses maps levels to the HTTP status codes logged with them, one is picked at random.
+type LevelStatuses map[model.LabelValue][]int
+
+// DefaultLevelStatuses maps warnings to 400 and errors to 500, other levels are successful requests.
+var DefaultLevelStatuses = LevelStatuses{
+	TRACE:    {200},
+	DEBUG:    {200},
+	INFO:     {200},
+	UNKNOWN:  {200},
+	WARN:     {400},
+	ERROR:    {500},
+	CRITICAL: {503},
+	FATAL:    {500},
+}
+
+// Pick returns a status code for the level, falling back to the default mapping and 200.
+func (s LevelStatuses) Pick(level model.LabelValue) int {
+	codes, ok := s[level]
+	if !ok || len(codes) == 0 {
+		codes = DefaultLevelStatuses[level]
+	}
+	if len(codes) == 0 {
+		return 200
+	}
+	return codes[rand.Intn(len(codes))]
+}
+
+// LevelProfile is the level mix of a service and the status codes of its access logs.
+type LevelProfile struct {
+	Weights  LevelWeights  `json:"weights,omitempty"`
+	Statuses LevelStatuses `json:"statuses,omitempty"`
+}
+
+// RandLevel returns a random level of the profile.
+func (p LevelProfile) RandLevel() model.LabelValue {
+	if len(p.Weights) == 0 {
+		return DefaultLevelWeights.Pick()
+	}
+	return p.Weights.Pick()
+}
+
+// Status returns a status code for the level.
+func (p LevelProfile) Status(level model.LabelValue) int {
+	return p.Statuses.Pick(level)
+}
+
+// LevelConfig holds per service level profiles, it is read from JSON files like:
+//
+//	{
+//	  "default": {"statuses": {"warn": [400, 404, 429], "error": [500, 502, 503]}},
+//	  "services": {
+//	    "nginx": {"weights": {"info": 60, "warn": 15, "error": 20, "critical": 5}},
+//	    "httpd": {"weights": {"trace": 20, "debug": 30, "info": 50}}
+//	  }
+//	}
+type LevelConfig struct {
+	Default  LevelProfile                      `json:"default"`
+	Services map[model.LabelValue]LevelProfile `json:"services,omitempty"`
+}
+
+// LoadLevelConfig reads a level configuration file.
+func LoadLevelConfig(path string) (LevelConfig, error) {
+	var c LevelConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := c.Default.validate(); err != nil {
+		return c, fmt.Errorf("%s: default: %w", path, err)
+	}
+	for service, p := range c.Services {
+		if err := p.validate(); err != nil {
+			return c, fmt.Errorf("%s: service %s: %w", path, service, err)
+		}
+	}
+	return c, nil
+}
+
+// validate rejects levels that are not in Levels and negative weights.
+func (p LevelProfile) validate() error {
+	for level, weight := range p.Weights {
+		if !slices.Contains(Levels, level) {
+			return fmt.Errorf("unknown level %q in weights", level)
+		}
+		if weight < 0 {
+			return fmt.Errorf("negative weight %v for level %q", weight, level)
+		}
+	}
+	for level := range p.Statuses {
+		if !slices.Contains(Levels, level) {
+			return fmt.Errorf("unknown level %q in statuses", level)
+		}
+	}
+	return nil
+}
+
+// Profile returns the profile of a service. Weights and statuses it doesn't set come from the default profile.
+func (c LevelConfig) Profile(service model.LabelValue) LevelProfile {
+	p := c.Services[service]
+	if len(p.Weights) == 0 {
+		p.Weights = c.Default.Weights
+	}
+	statuses := LevelStatuses{}
+	for level, codes := range c.Default.Statuses {
+		statuses[level] = codes
+	}
+	for level, codes := range p.Statuses {
+		statuses[level] = codes
+	}
+	p.Statuses = statuses
+	return p
+}
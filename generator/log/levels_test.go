@@ -0,0 +1,102 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelWeightsPick(t *testing.T) {
+	a := assert.New(t)
+	weights := LevelWeights{INFO: 70, ERROR: 20, CRITICAL: 10, DEBUG: 0}
+	counts := map[model.LabelValue]int{}
+	for i := 0; i < 10000; i++ {
+		counts[weights.Pick()]++
+	}
+	a.InDelta(7000, counts[INFO], 300)
+	a.InDelta(2000, counts[ERROR], 300)
+	a.InDelta(1000, counts[CRITICAL], 300)
+	a.Zero(counts[DEBUG])
+
+	a.Equal(INFO, LevelWeights{}.Pick())
+}
+
+func TestLevelStatuses(t *testing.T) {
+	a := assert.New(t)
+	statuses := LevelStatuses{ERROR: {502, 503}, WARN: {}}
+	for i := 0; i < 20; i++ {
+		a.Contains([]int{502, 503}, statuses.Pick(ERROR))
+	}
+	a.Equal(400, statuses.Pick(WARN), "empty lists fall back to the default mapping")
+	a.Equal(503, statuses.Pick(CRITICAL))
+	a.Equal(200, statuses.Pick("custom"))
+}
+
+func TestLevelConfig(t *testing.T) {
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "levels.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"default": {"statuses": {"warn": [404], "error": [500, 502]}},
+		"services": {
+			"nginx": {"weights": {"info": 60, "fatal": 40}, "statuses": {"error": [504]}}
+		}
+	}`), 0o644))
+	c, err := LoadLevelConfig(path)
+	require.NoError(t, err)
+
+	nginx := c.Profile("nginx")
+	a.Equal(LevelWeights{INFO: 60, FATAL: 40}, nginx.Weights)
+	a.Equal(LevelStatuses{WARN: {404}, ERROR: {504}}, nginx.Statuses)
+	a.Equal(404, nginx.Status(WARN))
+	a.Equal(504, nginx.Status(ERROR))
+
+	other := c.Profile("apache")
+	a.Empty(other.Weights)
+	a.Contains(Levels, other.RandLevel())
+	a.Contains([]int{500, 502}, other.Status(ERROR))
+	a.Equal(200, other.Status(INFO))
+
+	_, err = LoadLevelConfig(filepath.Join(t.TempDir(), "missing.json"))
+	a.Error(err)
+
+	for config, msg := range map[string]string{
+		`{"default": {"weights": {"warning": 10}}}`:                      `default: unknown level "warning" in weights`,
+		`{"services": {"nginx": {"statuses": {"notice": [200]}}}}`:       `service nginx: unknown level "notice" in statuses`,
+		`{"services": {"nginx": {"weights": {"info": 1, "error": -1}}}}`: `service nginx: negative weight -1 for level "error"`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(config), 0o644))
+		_, err = LoadLevelConfig(path)
+		a.EqualError(err, path+": "+msg)
+	}
+}
+
+func TestAppLoggerLevels(t *testing.T) {
+	a := assert.New(t)
+	var got []model.LabelSet
+	logger := LoggerFunc(func(labels model.LabelSet, _ time.Time, _ string, _ push.LabelsAdapter) error {
+		got = append(got, labels)
+		return nil
+	})
+	app := NewAppLogger(model.LabelSet{"service_name": "api"}, logger).WithLevels(LevelProfile{
+		Weights:  LevelWeights{TRACE: 1},
+		Statuses: LevelStatuses{TRACE: {204}},
+	})
+	a.Equal(TRACE, app.RandLevel())
+	a.Equal(204, app.Status(TRACE))
+
+	for _, level := range Levels {
+		app.Log(level, time.Now(), "msg")
+	}
+	app.Log("", time.Now(), "msg")
+	require.Len(t, got, len(Levels)+1)
+	for i, level := range Levels {
+		a.Equal(model.LabelSet{"service_name": "api", "level": level}, got[i])
+	}
+	a.Equal(model.LabelSet{"service_name": "api"}, got[len(Levels)])
+}
@@ -0,0 +1,250 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// A minimal msgpack encoder and decoder covering the types used by the Fluent Forward protocol.
+
+type msgpackWriter struct {
+	bytes.Buffer
+}
+
+func (w *msgpackWriter) writeInt(v int64) {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		w.WriteByte(byte(v))
+	case v < 0 && v >= -32:
+		w.WriteByte(byte(v))
+	case v >= 0:
+		w.WriteByte(0xcf)
+		_ = binary.Write(w, binary.BigEndian, uint64(v))
+	default:
+		w.WriteByte(0xd3)
+		_ = binary.Write(w, binary.BigEndian, v)
+	}
+}
+
+func (w *msgpackWriter) writeString(v string) {
+	n := len(v)
+	switch {
+	case n <= 31:
+		w.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		w.WriteByte(0xd9)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(0xda)
+		_ = binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(0xdb)
+		_ = binary.Write(w, binary.BigEndian, uint32(n))
+	}
+	w.WriteString(v)
+}
+
+func (w *msgpackWriter) writeArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		w.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(0xdc)
+		_ = binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(0xdd)
+		_ = binary.Write(w, binary.BigEndian, uint32(n))
+	}
+}
+
+func (w *msgpackWriter) writeMapHeader(n int) {
+	switch {
+	case n <= 15:
+		w.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(0xde)
+		_ = binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(0xdf)
+		_ = binary.Write(w, binary.BigEndian, uint32(n))
+	}
+}
+
+// writeEventTime writes the Fluent EventTime extension (type 0) with nanosecond precision.
+func (w *msgpackWriter) writeEventTime(t time.Time) {
+	w.WriteByte(0xd7)
+	w.WriteByte(0x00)
+	_ = binary.Write(w, binary.BigEndian, uint32(t.Unix()))
+	_ = binary.Write(w, binary.BigEndian, uint32(t.Nanosecond()))
+}
+
+// msgpackExt is a decoded extension value.
+type msgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// decodeMsgpack reads a single value. Maps are decoded to map[string]any, integers to int64.
+func decodeMsgpack(r io.Reader) (any, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return decodeMsgpackMap(r, int(c&0x0f))
+	case c&0xf0 == 0x90:
+		return decodeMsgpackArray(r, int(c&0x0f))
+	case c&0xe0 == 0xa0:
+		return readMsgpackString(r, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		n, err := readMsgpackUint(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xc5, 0xda:
+		n, err := readMsgpackUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xc6, 0xdb:
+		n, err := readMsgpackUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xca:
+		v, err := readMsgpackUint(r, 4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := readMsgpackUint(r, 8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := readMsgpackUint(r, 1<<(c-0xcc))
+		return int64(v), err
+	case 0xd0:
+		v, err := readMsgpackUint(r, 1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := readMsgpackUint(r, 2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := readMsgpackUint(r, 4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := readMsgpackUint(r, 8)
+		return int64(v), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readMsgpackExt(r, 1<<(c-0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackUint(r, 1<<(c-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackExt(r, int(n))
+	case 0xdc:
+		n, err := readMsgpackUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, int(n))
+	case 0xdd:
+		n, err := readMsgpackUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, int(n))
+	case 0xde:
+		n, err := readMsgpackUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, int(n))
+	case 0xdf:
+		n, err := readMsgpackUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, int(n))
+	}
+	return nil, fmt.Errorf("unsupported msgpack type 0x%x", c)
+}
+
+func readMsgpackUint(r io.Reader, size int) (uint64, error) {
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, b := range buf {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func readMsgpackString(r io.Reader, n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readMsgpackExt(r io.Reader, n int) (msgpackExt, error) {
+	buf := make([]byte, n+1)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return msgpackExt{}, err
+	}
+	return msgpackExt{Type: int8(buf[0]), Data: buf[1:]}, nil
+}
+
+func decodeMsgpackArray(r io.Reader, n int) ([]any, error) {
+	arr := make([]any, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func decodeMsgpackMap(r io.Reader, n int) (map[string]any, error) {
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		k, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, errors.New("msgpack map keys must be strings")
+		}
+		if m[key], err = decodeMsgpack(r); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -90,6 +90,8 @@ func extractTraceID(metadata push.LabelsAdapter) string {
 func getSlogLevel(labels model.LabelSet) slog.Level {
 	if level, ok := labels["level"]; ok {
 		switch level {
+		case "fatal", "critical":
+			return slog.LevelError + 4
 		case "error":
 			return slog.LevelError
 		case "warn":
@@ -98,6 +100,8 @@ func getSlogLevel(labels model.LabelSet) slog.Level {
 			return slog.LevelInfo
 		case "debug":
 			return slog.LevelDebug
+		case "trace":
+			return slog.LevelDebug - 4
 		}
 	}
 	return slog.LevelInfo
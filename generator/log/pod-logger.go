@@ -0,0 +1,280 @@
+package log
+
+import (
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// PodLogFormat is the on-disk format written by the container runtime.
+type PodLogFormat string
+
+const (
+	CRIFormat    PodLogFormat = "cri"
+	DockerFormat PodLogFormat = "docker"
+)
+
+// Kubelet names rotated files after the rotation time.
+const rotationTimestamp = "20060102-150405"
+
+// PodLoggerConfig configures a PodLogger.
+type PodLoggerConfig struct {
+	// Root is the pod log directory, /var/log/pods on a node.
+	Root   string
+	Format PodLogFormat
+	// MaxSize is the size in bytes at which 0.log is rotated, like containerLogMaxSize.
+	MaxSize int64
+	// MaxFiles is the number of files kept per container including 0.log, like containerLogMaxFiles.
+	MaxFiles int
+}
+
+// PodLogger implements the Logger interface and writes logs the way the kubelet lays them out:
+// <root>/<namespace>_<pod>_<uid>/<container>/0.log. The namespace and service_name labels and the
+// pod metadata from ForAllClusters or the pod label of the pod lifecycles are used for the path,
+// the service name is the container name. Pods of the same name in different clusters have
+// different UIDs, so their directories are kept apart.
+type PodLogger struct {
+	cfg   PodLoggerConfig
+	mtx   sync.Mutex
+	files map[string]*podLogFile
+}
+
+type podLogFile struct {
+	dir  string
+	file *os.File
+	size int64
+}
+
+// NewPodLogger creates a pod log writer, directories are created on first write.
+func NewPodLogger(cfg PodLoggerConfig) (*PodLogger, error) {
+	if cfg.Root == "" {
+		cfg.Root = "/var/log/pods"
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = CRIFormat
+	case CRIFormat, DockerFormat:
+	default:
+		return nil, fmt.Errorf("unsupported pod log format %q", cfg.Format)
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 * 1024 * 1024
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = 5
+	}
+	return &PodLogger{cfg: cfg, files: map[string]*podLogFile{}}, nil
+}
+
+// Handle implements the Logger interface
+func (p *PodLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return p.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface, warn and error lines are written to stderr.
+func (p *PodLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	return p.write(labels, timestamp, message, metadata, "")
+}
+
+// ForPod returns a logger writing the logs of pod, for streams whose metadata doesn't tell their
+// pod like the nginx ones.
+func (p *PodLogger) ForPod(pod string) Logger {
+	return LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+		return p.write(labels, timestamp, message, metadata, pod)
+	})
+}
+
+// write writes a line to the log file of pod, taken from the metadata or the labels when empty.
+func (p *PodLogger) write(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter, pod string) error {
+	namespace := string(labels["namespace"])
+	if namespace == "" {
+		namespace = "default"
+	}
+	container := string(labels["service_name"])
+	if container == "" {
+		container = "app"
+	}
+	if pod == "" {
+		pod = string(labels["pod"])
+	}
+	for _, m := range metadata {
+		if pod == "" && m.Name == "pod" && m.Value != "" {
+			pod = m.Value
+		}
+	}
+	if pod == "" {
+		pod = container + "-0"
+	}
+	stream := "stdout"
+	if level := labels["level"]; level == ERROR || level == WARN || level == CRITICAL || level == FATAL {
+		stream = "stderr"
+	}
+	line := p.format(timestamp, stream, message)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	dir := filepath.Join(p.cfg.Root, namespace+"_"+pod+"_"+PodUID(string(labels["cluster"]), namespace, pod), container)
+	f, ok := p.files[dir]
+	if !ok {
+		var err error
+		if f, err = openPodLogFile(dir); err != nil {
+			return err
+		}
+		p.files[dir] = f
+	}
+	if f.size > 0 && f.size+int64(len(line)) > p.cfg.MaxSize {
+		if err := p.rotate(f, timestamp); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.WriteString(line)
+	f.size += int64(n)
+	return err
+}
+
+// Stop closes all open log files.
+func (p *PodLogger) Stop() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for dir, f := range p.files {
+		_ = f.file.Close()
+		delete(p.files, dir)
+	}
+}
+
+func (p *PodLogger) format(timestamp time.Time, stream, message string) string {
+	ts := timestamp.UTC().Format(time.RFC3339Nano)
+	if p.cfg.Format == DockerFormat {
+		line, _ := json.Marshal(struct {
+			Log    string `json:"log"`
+			Stream string `json:"stream"`
+			Time   string `json:"time"`
+		}{message + "\n", stream, ts})
+		return string(line) + "\n"
+	}
+	// CRI splits multiline messages into one full entry per line.
+	var sb strings.Builder
+	for _, l := range strings.Split(message, "\n") {
+		sb.WriteString(ts + " " + stream + " F " + l + "\n")
+	}
+	return sb.String()
+}
+
+func openPodLogFile(dir string) (*podLogFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, "0.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &podLogFile{dir: dir, file: file, size: info.Size()}, nil
+}
+
+// rotate renames 0.log to 0.log.<timestamp>, compresses older rotated files and removes
+// files beyond MaxFiles. Like the kubelet, the most recent rotated file stays uncompressed.
+func (p *PodLogger) rotate(f *podLogFile, now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	current := filepath.Join(f.dir, "0.log")
+	rotated := current + "." + now.UTC().Format(rotationTimestamp)
+	for i := 1; fileExists(rotated) || fileExists(rotated+".gz"); i++ {
+		rotated = fmt.Sprintf("%s.%s-%d", current, now.UTC().Format(rotationTimestamp), i)
+	}
+	if err := os.Rename(current, rotated); err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(current + ".*")
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+	for _, m := range matches {
+		if m != rotated && !strings.HasSuffix(m, ".gz") {
+			if err := gzipFile(m); err != nil {
+				return err
+			}
+		}
+	}
+
+	if matches, err = filepath.Glob(current + ".*"); err != nil {
+		return err
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return strings.TrimSuffix(matches[i], ".gz") < strings.TrimSuffix(matches[j], ".gz")
+	})
+	for len(matches) > p.cfg.MaxFiles-1 {
+		if err := os.Remove(matches[0]); err != nil {
+			return err
+		}
+		matches = matches[1:]
+	}
+
+	file, err := os.OpenFile(current, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	f.file = file
+	f.size = 0
+	return nil
+}
+
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// PodUID returns a stable UUID shaped identifier for a pod of a cluster, so restarts of the
+// generator keep writing to the same directory. The cluster can be empty.
+func PodUID(cluster, namespace, pod string) string {
+	key := namespace + "/" + pod
+	if cluster != "" {
+		key = cluster + "/" + key
+	}
+	h := sha1.Sum([]byte(key))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
@@ -0,0 +1,110 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var podTestLabels = model.LabelSet{"namespace": "tempo-prod", "service_name": "tempo-ingester", "level": ERROR}
+var podTestMetadata = push.LabelsAdapter{{Name: "pod", Value: "tempo-ingester-hc-0abc"}}
+
+func TestPodLoggerCRI(t *testing.T) {
+	a := assert.New(t)
+
+	root := t.TempDir()
+	logger, err := NewPodLogger(PodLoggerConfig{Root: root})
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 9, 13, 10, 18, 49, 123456789, time.UTC)
+	a.NoError(logger.HandleWithMetadata(podTestLabels, ts, "panic: boom\ngoroutine 1", podTestMetadata))
+	a.NoError(logger.HandleWithMetadata(model.LabelSet{"namespace": "tempo-prod", "service_name": "tempo-ingester", "level": INFO}, ts, "ok", podTestMetadata))
+	logger.Stop()
+
+	path := filepath.Join(root, "tempo-prod_tempo-ingester-hc-0abc_"+PodUID("", "tempo-prod", "tempo-ingester-hc-0abc"), "tempo-ingester", "0.log")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	a.Equal(`2024-09-13T10:18:49.123456789Z stderr F panic: boom
+2024-09-13T10:18:49.123456789Z stderr F goroutine 1
+2024-09-13T10:18:49.123456789Z stdout F ok
+`, string(content))
+}
+
+func TestPodLoggerDocker(t *testing.T) {
+	root := t.TempDir()
+	logger, err := NewPodLogger(PodLoggerConfig{Root: root, Format: DockerFormat})
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 9, 13, 10, 18, 49, 0, time.UTC)
+	assert.NoError(t, logger.Handle(model.LabelSet{"service_name": "nginx"}, ts, `GET "/" 200`))
+	logger.Stop()
+
+	content, err := os.ReadFile(filepath.Join(root, "default_nginx-0_"+PodUID("", "default", "nginx-0"), "nginx", "0.log"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"log":"GET \"/\" 200\n","stream":"stdout","time":"2024-09-13T10:18:49Z"}`+"\n", string(content))
+}
+
+func TestPodLoggerForPod(t *testing.T) {
+	a := assert.New(t)
+
+	root := t.TempDir()
+	logger, err := NewPodLogger(PodLoggerConfig{Root: root})
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 9, 13, 10, 18, 49, 0, time.UTC)
+	for _, cluster := range []model.LabelValue{"us-east-1", "eu-west-1"} {
+		labels := model.LabelSet{"cluster": cluster, "namespace": "gateway", "service_name": "nginx"}
+		a.NoError(logger.ForPod("nginx-7d4b9c-x2k4p").Handle(labels, ts, "GET / 200"))
+		a.NoError(logger.ForPod("nginx-7d4b9c-q8z1m").Handle(labels, ts, "GET / 200"))
+	}
+	logger.Stop()
+
+	for _, cluster := range []string{"us-east-1", "eu-west-1"} {
+		for _, pod := range []string{"nginx-7d4b9c-x2k4p", "nginx-7d4b9c-q8z1m"} {
+			a.FileExists(filepath.Join(root, "gateway_"+pod+"_"+PodUID(cluster, "gateway", pod), "nginx", "0.log"))
+		}
+	}
+}
+
+func TestPodLoggerRotation(t *testing.T) {
+	a := assert.New(t)
+
+	root := t.TempDir()
+	logger, err := NewPodLogger(PodLoggerConfig{Root: root, MaxSize: 100, MaxFiles: 3})
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		// Each line is bigger than half of MaxSize so every write after the first rotates.
+		a.NoError(logger.HandleWithMetadata(podTestLabels, ts.Add(time.Duration(i)*time.Minute), strings.Repeat("x", 40), podTestMetadata))
+	}
+	logger.Stop()
+
+	dir := filepath.Join(root, "tempo-prod_tempo-ingester-hc-0abc_"+PodUID("", "tempo-prod", "tempo-ingester-hc-0abc"), "tempo-ingester")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	// Rotated files are named after the rotation time, not the time of their last line.
+	a.Equal([]string{"0.log", "0.log.20240913-100300.gz", "0.log.20240913-100400"}, names)
+
+	f, err := os.Open(filepath.Join(dir, "0.log.20240913-100300.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	a.Equal("2024-09-13T10:02:00Z stderr F "+strings.Repeat("x", 40)+"\n", string(content))
+}
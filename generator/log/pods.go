@@ -0,0 +1,116 @@
+package log
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// PodPhase is the state of a simulated pod.
+type PodPhase string
+
+const (
+	PodRunning          PodPhase = "Running"
+	PodCrashLoopBackOff PodPhase = "CrashLoopBackOff"
+)
+
+// Pod is a pod of a simulated service, generators of cluster logs like Kubernetes events refer
+// to them.
+type Pod struct {
+	Cluster, Namespace, Service, Name string
+	Node                              string
+	Phase                             PodPhase
+}
+
+// UID returns the UID of the pod, the same as in the paths of the PodLogger.
+func (p Pod) UID() string {
+	return PodUID(p.Cluster, p.Namespace, p.Name)
+}
+
+// PodRegistry tracks the running pods. It is safe for concurrent use.
+type PodRegistry struct {
+	mtx  sync.RWMutex
+	pods map[string]map[string]Pod
+}
+
+// Pods are the pods of the running services, by cluster. ForAllClusters and the lifecycle
+// deployments register theirs.
+var Pods = NewPodRegistry()
+
+func NewPodRegistry() *PodRegistry {
+	return &PodRegistry{pods: map[string]map[string]Pod{}}
+}
+
+// Add adds or updates a pod. Pods without a node are scheduled on a random node of their cluster.
+func (r *PodRegistry) Add(p Pod) Pod {
+	if p.Node == "" {
+		nodes := Nodes(p.Cluster)
+		p.Node = nodes[rand.Intn(len(nodes))]
+	}
+	if p.Phase == "" {
+		p.Phase = PodRunning
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.pods[p.Cluster] == nil {
+		r.pods[p.Cluster] = map[string]Pod{}
+	}
+	r.pods[p.Cluster][p.Name] = p
+	return p
+}
+
+// SetPhase changes the phase of a pod if it is still registered.
+func (r *PodRegistry) SetPhase(cluster, name string, phase PodPhase) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if p, ok := r.pods[cluster][name]; ok {
+		p.Phase = phase
+		r.pods[cluster][name] = p
+	}
+}
+
+// Remove removes a deleted pod.
+func (r *PodRegistry) Remove(cluster, name string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.pods[cluster], name)
+}
+
+// List returns the pods of a cluster sorted by name.
+func (r *PodRegistry) List(cluster string) []Pod {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	out := make([]Pod, 0, len(r.pods[cluster]))
+	for _, p := range r.pods[cluster] {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Rand returns a random pod of a cluster, false when it has none.
+func (r *PodRegistry) Rand(cluster string) (Pod, bool) {
+	pods := r.List(cluster)
+	if len(pods) == 0 {
+		return Pod{}, false
+	}
+	return pods[rand.Intn(len(pods))], true
+}
+
+// nodeCount is the number of nodes of every cluster.
+const nodeCount = 6
+
+// Nodes returns the names of the nodes of a cluster, they are the same on every call.
+func Nodes(cluster string) []string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(cluster))
+	rnd := rand.New(rand.NewSource(int64(h.Sum64())))
+	subnet := rnd.Intn(256)
+	nodes := make([]string, nodeCount)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("ip-10-%d-%d-%d.%s.compute.internal", subnet, rnd.Intn(256), 1+rnd.Intn(254), cluster)
+	}
+	return nodes
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPodRegistry(t *testing.T) {
+	a := assert.New(t)
+	r := NewPodRegistry()
+	_, ok := r.Rand("eu-west-1")
+	a.False(ok)
+
+	p := r.Add(Pod{Cluster: "eu-west-1", Namespace: "shop", Service: "cart", Name: "cart-b"})
+	a.Contains(Nodes("eu-west-1"), p.Node)
+	a.Equal(PodRunning, p.Phase)
+	r.Add(Pod{Cluster: "eu-west-1", Namespace: "shop", Service: "cart", Name: "cart-a", Node: "node-1"})
+	r.Add(Pod{Cluster: "us-east-1", Namespace: "shop", Service: "cart", Name: "cart-c"})
+
+	pods := r.List("eu-west-1")
+	a.Len(pods, 2)
+	a.Equal("cart-a", pods[0].Name)
+	a.Equal("node-1", pods[0].Node)
+
+	r.SetPhase("eu-west-1", "cart-b", PodCrashLoopBackOff)
+	a.Equal(PodCrashLoopBackOff, r.List("eu-west-1")[1].Phase)
+	r.SetPhase("eu-west-1", "gone", PodCrashLoopBackOff)
+	a.Len(r.List("eu-west-1"), 2)
+
+	r.Remove("eu-west-1", "cart-a")
+	got, ok := r.Rand("eu-west-1")
+	a.True(ok)
+	a.Equal("cart-b", got.Name)
+}
+
+func TestNodes(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(Nodes("eu-west-1"), Nodes("eu-west-1"))
+	a.NotEqual(Nodes("eu-west-1"), Nodes("us-east-1"))
+	a.Regexp(`^ip-10-\d+-\d+-\d+\.eu-west-1\.compute\.internal$`, Nodes("eu-west-1")[0])
+}
+
+func TestForAllClustersStatefulServices(t *testing.T) {
+	a := assert.New(t)
+	pods := map[string][]string{}
+	ForAllClusters("kafka", "kafka", func(labels model.LabelSet, metadata push.LabelsAdapter) {
+		for _, m := range metadata {
+			if m.Name == "pod" {
+				pods[string(labels["cluster"])] = append(pods[string(labels["cluster"])], m.Value)
+			}
+		}
+	})
+	a.Len(pods, len(Clusters))
+	for _, names := range pods {
+		a.Equal([]string{"kafka-0", "kafka-1", "kafka-2"}, names)
+	}
+}
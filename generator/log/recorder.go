@@ -0,0 +1,183 @@
+package log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// A recording is gzipped JSON lines. Stream label sets are written once and referenced by id:
+//
+//	{"s":1,"labels":{"service_name":"nginx"}}
+//	{"s":1,"t":1726222729000000000,"l":"GET /api 200","m":{"pod":"nginx-1"}}
+type recordLine struct {
+	Stream   int                `json:"s"`
+	Labels   *map[string]string `json:"labels,omitempty"`
+	Time     int64              `json:"t,omitempty"`
+	Line     string             `json:"l,omitempty"`
+	Metadata map[string]string  `json:"m,omitempty"`
+}
+
+// Recorder writes every entry it sees to a recording file.
+type Recorder struct {
+	mtx     sync.Mutex
+	file    *os.File
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	streams map[string]int
+}
+
+// NewRecorder creates a recording file at path, an existing file is truncated.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &Recorder{
+		file:    file,
+		gz:      gz,
+		enc:     json.NewEncoder(gz),
+		streams: map[string]int{},
+	}, nil
+}
+
+// Record appends a single entry to the recording.
+func (r *Recorder) Record(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	entry := NewEntry(labels, timestamp, message, metadata)
+	key := labels.String()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.enc == nil {
+		return errLoggerStopped
+	}
+	id, ok := r.streams[key]
+	if !ok {
+		id = len(r.streams) + 1
+		r.streams[key] = id
+		if err := r.enc.Encode(recordLine{Stream: id, Labels: &entry.Labels}); err != nil {
+			return err
+		}
+	}
+	return r.enc.Encode(recordLine{Stream: id, Time: timestamp.UnixNano(), Line: message, Metadata: entry.Metadata})
+}
+
+// Wrap returns a Logger that records every entry before passing it to next.
+func (r *Recorder) Wrap(next Logger) Logger {
+	return LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+		if err := r.Record(labels, timestamp, message, metadata); err != nil {
+			return err
+		}
+		return next.HandleWithMetadata(labels, timestamp, message, metadata)
+	})
+}
+
+// Stop flushes and closes the recording.
+func (r *Recorder) Stop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.enc == nil {
+		return
+	}
+	_ = r.gz.Close()
+	_ = r.file.Close()
+	r.enc = nil
+}
+
+// ReadRecording calls fn for every entry of a recording in the order they were recorded.
+func ReadRecording(path string, fn func(Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return fmt.Errorf("%s is not a recording: %w", path, err)
+	}
+	defer gz.Close()
+
+	streams := map[int]map[string]string{}
+	dec := json.NewDecoder(gz)
+	for {
+		var line recordLine
+		if err := dec.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode recording: %w", err)
+		}
+		if line.Labels != nil {
+			streams[line.Stream] = *line.Labels
+			continue
+		}
+		labels, ok := streams[line.Stream]
+		if !ok {
+			return fmt.Errorf("recording references unknown stream %d", line.Stream)
+		}
+		if err := fn(Entry{
+			Timestamp: time.Unix(0, line.Time),
+			Labels:    labels,
+			Metadata:  line.Metadata,
+			Line:      line.Line,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// ReplayOptions controls how a recording is pushed back.
+type ReplayOptions struct {
+	// ShiftToNow moves all timestamps so the first entry happens when the replay starts.
+	// Combined with Speed the timestamps follow the scaled pacing, so entries are always sent "now".
+	ShiftToNow bool
+	// Speed scales the original pacing, 2 replays twice as fast. Zero or less sends entries without waiting.
+	Speed float64
+}
+
+// Replay pushes a recording through logger and returns the number of entries sent.
+func Replay(ctx context.Context, path string, logger Logger, opts ReplayOptions) (int, error) {
+	var (
+		count  int
+		first  time.Time
+		start  = time.Now()
+		offset time.Duration
+	)
+	err := ReadRecording(path, func(e Entry) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if count == 0 {
+			first = e.Timestamp
+			if opts.ShiftToNow {
+				offset = start.Sub(first)
+			}
+		}
+		ts := e.Timestamp.Add(offset)
+		if opts.Speed > 0 {
+			target := start.Add(time.Duration(float64(e.Timestamp.Sub(first)) / opts.Speed))
+			if opts.ShiftToNow {
+				ts = target
+			}
+			if wait := time.Until(target); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		count++
+		return logger.HandleWithMetadata(e.LabelSet(), ts, e.Line, e.StructuredMetadata())
+	})
+	return count, err
+}
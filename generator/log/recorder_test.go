@@ -0,0 +1,101 @@
+package log
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type collected struct {
+	labels   model.LabelSet
+	ts       time.Time
+	line     string
+	metadata push.LabelsAdapter
+}
+
+func collect(entries *[]collected) Logger {
+	return LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+		*entries = append(*entries, collected{labels, timestamp, message, metadata})
+		return nil
+	})
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	a := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "session.rec")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+
+	var forwarded []collected
+	logger := recorder.Wrap(collect(&forwarded))
+
+	nginx := model.LabelSet{"service_name": "nginx", "level": INFO}
+	tempo := model.LabelSet{"service_name": "tempo", "level": ERROR}
+	first := time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC)
+	metadata := push.LabelsAdapter{{Name: "pod", Value: "nginx-1"}, {Name: "traceID", Value: "abc"}}
+	a.NoError(logger.HandleWithMetadata(nginx, first, "GET / 200", metadata))
+	a.NoError(logger.Handle(tempo, first.Add(time.Second), "flush failed"))
+	a.NoError(logger.Handle(nginx, first.Add(2*time.Second), ""))
+	recorder.Stop()
+	a.Error(logger.Handle(nginx, first, "after stop"))
+	a.Len(forwarded, 3)
+
+	var replayed []collected
+	count, err := Replay(context.Background(), path, collect(&replayed), ReplayOptions{})
+	require.NoError(t, err)
+	a.Equal(3, count)
+	for i := range forwarded {
+		a.Equal(forwarded[i].labels, replayed[i].labels)
+		a.True(forwarded[i].ts.Equal(replayed[i].ts))
+		a.Equal(forwarded[i].line, replayed[i].line)
+		a.Equal(forwarded[i].metadata, replayed[i].metadata)
+	}
+}
+
+func TestReplayShiftAndSpeed(t *testing.T) {
+	a := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "session.rec")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+	first := time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC)
+	labels := model.LabelSet{"service_name": "nginx"}
+	a.NoError(recorder.Record(labels, first, "a", nil))
+	a.NoError(recorder.Record(labels, first.Add(time.Second), "b", nil))
+	recorder.Stop()
+
+	var replayed []collected
+	start := time.Now()
+	_, err = Replay(context.Background(), path, collect(&replayed), ReplayOptions{ShiftToNow: true, Speed: 20})
+	require.NoError(t, err)
+	require.Len(t, replayed, 2)
+
+	// One second of recording at 20x takes 50ms.
+	a.GreaterOrEqual(time.Since(start), 50*time.Millisecond)
+	a.WithinDuration(start, replayed[0].ts, 20*time.Millisecond)
+	a.WithinDuration(start.Add(50*time.Millisecond), replayed[1].ts, 20*time.Millisecond)
+}
+
+func TestReplayCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.rec")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+	first := time.Now()
+	require.NoError(t, recorder.Record(model.LabelSet{}, first, "a", nil))
+	require.NoError(t, recorder.Record(model.LabelSet{}, first.Add(time.Hour), "b", nil))
+	recorder.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var replayed []collected
+	count, err := Replay(ctx, path, collect(&replayed), ReplayOptions{Speed: 1})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,197 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// SplunkEndpoint selects the HTTP Event Collector endpoint.
+type SplunkEndpoint string
+
+const (
+	SplunkEvent SplunkEndpoint = "event"
+	SplunkRaw   SplunkEndpoint = "raw"
+)
+
+// SplunkConfig configures a SplunkLogger.
+type SplunkConfig struct {
+	// URL is the base URL of the HTTP Event Collector, e.g. https://localhost:8088.
+	URL        string
+	Token      string
+	Endpoint   SplunkEndpoint
+	Index      string
+	SourceType string
+	Batch      BatchConfig
+	Client     *http.Client
+}
+
+// SplunkLogger implements the Logger interface and sends entries to a Splunk HTTP Event Collector.
+// The event endpoint keeps labels and metadata as indexed fields, the raw endpoint only keeps
+// host, source and sourcetype.
+type SplunkLogger struct {
+	cfg     SplunkConfig
+	channel string
+	batcher *batcher[SplunkHECEvent]
+}
+
+// SplunkHECEvent is the payload of the event endpoint.
+type SplunkHECEvent struct {
+	Time       float64           `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      string            `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+type splunkResponse struct {
+	Text string `json:"text"`
+	Code int    `json:"code"`
+}
+
+// NewSplunkLogger creates a new Splunk HEC logger.
+func NewSplunkLogger(cfg SplunkConfig) (*SplunkLogger, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("splunk url is required")
+	}
+	switch cfg.Endpoint {
+	case "":
+		cfg.Endpoint = SplunkEvent
+	case SplunkEvent, SplunkRaw:
+	default:
+		return nil, fmt.Errorf("unsupported splunk endpoint %q", cfg.Endpoint)
+	}
+	if cfg.SourceType == "" {
+		cfg.SourceType = "explore-logs"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	s := &SplunkLogger{
+		cfg: cfg,
+		// The raw endpoint requires a channel, HEC uses it to track acknowledgements.
+		channel: gofakeit.UUID(),
+	}
+	s.batcher = newBatcher("splunk", cfg.Batch, s.send)
+	return s, nil
+}
+
+// Handle implements the Logger interface
+func (s *SplunkLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return s.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface, the entry is sent with the next batch.
+// The host is the pod or cluster and the source is the service name.
+func (s *SplunkLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	event := SplunkHECEvent{
+		Time:       float64(timestamp.UnixNano()) / float64(time.Second),
+		Host:       string(labels["cluster"]),
+		Source:     string(labels["service_name"]),
+		SourceType: s.cfg.SourceType,
+		Index:      s.cfg.Index,
+		Event:      message,
+		Fields:     make(map[string]string, len(labels)+len(metadata)),
+	}
+	for k, v := range labels {
+		event.Fields[string(k)] = string(v)
+	}
+	for _, m := range metadata {
+		event.Fields[m.Name] = m.Value
+		if m.Name == "pod" {
+			event.Host = m.Value
+		}
+	}
+	return s.batcher.add(event)
+}
+
+// Stop flushes pending events and waits for in-flight requests.
+func (s *SplunkLogger) Stop() {
+	s.batcher.stop()
+}
+
+func (s *SplunkLogger) send(events []SplunkHECEvent) error {
+	if s.cfg.Endpoint == SplunkEvent {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, e := range events {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return s.post("/services/collector/event", nil, buf.Bytes())
+	}
+
+	// Raw requests carry host and source as query parameters, so each request is a single stream.
+	type stream struct{ host, source string }
+	streams := map[stream][]string{}
+	var order []stream
+	for _, e := range events {
+		key := stream{e.Host, e.Source}
+		if _, ok := streams[key]; !ok {
+			order = append(order, key)
+		}
+		streams[key] = append(streams[key], e.Event)
+	}
+	for _, key := range order {
+		params := url.Values{}
+		params.Set("channel", s.channel)
+		params.Set("sourcetype", s.cfg.SourceType)
+		if key.host != "" {
+			params.Set("host", key.host)
+		}
+		if key.source != "" {
+			params.Set("source", key.source)
+		}
+		if s.cfg.Index != "" {
+			params.Set("index", s.cfg.Index)
+		}
+		if err := s.post("/services/collector/raw", params, []byte(strings.Join(streams[key], "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SplunkLogger) post(path string, params url.Values, body []byte) error {
+	u := strings.TrimSuffix(s.cfg.URL, "/") + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.cfg.Token)
+	req.Header.Set("X-Splunk-Request-Channel", s.channel)
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result splunkResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, respBody)
+	}
+	if resp.StatusCode/100 != 2 || result.Code != 0 {
+		return fmt.Errorf("request failed with status %d: %s (code %d)", resp.StatusCode, result.Text, result.Code)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hecReceiver is an in-process HTTP Event Collector that records events and raw lines.
+type hecReceiver struct {
+	mtx    sync.Mutex
+	events []SplunkHECEvent
+	raw    []string
+	params []string
+	errs   []error
+}
+
+func (h *hecReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if r.Header.Get("Authorization") != "Splunk secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = fmt.Fprint(w, `{"text":"Invalid authorization","code":3}`)
+		return
+	}
+	switch r.URL.Path {
+	case "/services/collector/event":
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var e SplunkHECEvent
+			if err := dec.Decode(&e); err != nil {
+				h.errs = append(h.errs, err)
+				break
+			}
+			h.events = append(h.events, e)
+		}
+	case "/services/collector/raw":
+		if r.URL.Query().Get("channel") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"text":"Data channel is missing","code":10}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		h.raw = append(h.raw, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")...)
+		h.params = append(h.params, r.URL.Query().Get("host")+"/"+r.URL.Query().Get("source"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = fmt.Fprint(w, `{"text":"Success","code":0}`)
+}
+
+func TestSplunkLoggerEvent(t *testing.T) {
+	a := assert.New(t)
+
+	receiver := &hecReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	logger, err := NewSplunkLogger(SplunkConfig{URL: server.URL, Token: "secret", Index: "main", Batch: BatchConfig{Wait: time.Hour}})
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 9, 13, 10, 18, 49, 500000000, time.UTC)
+	labels := model.LabelSet{"cluster": "us-east-1", "service_name": "cart", "level": WARN}
+	a.NoError(logger.HandleWithMetadata(labels, ts, "slow checkout", push.LabelsAdapter{{Name: "pod", Value: "cart-1"}}))
+	a.NoError(logger.Handle(labels, ts, "second"))
+	logger.Stop()
+
+	a.Empty(receiver.errs)
+	require.Len(t, receiver.events, 2)
+	a.Equal(SplunkHECEvent{
+		Time:       1726222729.5,
+		Host:       "cart-1",
+		Source:     "cart",
+		SourceType: "explore-logs",
+		Index:      "main",
+		Event:      "slow checkout",
+		Fields:     map[string]string{"cluster": "us-east-1", "service_name": "cart", "level": "warn", "pod": "cart-1"},
+	}, receiver.events[0])
+	a.Equal("us-east-1", receiver.events[1].Host)
+}
+
+func TestSplunkLoggerRaw(t *testing.T) {
+	a := assert.New(t)
+
+	receiver := &hecReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	logger, err := NewSplunkLogger(SplunkConfig{URL: server.URL, Token: "secret", Endpoint: SplunkRaw, Batch: BatchConfig{Wait: time.Hour}})
+	require.NoError(t, err)
+
+	ts := time.Now()
+	a.NoError(logger.Handle(model.LabelSet{"cluster": "a", "service_name": "cart"}, ts, "one"))
+	a.NoError(logger.Handle(model.LabelSet{"cluster": "b", "service_name": "cart"}, ts, "two"))
+	a.NoError(logger.Handle(model.LabelSet{"cluster": "a", "service_name": "cart"}, ts, "three"))
+	logger.Stop()
+
+	a.Equal([]string{"one", "three", "two"}, receiver.raw)
+	a.Equal([]string{"a/cart", "b/cart"}, receiver.params)
+}
+
+func TestSplunkLoggerError(t *testing.T) {
+	server := httptest.NewServer(&hecReceiver{})
+	defer server.Close()
+
+	logger, err := NewSplunkLogger(SplunkConfig{URL: server.URL, Token: "wrong"})
+	require.NoError(t, err)
+	defer logger.Stop()
+
+	err = logger.send([]SplunkHECEvent{{Event: "denied"}})
+	assert.EqualError(t, err, "request failed with status 401: Invalid authorization (code 3)")
+}
@@ -0,0 +1,255 @@
+package log
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// SyslogFraming selects how messages are delimited on stream transports (RFC6587).
+type SyslogFraming string
+
+const (
+	OctetCounting  SyslogFraming = "octet-counting"
+	NonTransparent SyslogFraming = "non-transparent"
+)
+
+// syslogSDID is the structured data ID used for labels and metadata. 32473 is the
+// private enterprise number reserved for documentation (RFC5612).
+const syslogSDID = "loki@32473"
+
+const rfc5424Timestamp = "2006-01-02T15:04:05.000000Z07:00"
+
+// SyslogConfig configures a SyslogLogger.
+type SyslogConfig struct {
+	// Network is one of udp, tcp or tls.
+	Network string
+	Address string
+	// Framing is only used for tcp and tls, UDP sends one message per datagram. Non-transparent
+	// framing ends messages at newlines, so newlines within messages are escaped as #012 like
+	// rsyslog does for control characters.
+	Framing SyslogFraming
+	// Facility is the syslog facility code, defaults to 1 (user-level messages) when nil.
+	Facility  *int
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+}
+
+// SyslogLogger implements the Logger interface and ships RFC5424 messages to a syslog receiver.
+type SyslogLogger struct {
+	cfg      SyslogConfig
+	hostname string
+
+	mtx  sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogLogger creates a syslog logger and dials the configured address.
+func NewSyslogLogger(cfg SyslogConfig) (*SyslogLogger, error) {
+	switch cfg.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", cfg.Network)
+	}
+	switch cfg.Framing {
+	case "":
+		cfg.Framing = OctetCounting
+	case OctetCounting, NonTransparent:
+	default:
+		return nil, fmt.Errorf("unsupported syslog framing %q", cfg.Framing)
+	}
+	facility := 1
+	if cfg.Facility != nil {
+		facility = *cfg.Facility
+	}
+	if facility < 0 || facility > 23 {
+		return nil, fmt.Errorf("invalid syslog facility %d", facility)
+	}
+	cfg.Facility = &facility
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	s := &SyslogLogger{cfg: cfg, hostname: hostname}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SyslogLogger) connect() error {
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.cfg.Network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.cfg.Address, s.cfg.TLSConfig)
+	} else {
+		conn, err = dialer.Dial(s.cfg.Network, s.cfg.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog receiver: %w", err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// Handle implements the Logger interface
+func (s *SyslogLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return s.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface, labels and metadata are sent as structured data.
+func (s *SyslogLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	msg := s.frame(FormatRFC5424(*s.cfg.Facility, s.hostname, labels, timestamp, message, metadata))
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		// Drop the connection so the next message reconnects, the receiver may have restarted.
+		_ = s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	return nil
+}
+
+func (s *SyslogLogger) frame(msg string) []byte {
+	switch {
+	case s.cfg.Network == "udp":
+		return []byte(msg)
+	case s.cfg.Framing == NonTransparent:
+		return []byte(strings.ReplaceAll(msg, "\n", "#012") + "\n")
+	default:
+		return []byte(strconv.Itoa(len(msg)) + " " + msg)
+	}
+}
+
+// Stop closes the connection to the syslog receiver.
+func (s *SyslogLogger) Stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// FormatRFC5424 renders a single RFC5424 message without transport framing.
+// The hostname is taken from the pod metadata or the cluster label, the app-name from service_name.
+func FormatRFC5424(facility int, defaultHostname string, labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) string {
+	hostname := defaultHostname
+	if cluster, ok := labels["cluster"]; ok && cluster != "" {
+		hostname = string(cluster)
+	}
+	for _, m := range metadata {
+		if m.Name == "pod" && m.Value != "" {
+			hostname = m.Value
+		}
+	}
+	appName := "-"
+	if svc, ok := labels["service_name"]; ok && svc != "" {
+		appName = string(svc)
+	}
+
+	sd := strings.Builder{}
+	sd.WriteString("[" + syslogSDID)
+	for _, name := range sortedLabelNames(labels) {
+		if name == "level" || name == "service_name" {
+			continue
+		}
+		writeSDParam(&sd, string(name), string(labels[name]))
+	}
+	for _, m := range metadata {
+		writeSDParam(&sd, m.Name, m.Value)
+	}
+	sd.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s - - %s %s",
+		facility*8+SyslogSeverity(labels["level"]),
+		timestamp.Format(rfc5424Timestamp),
+		headerField(hostname, 255),
+		headerField(appName, 48),
+		sd.String(),
+		message,
+	)
+}
+
+// SyslogSeverity maps a level label to a syslog severity code.
+func SyslogSeverity(level model.LabelValue) int {
+	switch level {
+	case FATAL, CRITICAL:
+		return 2
+	case ERROR:
+		return 3
+	case WARN:
+		return 4
+	case DEBUG, TRACE:
+		return 7
+	default:
+		return 6
+	}
+}
+
+func writeSDParam(sd *strings.Builder, name, value string) {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	if name == "" {
+		return
+	}
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+	sd.WriteString(" " + name + `="` + value + `"`)
+}
+
+// headerField makes sure a header value is printable ASCII without spaces and within the RFC5424 length limit.
+func headerField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+	if value == "" {
+		return "-"
+	}
+	if len(value) > maxLen {
+		return value[:maxLen]
+	}
+	return value
+}
+
+func sortedLabelNames(labels model.LabelSet) model.LabelNames {
+	names := make(model.LabelNames, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Sort(names)
+	return names
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var syslogTestLabels = model.LabelSet{
+	"cluster":      "us-east-1",
+	"namespace":    "gateway",
+	"service_name": "nginx",
+	"level":        ERROR,
+}
+
+var syslogTestTime = time.Date(2024, 9, 13, 10, 18, 49, 123456000, time.UTC)
+
+func TestFormatRFC5424(t *testing.T) {
+	a := assert.New(t)
+
+	msg := FormatRFC5424(1, "localhost", syslogTestLabels, syslogTestTime, "connection refused", push.LabelsAdapter{
+		{Name: "pod", Value: "nginx-abcde"},
+		{Name: "quote", Value: `a "b" ]`},
+	})
+	a.Equal(`<11>1 2024-09-13T10:18:49.123456Z nginx-abcde nginx - - [loki@32473 cluster="us-east-1" namespace="gateway" pod="nginx-abcde" quote="a \"b\" \]"] connection refused`, msg)
+
+	msg = FormatRFC5424(16, "localhost", model.LabelSet{"level": DEBUG}, syslogTestTime, "hello", nil)
+	a.Equal(`<135>1 2024-09-13T10:18:49.123456Z localhost - - - [loki@32473] hello`, msg)
+}
+
+func TestSyslogLoggerTCPOctetCounting(t *testing.T) {
+	a := assert.New(t)
+
+	lines := listenTCP(t, func(r *bufio.Reader) (string, error) {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			return "", err
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(r, buf)
+		return string(buf), err
+	})
+
+	logger, err := NewSyslogLogger(SyslogConfig{Network: "tcp", Address: lines.addr})
+	require.NoError(t, err)
+	defer logger.Stop()
+
+	a.NoError(logger.HandleWithMetadata(syslogTestLabels, syslogTestTime, "first", nil))
+	a.NoError(logger.Handle(syslogTestLabels, syslogTestTime, "second\nwith newline"))
+
+	a.True(strings.HasSuffix(<-lines.ch, "] first"))
+	a.True(strings.HasSuffix(<-lines.ch, "] second\nwith newline"))
+}
+
+func TestSyslogLoggerTCPNonTransparent(t *testing.T) {
+	a := assert.New(t)
+
+	lines := listenTCP(t, func(r *bufio.Reader) (string, error) {
+		line, err := r.ReadString('\n')
+		return strings.TrimSuffix(line, "\n"), err
+	})
+
+	kern := 0
+	logger, err := NewSyslogLogger(SyslogConfig{Network: "tcp", Address: lines.addr, Framing: NonTransparent, Facility: &kern})
+	require.NoError(t, err)
+	defer logger.Stop()
+
+	a.NoError(logger.Handle(syslogTestLabels, syslogTestTime, "hello"))
+	a.NoError(logger.Handle(syslogTestLabels, syslogTestTime, "panic: boom\n\tmain.go:12"))
+	a.True(strings.HasPrefix(<-lines.ch, "<3>1 2024-09-13T10:18:49.123456Z us-east-1 nginx"))
+	a.True(strings.HasSuffix(<-lines.ch, "] panic: boom#012\tmain.go:12"), "multiline messages stay in a single frame")
+}
+
+func TestSyslogLoggerUDP(t *testing.T) {
+	a := assert.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	logger, err := NewSyslogLogger(SyslogConfig{Network: "udp", Address: conn.LocalAddr().String()})
+	require.NoError(t, err)
+	defer logger.Stop()
+
+	a.NoError(logger.Handle(syslogTestLabels, syslogTestTime, "hello"))
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	a.True(strings.HasPrefix(string(buf[:n]), "<11>1 "))
+	a.True(strings.HasSuffix(string(buf[:n]), "] hello"))
+}
+
+func TestNewSyslogLoggerValidation(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewSyslogLogger(SyslogConfig{Network: "unix", Address: "/dev/log"})
+	a.Error(err)
+	_, err = NewSyslogLogger(SyslogConfig{Network: "tcp", Address: "localhost:601", Framing: "lines"})
+	a.Error(err)
+	facility := 24
+	_, err = NewSyslogLogger(SyslogConfig{Network: "tcp", Address: "localhost:601", Facility: &facility})
+	a.Error(err)
+}
+
+type tcpLines struct {
+	addr string
+	ch   chan string
+}
+
+// listenTCP accepts a single connection and decodes messages with the given framing.
+func listenTCP(t *testing.T, read func(r *bufio.Reader) (string, error)) tcpLines {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	lines := tcpLines{addr: l.Addr().String(), ch: make(chan string, 10)}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := read(r)
+			if err != nil {
+				return
+			}
+			lines.ch <- line
+		}
+	}()
+	return lines
+}
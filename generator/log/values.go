@@ -2,8 +2,12 @@ package log
 
 import (
 	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/catalog"
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/load"
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
+	"math"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -66,17 +70,26 @@ var URI = []string{
 }
 
 const (
-	INFO  = model.LabelValue("info")
-	ERROR = model.LabelValue("error")
-	WARN  = model.LabelValue("warn")
-	DEBUG = model.LabelValue("debug")
+	INFO     = model.LabelValue("info")
+	ERROR    = model.LabelValue("error")
+	WARN     = model.LabelValue("warn")
+	DEBUG    = model.LabelValue("debug")
+	TRACE    = model.LabelValue("trace")
+	CRITICAL = model.LabelValue("critical")
+	FATAL    = model.LabelValue("fatal")
+	UNKNOWN  = model.LabelValue("unknown")
 )
 
-var level = []model.LabelValue{
+// Levels lists all levels an AppLogger adds as the level label.
+var Levels = []model.LabelValue{
+	TRACE,
 	DEBUG,
 	INFO,
 	WARN,
 	ERROR,
+	CRITICAL,
+	FATAL,
+	UNKNOWN,
 }
 
 var OrgIDs = []string{"1218", "29", "1010", "2419", "2919"}
@@ -87,44 +100,76 @@ var defaultTraceId = gofakeit.UUID()
 var lessRandomPodLabelName = "tempo-ingester"
 
 func RandLevel() model.LabelValue {
-	r := rand.Intn(100)
-	if r < 5 {
-		return ERROR
-	} else if r < 10 {
-		return WARN
-	} else {
-		return level[rand.Intn(len(level)-2)]
-	}
+	return DefaultLevelWeights.Pick()
 }
 
+// Default distributions of the Rand helpers, dist.Configure overrides them by field name.
+var (
+	uriDist      = dist.NewZipf(1.1, 0)
+	orgIDDist    = dist.NewZipf(1.2, 0)
+	userIDDist   = dist.NewZipf(0.8, 0)
+	fileNameDist = dist.NewZipf(1, 0)
+	durationDist = dist.LogNormal{Median: 0.3, Sigma: 1.5}
+)
+
 func RandURI() string {
-	return URI[rand.Intn(len(URI))]
+	return URI[dist.Pick(dist.Field("uri", uriDist), len(URI))]
+}
+
+// ClusterServices run a single pod per cluster, like the exporter of Kubernetes events.
+var ClusterServices = map[model.LabelValue]bool{
+	"kube-events": true,
+}
+
+// StatefulServices run a fixed number of pods per cluster named after their ordinal, like the
+// kafka-0, kafka-1 and kafka-2 pods of a stateful set.
+var StatefulServices = map[model.LabelValue]int{
+	"kafka":    3,
+	"etcd":     3,
+	"redis":    3,
+	"rabbitmq": 3,
 }
 
 func ForAllClusters(namespace, svc model.LabelValue, cb func(model.LabelSet, push.LabelsAdapter)) {
 	podCount := rand.Intn(10) + 1
+	if ClusterServices[svc] {
+		podCount = 1
+	}
+	if n, ok := StatefulServices[svc]; ok {
+		podCount = n
+	}
 	if string(svc) == lessRandomPodLabelName {
 		podCount = 8
 	}
 	for _, cluster := range Clusters {
 		for i := 0; i < podCount; i++ {
-			clusterInt := 0
-			for _, char := range cluster {
-				clusterInt += int(char)
+			metadata := RandStructuredMetadata(string(svc), i)
+			for _, m := range metadata {
+				if m.Name == "pod" {
+					Pods.Add(Pod{Cluster: cluster, Namespace: string(namespace), Service: string(svc), Name: m.Value})
+				}
 			}
-
-			cb(model.LabelSet{
-				"env":              model.LabelValue(namespaces[rand.Intn(len(namespaces))]),
-				"cluster":          model.LabelValue(cluster),
-				"__stream_shard__": model.LabelValue(shards[clusterInt%len(shards)]),
-				"namespace":        namespace,
-				"service_name":     svc,
-				"file":             "C:\\Grafana\\logs\\" + namespace + ".txt",
-			}, RandStructuredMetadata(string(svc), i))
+			cb(ClusterLabels(namespace, svc, cluster), metadata)
 		}
 	}
 }
 
+// ClusterLabels returns the stream labels of a service running in a cluster.
+func ClusterLabels(namespace, svc model.LabelValue, cluster string) model.LabelSet {
+	clusterInt := 0
+	for _, char := range cluster {
+		clusterInt += int(char)
+	}
+	return model.LabelSet{
+		"env":              model.LabelValue(namespaces[rand.Intn(len(namespaces))]),
+		"cluster":          model.LabelValue(cluster),
+		"__stream_shard__": model.LabelValue(shards[clusterInt%len(shards)]),
+		"namespace":        namespace,
+		"service_name":     svc,
+		"file":             "C:\\Grafana\\logs\\" + namespace + ".txt",
+	}
+}
+
 func RandSeq(n int) string {
 	letters := []rune("abcdefghijklmnopqrstuvwxyz0123456789")
 	b := make([]rune, n)
@@ -135,38 +180,29 @@ func RandSeq(n int) string {
 }
 
 func RandOrgID() string {
-	return OrgIDs[rand.Intn(len(OrgIDs))]
+	return OrgIDs[dist.Pick(dist.Field("org", orgIDDist), len(OrgIDs))]
 }
 
 func RandUserID() string {
-	return UserIDs[rand.Intn(len(UserIDs))]
-}
-
-func RandError() string {
-	switch rand.Intn(10) {
-	case 0:
-		return gofakeit.ErrorDatabase().Error()
-	case 1:
-		return gofakeit.ErrorGRPC().Error()
-	case 2:
-		return gofakeit.ErrorObject().Error()
-	case 3:
-		return gofakeit.ErrorRuntime().Error()
-	case 4:
-		return gofakeit.ErrorHTTP().Error()
-	default:
-		return gofakeit.Error().Error()
-	}
+	return UserIDs[dist.Pick(dist.Field("user", userIDDist), len(UserIDs))]
+}
+
+// RandServiceError returns the message of a recurring error of a service, from its catalog.
+func RandServiceError(service string) string {
+	t := load.Now()
+	return catalog.For(service, t).Pick(t).Message
 }
 
 var filesNames = []string{gofakeit.ProductName(), gofakeit.ProductName(), gofakeit.ProductName(), gofakeit.Word(), gofakeit.Word()}
 
 func RandFileName() string {
-	return strings.ReplaceAll(strings.ToLower(filesNames[rand.Intn(len(filesNames))]), " ", "_")
+	return strings.ReplaceAll(strings.ToLower(filesNames[dist.Pick(dist.Field("file", fileNameDist), len(filesNames))]), " ", "_")
 }
 
+// RandDuration returns a duration between 1ms and 30s with millisecond precision.
 func RandDuration() string {
-	return (time.Duration(gofakeit.Number(1, 30000)) * time.Millisecond).String()
+	seconds := dist.Between(dist.Field("duration", durationDist), 0.001, 30)
+	return (time.Duration(math.Round(seconds*1000)) * time.Millisecond).String()
 }
 
 func RandTraceID(prevTrace string) string {
@@ -186,6 +222,9 @@ func RandStructuredMetadata(svc string, index int) push.LabelsAdapter {
 		// Hardcode the pod name ID for the tempo-ingester service so we can consistently query metadata in e2e tests.
 		podName = lessRandomPodLabelName + "-hc-" + strconv.Itoa(index) + RandSeq(3)
 	}
+	if _, ok := StatefulServices[model.LabelValue(svc)]; ok {
+		podName = svc + "-" + strconv.Itoa(index)
+	}
 	return push.LabelsAdapter{
 		push.LabelAdapter{Name: "traceID", Value: RandTraceID(defaultTraceId)},
 		push.LabelAdapter{Name: "pod", Value: podName},
@@ -2,71 +2,403 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/ingest"
+	"github.com/grafana/explore-logs/generator/load"
 	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/explore-logs/generator/rollout"
+	"github.com/grafana/explore-logs/generator/scenario"
+	"github.com/grafana/explore-logs/generator/session"
 	"github.com/grafana/loki-client-go/loki"
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
 )
 
 func main() {
+	// The first argument selects a subcommand, its flags follow it.
+	command := ""
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	url := flag.String("url", "http://localhost:3100/loki/api/v1/push", "Loki URL")
 	dry := flag.Bool("dry", false, "Dry run: log to stdout instead of Loki")
+	dryFormat := flag.String("dry-format", string(log.DryDefault), "Dry run output format: default, logcli, json or push")
 	tenantId := flag.String("tenant-id", "", "Loki tenant ID")
+	sink := flag.String("sink", "loki", "Where to send logs: loki, syslog, elasticsearch, splunk, fluent or pods")
+	syslogAddress := flag.String("syslog-address", "localhost:601", "Syslog receiver address")
+	syslogNetwork := flag.String("syslog-network", "tcp", "Syslog transport: udp, tcp or tls")
+	syslogFraming := flag.String("syslog-framing", string(log.OctetCounting), "Syslog framing for tcp and tls: octet-counting or non-transparent")
+	syslogInsecure := flag.Bool("syslog-tls-insecure", false, "Skip certificate verification for the tls syslog transport")
+	elasticsearchURL := flag.String("elasticsearch-url", "http://localhost:9200", "Elasticsearch or OpenSearch URL")
+	elasticsearchIndex := flag.String("elasticsearch-index", "explore-logs", "Elasticsearch index or data stream")
+	elasticsearchUsername := flag.String("elasticsearch-username", "", "Elasticsearch basic auth username")
+	elasticsearchPassword := flag.String("elasticsearch-password", "", "Elasticsearch basic auth password")
+	splunkURL := flag.String("splunk-url", "http://localhost:8088", "Splunk HTTP Event Collector URL")
+	splunkToken := flag.String("splunk-token", "", "Splunk HTTP Event Collector token")
+	splunkEndpoint := flag.String("splunk-endpoint", string(log.SplunkEvent), "Splunk HTTP Event Collector endpoint: event or raw")
+	splunkIndex := flag.String("splunk-index", "", "Splunk index, defaults to the token's default index")
+	fluentAddress := flag.String("fluent-address", "localhost:24224", "Fluent Forward receiver address")
+	fluentTagPrefix := flag.String("fluent-tag-prefix", "explore-logs", "Fluent tag prefix, the service name is appended")
+	fluentAck := flag.Bool("fluent-ack", false, "Require the Fluent Forward receiver to acknowledge every chunk")
+	podsDir := flag.String("pods-dir", "/var/log/pods", "Root directory for the pods sink")
+	podsFormat := flag.String("pods-format", string(log.CRIFormat), "Pod log file format: cri or docker")
+	podsMaxSize := flag.Int64("pods-max-size", 10*1024*1024, "Size in bytes at which pod log files are rotated")
+	podsMaxFiles := flag.Int("pods-max-files", 5, "Number of log files kept per container")
+	batchSize := flag.Int("batch-size", 500, "Maximum number of entries per request for batching sinks")
+	batchWait := flag.Duration("batch-wait", time.Second, "Maximum time to wait before sending a partial batch")
+	concurrency := flag.Int("concurrency", 2, "Number of concurrent requests for batching sinks")
+	record := flag.String("record", "", "Write every emitted entry to this recording file")
+	shiftToNow := flag.Bool("shift-to-now", true, "replay and import: shift timestamps so the data appears recent")
+	speed := flag.Float64("speed", 1, "replay: pacing multiplier, 0 sends entries as fast as possible")
+	importSpeed := flag.Float64("import-speed", 0, "import: pacing multiplier, 0 sends entries as fast as possible, ending at the current time with -shift-to-now")
+	importFormat := flag.String("import-format", string(ingest.Auto), "import and learn: file format: auto, text, jsonl, logcli or loki")
+	importLabels := flag.String("labels", "", "import and learn: labels added to every entry, e.g. service_name=api,env=prod")
+	pathLabels := flag.String("path-labels", "", "import and learn: regular expression with named captures matched against file paths")
+	lineLabels := flag.String("line-labels", "", "import and learn: regular expression with named captures matched against lines")
+	timestampField := flag.String("timestamp-field", "", "import and learn: JSON field holding the timestamp")
+	output := flag.String("o", "", "learn: write the scenario to this file instead of stdout")
+	sessionRate := flag.Float64("sessions", 0.5, "New shop sessions per second flowing from the gateway to the backend services, 0 disables them")
+	distributions := flag.String("distributions", "", `Field value distributions, e.g. "uri=zipf(1.5);latency=lognormal(100ms, 2);bytes=pareto(500, 1.2);query=lognormal(5ms, 2)". Fields: `+strings.Join(dist.Fields, ", "))
+	levelsPath := flag.String("levels", "", "JSON file with per service level weights and status codes, replaces the built-in profiles")
+	scenarioPath := flag.String("scenario", "", "Generate the services of this scenario file instead of the built-in ones")
+	loadCurve := flag.String("load", "", `Load curve multiplying the rate of every service, "default" or settings like peak=14,amplitude=0.6,weekend=0.5,tz=Europe/Paris,noise=0.1,growth=0.01. Flat when empty`)
+	backfill := flag.Duration("backfill", 0, "Start generating this far in the past, e.g. 24h, then catch up with the current time and continue live")
+	backfillSpeed := flag.Float64("backfill-speed", 60, "backfill: how many times faster than real time past data is generated, lower it when the sink can't keep up")
+	rolloutSpec := flag.String("rollout", "", `Roll Tempo out from weekly-r138 to weekly-r139, with settings like after=30m,window=2h. Disabled when empty`)
+	splitTraces := flag.Bool("split-stacktraces", false, "Log every line of the stack traces of the polyglot services as an entry of its own, like a shipper without multiline support")
+	podLifecycle := flag.Bool("lifecycle", false, "Run services as pods with a pod label that are rolled out, autoscaled with the load curve and sometimes crash loop")
+	controlAddr := flag.String("control", "", "Address of the control API of the scenario failures and the attacks, e.g. :8081")
+	attackSpec := flag.String("attacks", "", `Attacks against the security services, e.g. "ssh-brute-force:after=10m,for=5m;credential-stuffing;port-scan". Attacks last 15m by default, the control API starts more. Not available with -scenario`)
 	flag.Parse()
 
-	cfg, err := loki.NewDefaultConfig(*url)
-	if err != nil {
+	if err := dist.Configure(*distributions); err != nil {
 		panic(err)
 	}
-	cfg.BackoffConfig.MaxRetries = 1
-	cfg.BackoffConfig.MinBackoff = 100 * time.Millisecond
-	cfg.BackoffConfig.MaxBackoff = 100 * time.Millisecond
 
-	if *tenantId != "" {
-		cfg.TenantID = *tenantId
+	if command == "learn" {
+		if flag.NArg() == 0 {
+			panic("usage: generator learn [flags] <file>...")
+		}
+		learn(flag.Args(), ingestOptions(*importFormat, *importLabels, *pathLabels, *lineLabels, *timestampField), *output)
+		return
 	}
 
-	client, err := loki.New(cfg)
-	if err != nil {
-		panic(err)
-	}
-	defer client.Stop()
+	batchConfig := log.BatchConfig{Size: *batchSize, Wait: *batchWait, Concurrency: *concurrency}
 
-	var logger log.Logger = client
+	var logger log.Logger
+	// podLogger is set by the pods sink, it writes the logs of each pod to a directory of its own.
+	var podLogger *log.PodLogger
 	if *dry {
-		logger = log.LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
-			fmt.Println(labels, timestamp, message, metadata)
-			return nil
-		})
+		dryLogger, err := log.NewDryLogger(os.Stdout, log.DryFormat(*dryFormat))
+		if err != nil {
+			panic(err)
+		}
+		logger = dryLogger
+	} else {
+		switch *sink {
+		case "loki":
+			cfg, err := loki.NewDefaultConfig(*url)
+			if err != nil {
+				panic(err)
+			}
+			cfg.BackoffConfig.MaxRetries = 1
+			cfg.BackoffConfig.MinBackoff = 100 * time.Millisecond
+			cfg.BackoffConfig.MaxBackoff = 100 * time.Millisecond
+
+			if *tenantId != "" {
+				cfg.TenantID = *tenantId
+			}
+
+			client, err := loki.New(cfg)
+			if err != nil {
+				panic(err)
+			}
+			defer client.Stop()
+			logger = client
+		case "syslog":
+			syslogLogger, err := log.NewSyslogLogger(log.SyslogConfig{
+				Network:   *syslogNetwork,
+				Address:   *syslogAddress,
+				Framing:   log.SyslogFraming(*syslogFraming),
+				TLSConfig: &tls.Config{InsecureSkipVerify: *syslogInsecure},
+			})
+			if err != nil {
+				panic(err)
+			}
+			defer syslogLogger.Stop()
+			logger = syslogLogger
+		case "elasticsearch":
+			esLogger, err := log.NewElasticsearchLogger(log.ElasticsearchConfig{
+				URL:      *elasticsearchURL,
+				Index:    *elasticsearchIndex,
+				Username: *elasticsearchUsername,
+				Password: *elasticsearchPassword,
+				Batch:    batchConfig,
+			})
+			if err != nil {
+				panic(err)
+			}
+			defer esLogger.Stop()
+			logger = esLogger
+		case "splunk":
+			splunkLogger, err := log.NewSplunkLogger(log.SplunkConfig{
+				URL:      *splunkURL,
+				Token:    *splunkToken,
+				Endpoint: log.SplunkEndpoint(*splunkEndpoint),
+				Index:    *splunkIndex,
+				Batch:    batchConfig,
+			})
+			if err != nil {
+				panic(err)
+			}
+			defer splunkLogger.Stop()
+			logger = splunkLogger
+		case "fluent":
+			fluentLogger, err := log.NewFluentLogger(log.FluentConfig{
+				Address:    *fluentAddress,
+				TagPrefix:  *fluentTagPrefix,
+				RequireAck: *fluentAck,
+				Batch:      batchConfig,
+			})
+			if err != nil {
+				panic(err)
+			}
+			defer fluentLogger.Stop()
+			logger = fluentLogger
+		case "pods":
+			var err error
+			podLogger, err = log.NewPodLogger(log.PodLoggerConfig{
+				Root:     *podsDir,
+				Format:   log.PodLogFormat(*podsFormat),
+				MaxSize:  *podsMaxSize,
+				MaxFiles: *podsMaxFiles,
+			})
+			if err != nil {
+				panic(err)
+			}
+			defer podLogger.Stop()
+			logger = podLogger
+		default:
+			panic(fmt.Sprintf("unknown sink %q", *sink))
+		}
+	}
+	var err error
+	var recorder *log.Recorder
+	if *record != "" {
+		recorder, err = log.NewRecorder(*record)
+		if err != nil {
+			panic(err)
+		}
+		defer recorder.Stop()
+		logger = recorder.Wrap(logger)
 	}
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	switch command {
+	case "":
+	case "replay":
+		if flag.NArg() != 1 {
+			panic("usage: generator replay [flags] <recording>")
+		}
+		count, err := log.Replay(ctx, flag.Arg(0), logger, log.ReplayOptions{ShiftToNow: *shiftToNow, Speed: *speed})
+		fmt.Fprintf(os.Stderr, "replayed %d entries\n", count)
+		if err != nil && ctx.Err() == nil {
+			panic(err)
+		}
+		return
+	case "import":
+		if flag.NArg() == 0 {
+			panic("usage: generator import [flags] <file>...")
+		}
+		opts := ingestOptions(*importFormat, *importLabels, *pathLabels, *lineLabels, *timestampField)
+		opts.ShiftToNow, opts.Speed = *shiftToNow, *importSpeed
+		count, err := ingest.Run(ctx, flag.Args(), logger, opts)
+		fmt.Fprintf(os.Stderr, "imported %d entries\n", count)
+		if err != nil && ctx.Err() == nil {
+			panic(err)
+		}
+		return
+	default:
+		panic(fmt.Sprintf("unknown command %q", command))
+	}
+
+	start := time.Now()
+	if *backfill > 0 {
+		start = start.Add(-*backfill)
+		if err := load.Default.Backfill(start, *backfillSpeed); err != nil {
+			panic(err)
+		}
+	}
+	if *loadCurve != "" {
+		curve, err := load.Parse(*loadCurve, start)
+		if err != nil {
+			panic(err)
+		}
+		load.Default.SetCurve(curve)
+	}
+	splitStackTraces = *splitTraces
+	if *rolloutSpec != "" {
+		r, err := rollout.Parse(*rolloutSpec, start)
+		if err != nil {
+			panic(err)
+		}
+		tempoRollout.Start, tempoRollout.Window = r.Start, r.Window
+	}
+	control := http.NewServeMux()
+	if *scenarioPath != "" {
+		// Scenarios replace the security services, nothing would log the attacks.
+		if *attackSpec != "" {
+			panic("-attacks can't be used with -scenario")
+		}
+		s, err := scenario.Load(*scenarioPath)
+		if err != nil {
+			panic(err)
+		}
+		failures := scenario.NewFailures(s, start)
+		generators = scenarioGenerators(s, failures)
+		control.Handle("/failures", failures)
+		control.Handle("/failures/", failures)
+	} else {
+		if err := attacks.Configure(*attackSpec, start); err != nil {
+			panic(err)
+		}
+		control.Handle("/attacks", attacks)
+		control.Handle("/attacks/", attacks)
+	}
+	if *controlAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(*controlAddr, control); err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	levels := levelProfiles
+	if *levelsPath != "" {
+		if levels, err = log.LoadLevelConfig(*levelsPath); err != nil {
+			panic(err)
+		}
+	}
+
 	// Creates and starts all apps.
 	for namespace, apps := range generators {
 		for serviceName, generator := range apps {
+			if *podLifecycle {
+				serviceLogger := logger
+				if strings.Contains(string(serviceName), "-otel") {
+					serviceLogger = log.NewOtelLogger(string(serviceName))
+					if recorder != nil {
+						serviceLogger = recorder.Wrap(serviceLogger)
+					}
+				}
+				startDeployments(ctx, namespace, serviceName, generator, serviceLogger, levels.Profile(serviceName))
+				continue
+			}
 			log.ForAllClusters(namespace, serviceName, func(labels model.LabelSet, metadata push.LabelsAdapter) {
+				serviceLogger := logger
+				if podLogger != nil {
+					// The pods sink still needs the pod of streams without metadata.
+					for _, m := range metadata {
+						if m.Name == "pod" {
+							serviceLogger = podLogger.ForPod(m.Value)
+							if recorder != nil {
+								serviceLogger = recorder.Wrap(serviceLogger)
+							}
+						}
+					}
+				}
 				// Remove `metadata` from nginx logs
 				if serviceName == "nginx" {
 					metadata = push.LabelsAdapter{}
 				}
 				if strings.Contains(string(serviceName), "-otel") {
-					generator(ctx, log.NewAppLogger(labels, log.NewOtelLogger(string(serviceName))), metadata)
+					var otelLogger log.Logger = log.NewOtelLogger(string(serviceName))
+					if recorder != nil {
+						otelLogger = recorder.Wrap(otelLogger)
+					}
+					generator(ctx, log.NewAppLogger(labels, otelLogger).WithLevels(levels.Profile(serviceName)), metadata)
 				} else {
-					generator(ctx, log.NewAppLogger(labels, logger), metadata)
+					generator(ctx, log.NewAppLogger(labels, serviceLogger).WithLevels(levels.Profile(serviceName)), metadata)
 				}
 
 			})
 		}
 	}
-	startFailingMimirPod(ctx, logger)
+	if *scenarioPath == "" {
+		startFailingMimirPod(ctx, logger)
+		if *sessionRate > 0 {
+			session.NewSimulator(logger, session.Options{Rate: *sessionRate}).Start(ctx)
+		}
+	}
 
 	<-ctx.Done()
 }
+
+func ingestOptions(format, labels, pathLabels, lineLabels, timestampField string) ingest.Options {
+	opts := ingest.Options{Format: ingest.Format(format), TimestampField: timestampField}
+	var err error
+	if opts.Labels, err = parseLabels(labels); err != nil {
+		panic(err)
+	}
+	if pathLabels != "" {
+		opts.PathLabels = regexp.MustCompile(pathLabels)
+	}
+	if lineLabels != "" {
+		opts.LineLabels = regexp.MustCompile(lineLabels)
+	}
+	return opts
+}
+
+// learn clusters the lines of sample files into a scenario.
+func learn(paths []string, opts ingest.Options, output string) {
+	learner := scenario.NewLearner(scenario.LearnOptions{})
+	for _, path := range paths {
+		if err := ingest.ReadFile(path, opts, func(e log.Entry) error {
+			learner.Add(e)
+			return nil
+		}); err != nil {
+			panic(err)
+		}
+	}
+	w := os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := learner.Scenario().Write(w); err != nil {
+		panic(err)
+	}
+}
+
+// parseLabels parses a comma separated list of name=value pairs.
+func parseLabels(s string) (model.LabelSet, error) {
+	labels := model.LabelSet{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", pair)
+		}
+		labels[model.LabelName(strings.TrimSpace(name))] = model.LabelValue(strings.TrimSpace(value))
+	}
+	return labels, labels.Validate()
+}
@@ -0,0 +1,132 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// EtcdTimestamp is the layout of the timestamps of the etcd logs.
+const EtcdTimestamp = "2006-01-02T15:04:05.000000Z07:00"
+
+// ApplyDist is the distribution of the time slow applies of etcd take past the expected 100ms,
+// in seconds. It is the "apply" field of the distributions.
+var ApplyDist dist.Distribution = dist.LogNormal{Median: 0.1, Sigma: 1}
+
+// registry are the Kubernetes resources stored in etcd.
+var registry = []string{"pods", "services/endpoints", "configmaps", "leases", "events", "deployments", "replicasets", "secrets"}
+
+// Etcd is a member of an etcd cluster. A member must not be used concurrently.
+type Etcd struct {
+	Name string
+	// ID is the member ID, Peers the IDs of the other members of the cluster.
+	ID       string
+	Peers    []string
+	revision int
+	index    int
+}
+
+// NewEtcd returns the member name of the cluster whose members are members.
+func NewEtcd(name string, members []string) *Etcd {
+	e := &Etcd{Name: name, ID: memberID(name), revision: 1_000_000 + rand.Intn(10_000_000)}
+	e.index = e.revision + rand.Intn(100_000)
+	for _, m := range members {
+		if m != name {
+			e.Peers = append(e.Peers, memberID(m))
+		}
+	}
+	return e
+}
+
+// memberID returns the hexadecimal ID of a member, stable for its name.
+func memberID(name string) string {
+	return fmt.Sprintf("%016x", hash(name))
+}
+
+// hash returns a stable hash of s.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// entry renders a line of the zap JSON logger of etcd. Fields are key value pairs, they keep
+// their order like zap does.
+func entry(t time.Time, level model.LabelValue, caller, msg string, fields ...any) log.Line {
+	name := string(level)
+	switch level {
+	case log.CRITICAL:
+		name = "error"
+	case log.TRACE:
+		name = "debug"
+	case log.UNKNOWN:
+		name = "info"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"level":%q,"ts":%q,"caller":%q,"msg":%q`, name, t.UTC().Format(EtcdTimestamp), caller, msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		v, _ := json.Marshal(fields[i+1])
+		fmt.Fprintf(&b, `,%q:%s`, fields[i], v)
+	}
+	b.WriteByte('}')
+	return log.Line{Time: t, Level: level, Text: b.String()}
+}
+
+// peer returns the ID of another member of the cluster.
+func (e *Etcd) peer() string {
+	if len(e.Peers) == 0 {
+		return e.ID
+	}
+	return e.Peers[rand.Intn(len(e.Peers))]
+}
+
+// Log returns the lines logged by the member at t: compactions and snapshots at info, requests
+// taking too long to apply and slow heartbeats at warn, lost peers at error and above.
+func (e *Etcd) Log(t time.Time, level model.LabelValue) []log.Line {
+	e.revision += rand.Intn(1000)
+	e.index += rand.Intn(1000)
+	switch level {
+	case log.WARN:
+		exceeded := time.Duration(dist.Between(dist.Field("apply", ApplyDist), 0.0001, 10) * float64(time.Second)).Round(time.Microsecond)
+		took := 100*time.Millisecond + exceeded
+		if rand.Intn(4) == 0 {
+			return []log.Line{entry(t, level, "etcdserver/raft.go:416", "leader failed to send out heartbeat on time; took too long, leader is overloaded likely from slow disk",
+				"to", e.peer(), "heartbeat-interval", "100ms", "expected-duration", "200ms", "exceeded-duration", exceeded.String())}
+		}
+		namespace := []string{"shop", "kube-system", "monitoring"}[rand.Intn(3)]
+		key := fmt.Sprintf("/registry/%s/%s/", registry[rand.Intn(len(registry))], namespace)
+		return []log.Line{entry(t, level, "etcdserver/util.go:170", "apply request took too long",
+			"took", took.String(), "expected-duration", "100ms", "prefix", "read-only range ",
+			"request", fmt.Sprintf("key:%q range_end:%q ", key, key[:len(key)-1]+"0"),
+			"response", fmt.Sprintf("range_response_count:%d size:%d", rand.Intn(500), rand.Intn(5_000_000)))}
+	case log.ERROR, log.CRITICAL, log.FATAL:
+		peer := e.peer()
+		return []log.Line{
+			entry(t, log.WARN, "rafthttp/stream.go:421", "lost TCP streaming connection with remote peer", "stream-reader-type", "stream MsgApp v2",
+				"local-member-id", e.ID, "remote-peer-id", peer, "error", "read tcp: i/o timeout"),
+			entry(t.Add(time.Duration(rand.Intn(500))*time.Millisecond), level, "rafthttp/peer_status.go:66", "peer became inactive (message send to peer failed)",
+				"peer-id", peer, "error", "failed to dial "+peer+" on stream MsgApp v2 (dial tcp: i/o timeout)"),
+		}
+	case log.DEBUG, log.TRACE:
+		return []log.Line{entry(t, level, "v3rpc/interceptor.go:182", "request stats", "start time", t.Add(-time.Duration(rand.Intn(5000))*time.Microsecond).UTC().Format(EtcdTimestamp),
+			"time spent", time.Duration(rand.Intn(5000)*int(time.Microsecond)).String(), "remote", fmt.Sprintf("10.0.%d.%d:%d", rand.Intn(16), 2+rand.Intn(250), 32768+rand.Intn(28232)),
+			"response type", "/etcdserverpb.KV/Range", "request count", 0, "request size", 18+rand.Intn(50), "response count", rand.Intn(100), "response size", rand.Intn(100_000))}
+	}
+	if rand.Intn(5) == 0 {
+		return []log.Line{entry(t, log.INFO, "etcdserver/server.go:1395", "triggering snapshot", "local-member-id", e.ID,
+			"local-member-applied-index", e.index, "local-member-snapshot-index", e.index-10_001, "local-member-snapshot-count", 10_000)}
+	}
+	took := time.Duration(5+rand.Intn(50)) * time.Millisecond
+	return []log.Line{
+		entry(t, log.INFO, "mvcc/index.go:214", "compact tree index", "revision", e.revision),
+		entry(t.Add(took), log.INFO, "mvcc/kvstore_compaction.go:66", "finished scheduled compaction", "compact-revision", e.revision,
+			"took", took.String(), "hash", rand.Uint32()),
+	}
+}
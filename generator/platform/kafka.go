@@ -0,0 +1,214 @@
+// Package platform generates the logs of the platform the services run on: Kafka and RabbitMQ
+// brokers, Redis and etcd. Each of them has a log format of its own, with a distinctive
+// timestamp layout, and keeps the state its logs describe, like the in-sync replicas of the
+// Kafka partitions or the role of the Redis servers.
+package platform
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// KafkaTimestamp is the layout of the timestamps of the log4j logs of Kafka.
+const KafkaTimestamp = "2006-01-02 15:04:05,000"
+
+// Topics are the Kafka topics of the services, with their number of partitions.
+var Topics = map[string]int{"orders": 6, "payments": 3, "shipments": 3, "cart-events": 6}
+
+// minISR is the min.insync.replicas of the topics.
+const minISR = 2
+
+// partition is a partition of a topic led by a broker.
+type partition struct {
+	topic    string
+	n        int
+	replicas []int
+	isr      []int
+	epoch    int
+	version  int
+	offset   int
+}
+
+func (p *partition) String() string {
+	return fmt.Sprintf("%s-%d", p.topic, p.n)
+}
+
+// Broker is a Kafka broker of a cluster of brokers, it logs the partitions it leads. The first
+// broker of the cluster is its controller. A broker must not be used concurrently.
+type Broker struct {
+	ID int
+	// Brokers are the IDs of the brokers of the cluster.
+	Brokers    []int
+	partitions []*partition
+	generation int
+}
+
+// NewBroker returns the broker id of a cluster of brokers. Replicas of the partitions are
+// spread over the brokers, each broker leads the partitions it is the first replica of.
+func NewBroker(id int, brokers []int) *Broker {
+	b := &Broker{ID: id, Brokers: brokers, generation: 1 + rand.Intn(50)}
+	topics := make([]string, 0, len(Topics))
+	for topic := range Topics {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+	for _, topic := range topics {
+		for n := 0; n < Topics[topic]; n++ {
+			replicas := make([]int, len(brokers))
+			for i := range replicas {
+				replicas[i] = brokers[(n+i)%len(brokers)]
+			}
+			if replicas[0] != id {
+				continue
+			}
+			b.partitions = append(b.partitions, &partition{
+				topic: topic, n: n, replicas: replicas, isr: slices.Clone(replicas),
+				epoch: 1 + rand.Intn(20), version: 1 + rand.Intn(100), offset: rand.Intn(10_000_000),
+			})
+		}
+	}
+	return b
+}
+
+// ids formats broker IDs like Kafka does in its ISR messages.
+func ids(brokers []int) string {
+	s := make([]string, len(brokers))
+	for i, id := range brokers {
+		s[i] = fmt.Sprint(id)
+	}
+	return strings.Join(s, ",")
+}
+
+// log4j renders a line of the broker with the log4j layout of Kafka: "[%d] %p %m (%c)%n". The
+// lines after the first one of message are an exception, printed after the logger.
+func log4j(t time.Time, level model.LabelValue, message, logger string) log.Line {
+	name := strings.ToUpper(string(level))
+	switch level {
+	case log.CRITICAL, log.FATAL:
+		name = "ERROR"
+	case log.UNKNOWN:
+		name = "INFO"
+	}
+	message, exception, _ := strings.Cut(message, "\n")
+	text := fmt.Sprintf("[%s] %s %s (%s)", t.Format(KafkaTimestamp), name, message, logger)
+	if exception != "" {
+		text += "\n" + exception
+	}
+	return log.Line{Time: t, Level: level, Text: text}
+}
+
+// pick returns a random partition matching f, nil when none does.
+func (b *Broker) pick(f func(p *partition) bool) *partition {
+	var matching []*partition
+	for _, p := range b.partitions {
+		if f(p) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		return nil
+	}
+	return matching[rand.Intn(len(matching))]
+}
+
+// prefix returns the prefix of the messages of the broker about partition p.
+func (b *Broker) prefix(p *partition) string {
+	return fmt.Sprintf("[Partition %s broker=%d]", p, b.ID)
+}
+
+// other returns another broker of the cluster.
+func (b *Broker) other() int {
+	for {
+		if id := b.Brokers[rand.Intn(len(b.Brokers))]; id != b.ID || len(b.Brokers) == 1 {
+			return id
+		}
+	}
+}
+
+func shrunk(p *partition) bool { return len(p.isr) < len(p.replicas) }
+
+// Log returns the lines logged by the broker at t. Replicas falling behind shrink the ISR of the
+// partitions at warn, they catch up again at info. Partitions with too few in-sync replicas
+// refuse writes at error, and elections of new leaders fail at critical.
+func (b *Broker) Log(t time.Time, level model.LabelValue) []log.Line {
+	for _, p := range b.partitions {
+		p.offset += rand.Intn(500)
+	}
+	switch level {
+	case log.WARN:
+		if p := b.pick(func(p *partition) bool { return len(p.isr) > 1 }); p != nil && rand.Intn(2) == 0 {
+			return b.shrink(t, p)
+		}
+		leader := b.other()
+		return []log.Line{log4j(t, log.WARN, fmt.Sprintf("[ReplicaFetcher replicaId=%d, leaderId=%d, fetcherId=0] Error in response for fetch request (type=FetchRequest, replicaId=%d, maxWait=500, minBytes=1, maxBytes=10485760, fetchData={}, isolationLevel=READ_UNCOMMITTED, removed=, replaced=, metadata=(sessionId=%d, epoch=%d), rackId=)\njava.io.IOException: Connection to %d was disconnected before the response was read\n\tat org.apache.kafka.clients.NetworkClientUtils.sendAndReceive(NetworkClientUtils.java:99)\n\tat kafka.server.BrokerBlockingSender.sendRequest(BrokerBlockingSender.scala:113)\n\tat kafka.server.RemoteLeaderEndPoint.fetch(RemoteLeaderEndPoint.scala:79)\n\tat kafka.server.AbstractFetcherThread.processFetchRequest(AbstractFetcherThread.scala:316)",
+			b.ID, leader, b.ID, rand.Intn(1<<30), rand.Intn(1000), leader), "kafka.server.ReplicaFetcherThread")}
+	case log.ERROR:
+		if p := b.pick(func(p *partition) bool { return len(p.isr) < minISR }); p != nil {
+			return []log.Line{log4j(t, log.ERROR, fmt.Sprintf("[ReplicaManager broker=%d] Error processing append operation on partition %s\norg.apache.kafka.common.errors.NotEnoughReplicasException: The size of the current ISR Set(%s) is insufficient to satisfy the min.isr requirement of %d for partition %s", b.ID, p, ids(p.isr), minISR, p), "kafka.server.ReplicaManager")}
+		}
+		if p := b.pick(func(p *partition) bool { return len(p.isr) > 1 }); p != nil {
+			// A replica is lost: the ISR shrinks until writes are refused.
+			return b.shrink(t, p)
+		}
+		return nil
+	case log.CRITICAL, log.FATAL:
+		p := b.partitions[rand.Intn(len(b.partitions))]
+		return []log.Line{log4j(t, level, fmt.Sprintf("[Controller id=%d epoch=%d] Controller %d epoch %d failed to change state for partition %s from OnlinePartition to OnlinePartition\nkafka.common.StateChangeFailedException: Failed to elect leader for partition %s under strategy PreferredReplicaPartitionLeaderElectionStrategy",
+			b.Brokers[0], b.generation, b.Brokers[0], b.generation, p, p), "state.change.logger")}
+	case log.DEBUG, log.TRACE:
+		leader := b.other()
+		return []log.Line{log4j(t, level, fmt.Sprintf("[ReplicaFetcher replicaId=%d, leaderId=%d, fetcherId=0] Built incremental fetch (sessionId=%d, epoch=%d) for node %d. Added 0 partition(s), altered 0 partition(s), removed 0 partition(s), replaced 0 partition(s) out of %d partition(s)",
+			b.ID, leader, rand.Intn(1<<30), rand.Intn(1000), leader, len(b.partitions)), "org.apache.kafka.clients.FetchSessionHandler")}
+	}
+
+	if p := b.pick(shrunk); p != nil && rand.Intn(2) == 0 {
+		// The replicas caught up.
+		from := ids(p.isr)
+		p.isr = slices.Clone(p.replicas)
+		p.version++
+		return []log.Line{
+			log4j(t, log.INFO, fmt.Sprintf("%s Expanding ISR from %s to %s", b.prefix(p), from, ids(p.isr)), "kafka.cluster.Partition"),
+			log4j(t, log.INFO, fmt.Sprintf("%s ISR updated to %s and version updated to %d", b.prefix(p), ids(p.isr), p.version), "kafka.cluster.Partition"),
+		}
+	}
+	p := b.partitions[rand.Intn(len(b.partitions))]
+	switch rand.Intn(4) {
+	case 0:
+		// A preferred leader election moves the partition back to its preferred replica.
+		p.epoch++
+		return []log.Line{
+			log4j(t, log.INFO, fmt.Sprintf("[Controller id=%d] Starting replica leader election (PREFERRED) for partitions %s triggered by AutoTriggered", b.Brokers[0], p), "kafka.controller.KafkaController"),
+			log4j(t, log.INFO, fmt.Sprintf("%s %s starts at leader epoch %d from offset %d with partition epoch %d, high watermark %d. Previous leader epoch was %d.", b.prefix(p), p, p.epoch, p.offset, p.version, p.offset, p.epoch-1), "kafka.cluster.Partition"),
+		}
+	case 1:
+		b.generation++
+		group := []string{"checkout-consumers", "shipping-consumers", "billing-consumers"}[rand.Intn(3)]
+		offsets := fmt.Sprintf("__consumer_offsets-%d", rand.Intn(50))
+		return []log.Line{
+			log4j(t, log.INFO, fmt.Sprintf("[GroupCoordinator %d]: Preparing to rebalance group %s in state PreparingRebalance with old generation %d (%s) (reason: Adding new member consumer-%s-1-%08x with group instance id None)", b.ID, group, b.generation-1, offsets, group, rand.Uint32()), "kafka.coordinator.group.GroupCoordinator"),
+			log4j(t.Add(time.Duration(100+rand.Intn(3000))*time.Millisecond), log.INFO, fmt.Sprintf("[GroupCoordinator %d]: Stabilized group %s generation %d (%s) with %d members", b.ID, group, b.generation, offsets, 1+rand.Intn(6)), "kafka.coordinator.group.GroupCoordinator"),
+		}
+	default:
+		return []log.Line{log4j(t, log.INFO, fmt.Sprintf("[LocalLog partition=%s, dir=/var/lib/kafka/data] Rolled new log segment at offset %d in %d ms.", p, p.offset, 1+rand.Intn(20)), "kafka.log.LocalLog")}
+	}
+}
+
+// shrink removes the last in-sync follower of p from its ISR.
+func (b *Broker) shrink(t time.Time, p *partition) []log.Line {
+	from := ids(p.isr)
+	out := p.isr[len(p.isr)-1]
+	p.isr = p.isr[:len(p.isr)-1]
+	p.version++
+	caughtUp := t.Add(-time.Duration(30+rand.Intn(30)) * time.Second)
+	return []log.Line{
+		log4j(t, log.WARN, fmt.Sprintf("%s Shrinking ISR from %s to %s. Leader: (highWatermark: %d, endOffset: %d). Out of sync replicas: (brokerId: %d, endOffset: %d, lastCaughtUpTimeMs: %d).",
+			b.prefix(p), from, ids(p.isr), p.offset-rand.Intn(100), p.offset, out, p.offset-100-rand.Intn(1000), caughtUp.UnixMilli()), "kafka.cluster.Partition"),
+		log4j(t, log.INFO, fmt.Sprintf("%s ISR updated to %s and version updated to %d", b.prefix(p), ids(p.isr), p.version), "kafka.cluster.Partition"),
+	}
+}
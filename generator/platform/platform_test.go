@@ -0,0 +1,82 @@
+package platform
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBroker(t *testing.T) {
+	a := assert.New(t)
+	led := 0
+	for _, id := range []int{1, 2, 3} {
+		b := NewBroker(id, []int{1, 2, 3})
+		for _, p := range b.partitions {
+			a.Equal(id, p.replicas[0], "brokers lead the partitions they are the first replica of")
+		}
+		led += len(b.partitions)
+	}
+	a.Equal(18, led, "every partition has a leader")
+}
+
+func TestBrokerISR(t *testing.T) {
+	a := assert.New(t)
+	b := NewBroker(2, []int{1, 2, 3})
+	var shrunk []log.Line
+	for len(shrunk) == 0 {
+		shrunk = b.Log(time.Now(), log.ERROR)
+	}
+	a.Contains(shrunk[0].Text, "] WARN [Partition ")
+	a.Contains(shrunk[0].Text, " Shrinking ISR from 2,3,1 to 2,3. ")
+
+	var refused bool
+	for i := 0; i < 100 && !refused; i++ {
+		lines := b.Log(time.Now(), log.ERROR)
+		refused = len(lines) > 0 && strings.Contains(lines[0].Text, "NotEnoughReplicasException")
+	}
+	a.True(refused, "writes are refused once partitions are down to one in-sync replica")
+}
+
+func TestLog4jException(t *testing.T) {
+	a := assert.New(t)
+	line := log4j(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), log.ERROR, "failed\njava.io.IOException: boom", "kafka.Log")
+	a.Equal("[2024-05-01 10:00:00,000] ERROR failed (kafka.Log)\njava.io.IOException: boom", line.Text)
+}
+
+func TestRabbitMQ(t *testing.T) {
+	a := assert.New(t)
+	r := NewRabbitMQ("rabbitmq-0", "10.0.3.4")
+	a.Equal("rabbit@rabbitmq-0", r.Node)
+	a.Contains(r.Log(time.Now(), log.WARN)[0].Text, " [warning] ")
+}
+
+func TestRedisSave(t *testing.T) {
+	a := assert.New(t)
+	r := NewRedis(Master, "10.0.1.2:6379")
+	for i := 0; i < 100; i++ {
+		if lines := r.Log(time.Now(), log.INFO); len(lines) == 5 {
+			a.True(strings.HasPrefix(lines[0].Text, "1:M "))
+			a.Contains(lines[2].Text, ":C ", "the forked child saves the dataset")
+			return
+		}
+	}
+	t.Fatal("no background save")
+}
+
+func TestEtcd(t *testing.T) {
+	a := assert.New(t)
+	members := []string{"etcd-0", "etcd-1", "etcd-2"}
+	e := NewEtcd("etcd-1", members)
+	a.Len(e.Peers, 2)
+	a.NotContains(e.Peers, e.ID)
+	a.Equal(e.ID, NewEtcd("etcd-1", members).ID, "member IDs are stable")
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal([]byte(e.Log(time.Now(), log.CRITICAL)[1].Text), &fields))
+	a.Equal("error", fields["level"])
+}
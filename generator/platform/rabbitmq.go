@@ -0,0 +1,84 @@
+package platform
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// RabbitMQTimestamp is the layout of the timestamps of the RabbitMQ logs.
+const RabbitMQTimestamp = "2006-01-02 15:04:05.000000-07:00"
+
+// rabbitMQUsers are the RabbitMQ users of the services publishing and consuming messages.
+var rabbitMQUsers = []string{"checkout", "shipping", "billing", "notifications"}
+
+// RabbitMQ is a RabbitMQ node. A node must not be used concurrently.
+type RabbitMQ struct {
+	// Node is the Erlang node name of the broker, like rabbit@rabbitmq-0.
+	Node    string
+	Address string
+	lastPID int
+}
+
+// NewRabbitMQ returns the RabbitMQ node of the pod named name listening on address.
+func NewRabbitMQ(name, address string) *RabbitMQ {
+	return &RabbitMQ{Node: "rabbit@" + name, Address: address, lastPID: 1000 + rand.Intn(9000)}
+}
+
+// pid returns the Erlang process ID of a new process of the node.
+func (r *RabbitMQ) pid() string {
+	r.lastPID += 1 + rand.Intn(50)
+	return fmt.Sprintf("<0.%d.0>", r.lastPID)
+}
+
+// line renders a message of process pid with the RabbitMQ log format.
+func (r *RabbitMQ) line(t time.Time, level model.LabelValue, pid, message string) log.Line {
+	name := string(level)
+	switch level {
+	case log.WARN:
+		name = "warning"
+	case log.FATAL:
+		name = "critical"
+	case log.TRACE:
+		name = "debug"
+	case log.UNKNOWN:
+		name = "info"
+	}
+	return log.Line{Time: t, Level: level, Text: fmt.Sprintf("%s [%s] %s %s", t.Format(RabbitMQTimestamp), name, pid, message)}
+}
+
+// Log returns the lines logged by the node at t: AMQP connections of the services at info,
+// clients going away at warn, channel exceptions and missed heartbeats at error and memory
+// alarms blocking the publishers at critical.
+func (r *RabbitMQ) Log(t time.Time, level model.LabelValue) []log.Line {
+	pid := r.pid()
+	user := rabbitMQUsers[rand.Intn(len(rabbitMQUsers))]
+	peer := fmt.Sprintf("10.0.%d.%d:%d -> %s:5672", rand.Intn(16), 2+rand.Intn(250), 32768+rand.Intn(28232), r.Address)
+	conn := fmt.Sprintf("%s (%s)", pid, peer)
+	switch level {
+	case log.WARN:
+		return []log.Line{r.line(t, level, pid, fmt.Sprintf("closing AMQP connection %s:\nclient unexpectedly closed TCP connection", conn))}
+	case log.ERROR:
+		if rand.Intn(2) == 0 {
+			return []log.Line{r.line(t, level, pid, fmt.Sprintf("closing AMQP connection %s:\nmissed heartbeats from client, timeout: 60s", conn))}
+		}
+		return []log.Line{r.line(t, level, pid, fmt.Sprintf("Channel error on connection %s (%s, vhost: '/', user: '%s'), channel 1:\noperation basic.publish caused a channel exception not_found: no exchange '%s.dlx' in vhost '/'", pid, peer, user, user))}
+	case log.CRITICAL, log.FATAL:
+		return []log.Line{
+			r.line(t, log.WARN, pid, fmt.Sprintf("memory resource limit alarm set on node '%s'.\n\n**********************************************************\n*** Publishers will be blocked until this alarm clears ***\n**********************************************************", r.Node)),
+			r.line(t, level, pid, fmt.Sprintf("vm_memory_high_watermark set. Memory used:%d allowed:%d", 3_300_000_000+rand.Intn(400_000_000), 3_277_174_374)),
+		}
+	case log.DEBUG, log.TRACE:
+		return []log.Line{r.line(t, level, pid, fmt.Sprintf("User '%s' authenticated successfully by backend rabbit_auth_backend_internal", user))}
+	}
+	if rand.Intn(3) == 0 {
+		return []log.Line{r.line(t, log.INFO, pid, fmt.Sprintf("closing AMQP connection %s, vhost: '/', user: '%s'", conn, user))}
+	}
+	return []log.Line{
+		r.line(t, log.INFO, pid, fmt.Sprintf("accepting AMQP connection %s", conn)),
+		r.line(t.Add(time.Duration(1+rand.Intn(20))*time.Millisecond), log.INFO, pid, fmt.Sprintf("connection %s: user '%s' authenticated and granted access to vhost '/'", conn, user)),
+	}
+}
@@ -0,0 +1,125 @@
+package platform
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// RedisTimestamp is the layout of the timestamps of the Redis logs.
+const RedisTimestamp = "02 Jan 2006 15:04:05.000"
+
+// Roles of the Redis processes, they prefix the lines after the process ID.
+const (
+	Master  = 'M'
+	Replica = 'S'
+	// child is the process forked to save the dataset.
+	child = 'C'
+)
+
+// Redis is a Redis server, a master or one of its replicas. A server must not be used
+// concurrently.
+type Redis struct {
+	PID     int
+	Role    byte
+	Address string
+	// Peers are the addresses of the replicas of a master, or of the master of a replica.
+	Peers   []string
+	lastPID int
+	offset  int
+}
+
+// NewRedis returns the Redis server of role listening on address, with the addresses of its
+// peers.
+func NewRedis(role byte, address string, peers ...string) *Redis {
+	return &Redis{PID: 1, Role: role, Address: address, Peers: peers, lastPID: 100 + rand.Intn(1000), offset: rand.Intn(1_000_000_000)}
+}
+
+// line renders a message of process pid of role in the classic Redis format
+// "pid:role date level message", where the level is one of the characters . - * #.
+func (r *Redis) line(t time.Time, level model.LabelValue, pid int, role byte, message string) log.Line {
+	c := '*'
+	switch level {
+	case log.DEBUG:
+		c = '.'
+	case log.TRACE:
+		c = '-'
+	case log.WARN, log.ERROR, log.CRITICAL, log.FATAL:
+		c = '#'
+	}
+	return log.Line{Time: t, Level: level, Text: fmt.Sprintf("%d:%c %s %c %s", pid, role, t.Format(RedisTimestamp), c, message)}
+}
+
+// peer returns the address of a peer of the server.
+func (r *Redis) peer() string {
+	if len(r.Peers) == 0 {
+		return fmt.Sprintf("10.0.%d.%d:6379", rand.Intn(16), 2+rand.Intn(250))
+	}
+	return r.Peers[rand.Intn(len(r.Peers))]
+}
+
+// Log returns the lines logged by the server at t: background saves and replication at info,
+// slow disks and clients over their buffer limits at warn, lost replicas and failed saves at
+// error and above.
+func (r *Redis) Log(t time.Time, level model.LabelValue) []log.Line {
+	r.offset += rand.Intn(100_000)
+	switch level {
+	case log.WARN:
+		if rand.Intn(2) == 0 {
+			return []log.Line{r.line(t, level, r.PID, r.Role, "Asynchronous AOF fsync is taking too long (disk is busy?). Writing the AOF buffer without waiting for fsync to complete, this may slow down Redis.")}
+		}
+		return []log.Line{r.line(t, level, r.PID, r.Role, fmt.Sprintf("Client id=%d addr=10.0.%d.%d:%d laddr=%s fd=%d name= age=%d idle=0 flags=N db=0 sub=0 psub=0 ssub=0 multi=-1 qbuf=0 qbuf-free=0 argv-mem=0 multi-mem=0 rbs=1024 rbp=0 obl=0 oll=%d omem=%d tot-mem=%d events=rw cmd=subscribe user=default redir=-1 resp=2 lib-name= lib-ver= closed for overcoming of output buffer limits.",
+			rand.Intn(100_000), rand.Intn(16), 2+rand.Intn(250), 32768+rand.Intn(28232), r.Address, 8+rand.Intn(100), rand.Intn(10_000), 1000+rand.Intn(5000), 33_554_432+rand.Intn(1_000_000), 33_600_000+rand.Intn(1_000_000)))}
+	case log.ERROR:
+		if r.Role == Replica {
+			return []log.Line{
+				r.line(t, level, r.PID, r.Role, "Connection with master lost."),
+				r.line(t, log.INFO, r.PID, r.Role, "Caching the disconnected master state."),
+				r.line(t.Add(time.Second), log.INFO, r.PID, r.Role, fmt.Sprintf("Connecting to MASTER %s", r.peer())),
+			}
+		}
+		return []log.Line{r.line(t, level, r.PID, r.Role, fmt.Sprintf("Connection with replica %s lost.", r.peer()))}
+	case log.CRITICAL, log.FATAL:
+		return []log.Line{
+			r.line(t, log.INFO, r.PID, r.Role, fmt.Sprintf("%d changes in 60 seconds. Saving...", 10_000+rand.Intn(50_000))),
+			r.line(t, level, r.PID, r.Role, "Can't save in background: fork: Cannot allocate memory"),
+		}
+	case log.DEBUG:
+		replicas := len(r.Peers)
+		if r.Role == Replica {
+			replicas = 0
+		}
+		return []log.Line{r.line(t, level, r.PID, r.Role, fmt.Sprintf("%d clients connected (%d replicas), %d bytes in use", 10+rand.Intn(200), replicas, 100_000_000+rand.Intn(900_000_000)))}
+	case log.TRACE:
+		return []log.Line{r.line(t, level, r.PID, r.Role, fmt.Sprintf("Accepted 10.0.%d.%d:%d", rand.Intn(16), 2+rand.Intn(250), 32768+rand.Intn(28232)))}
+	}
+
+	if rand.Intn(3) == 0 {
+		if r.Role == Replica {
+			return []log.Line{
+				r.line(t, log.INFO, r.PID, r.Role, fmt.Sprintf("Trying a partial resynchronization (request %016x%016x%08x:%d).", rand.Uint64(), rand.Uint64(), rand.Uint32(), r.offset)),
+				r.line(t, log.INFO, r.PID, r.Role, "Successful partial resynchronization with master."),
+				r.line(t, log.INFO, r.PID, r.Role, "MASTER <-> REPLICA sync: Master accepted a Partial Resynchronization."),
+			}
+		}
+		replica := r.peer()
+		return []log.Line{
+			r.line(t, log.INFO, r.PID, r.Role, fmt.Sprintf("Replica %s asks for synchronization", replica)),
+			r.line(t, log.INFO, r.PID, r.Role, fmt.Sprintf("Partial resynchronization request from %s accepted. Sending %d bytes of backlog starting from offset %d.", replica, rand.Intn(100_000), r.offset)),
+		}
+	}
+	// A background save: the server forks a child writing the dataset to disk.
+	r.lastPID += 1 + rand.Intn(100)
+	done := t.Add(time.Duration(100+rand.Intn(2000)) * time.Millisecond)
+	cow := 1 + rand.Intn(20)
+	return []log.Line{
+		r.line(t, log.INFO, r.PID, r.Role, fmt.Sprintf("%d changes in 300 seconds. Saving...", 10+rand.Intn(1000))),
+		r.line(t, log.INFO, r.PID, r.Role, fmt.Sprintf("Background saving started by pid %d", r.lastPID)),
+		r.line(done, log.INFO, r.lastPID, child, "DB saved on disk"),
+		r.line(done, log.INFO, r.lastPID, child, fmt.Sprintf("Fork CoW for RDB: current %d MB, peak %d MB, average %d MB", cow, cow, (cow+1)/2)),
+		r.line(done.Add(100*time.Millisecond), log.INFO, r.PID, r.Role, "Background saving terminated with success"),
+	}
+}
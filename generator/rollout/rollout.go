@@ -0,0 +1,101 @@
+// Package rollout moves services from one version to the next. The share of streams running
+// the new version grows over a window like a canary, so the patterns, fields and errors of both
+// versions can be compared while they overlap.
+package rollout
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+)
+
+// Rollout is the move of a service from version From to version To. Its zero value never
+// starts.
+type Rollout struct {
+	From, To string
+	// Start is when the first streams move to To, Window how long it takes for all of them.
+	Start  time.Time
+	Window time.Duration
+}
+
+// DefaultWindow is the duration of rollouts that don't set one.
+const DefaultWindow = time.Hour
+
+// Share returns the share of streams running the new version at t, between 0 and 1.
+func (r Rollout) Share(t time.Time) float64 {
+	if r.Start.IsZero() || t.Before(r.Start) {
+		return 0
+	}
+	if r.Window <= 0 {
+		return 1
+	}
+	return min(float64(t.Sub(r.Start))/float64(r.Window), 1)
+}
+
+// Stream is a stream of the service, it moves to the new version once the share of the rollout
+// reaches its threshold. Streams never move back.
+type Stream struct {
+	Rollout
+	threshold float64
+}
+
+// Stream returns a new stream with a random threshold.
+func (r Rollout) Stream() *Stream {
+	// Avoid a threshold of 0, which would run the new version before the rollout starts.
+	return &Stream{Rollout: r, threshold: 1 - rand.Float64()}
+}
+
+// Upgraded returns whether the stream runs the new version at t.
+func (s *Stream) Upgraded(t time.Time) bool {
+	return s.Share(t) >= s.threshold
+}
+
+// Version returns the version the stream runs at t.
+func (s *Stream) Version(t time.Time) string {
+	if s.Upgraded(t) {
+		return s.To
+	}
+	return s.From
+}
+
+// Metadata returns metadata with the version added, for each version of the rollout.
+func (r Rollout) Metadata(metadata push.LabelsAdapter) (from, to push.LabelsAdapter) {
+	with := func(version string) push.LabelsAdapter {
+		out := make(push.LabelsAdapter, 0, len(metadata)+1)
+		out = append(out, metadata...)
+		return append(out, push.LabelAdapter{Name: "version", Value: version})
+	}
+	return with(r.From), with(r.To)
+}
+
+// Parse parses the timing of a rollout written as comma separated settings, e.g.
+// "after=30m,window=2h". The rollout starts after the given time since start.
+func Parse(spec string, start time.Time) (Rollout, error) {
+	r := Rollout{Start: start, Window: DefaultWindow}
+	for _, setting := range strings.Split(spec, ",") {
+		if strings.TrimSpace(setting) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return r, fmt.Errorf("invalid rollout setting %q, expected name=value", setting)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return r, fmt.Errorf("rollout: invalid %s %q", name, value)
+		}
+		switch name {
+		case "after":
+			r.Start = start.Add(d)
+		case "window":
+			r.Window = d
+		default:
+			return r, fmt.Errorf("unknown rollout setting %q", name)
+		}
+	}
+	return r, nil
+}
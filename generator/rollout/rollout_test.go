@@ -0,0 +1,80 @@
+package rollout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShare(t *testing.T) {
+	a := assert.New(t)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	r := Rollout{From: "v1", To: "v2", Start: start, Window: time.Hour}
+
+	a.Equal(0.0, r.Share(start.Add(-time.Minute)))
+	a.Equal(0.0, r.Share(start))
+	a.Equal(0.25, r.Share(start.Add(15*time.Minute)))
+	a.Equal(1.0, r.Share(start.Add(2*time.Hour)))
+	a.Equal(0.0, Rollout{}.Share(start), "the zero rollout never starts")
+}
+
+func TestStreams(t *testing.T) {
+	a := assert.New(t)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	r := Rollout{From: "v1", To: "v2", Start: start, Window: time.Hour}
+
+	streams := make([]*Stream, 1000)
+	for i := range streams {
+		streams[i] = r.Stream()
+	}
+	count := func(t time.Time) int {
+		n := 0
+		for _, s := range streams {
+			if s.Version(t) == "v2" {
+				n++
+			}
+		}
+		return n
+	}
+	a.Equal(0, count(start.Add(-time.Second)))
+	a.InDelta(500, count(start.Add(30*time.Minute)), 60)
+	a.Equal(len(streams), count(start.Add(time.Hour)))
+
+	// Upgraded streams stay upgraded.
+	for _, s := range streams {
+		if s.Upgraded(start.Add(10 * time.Minute)) {
+			a.True(s.Upgraded(start.Add(20 * time.Minute)))
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	metadata := push.LabelsAdapter{{Name: "pod", Value: "a"}}
+	from, to := Rollout{From: "v1", To: "v2"}.Metadata(metadata)
+	assert.Equal(t, push.LabelsAdapter{{Name: "pod", Value: "a"}, {Name: "version", Value: "v1"}}, from)
+	assert.Equal(t, push.LabelsAdapter{{Name: "pod", Value: "a"}, {Name: "version", Value: "v2"}}, to)
+	assert.Len(t, metadata, 1)
+}
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	r, err := Parse("after=30m, window=2h", start)
+	require.NoError(t, err)
+	a.Equal(start.Add(30*time.Minute), r.Start)
+	a.Equal(2*time.Hour, r.Window)
+
+	r, err = Parse("", start)
+	require.NoError(t, err)
+	a.Equal(start, r.Start)
+	a.Equal(DefaultWindow, r.Window)
+
+	for _, spec := range []string{"after", "after=soon", "window=-1h", "speed=2"} {
+		_, err := Parse(spec, start)
+		a.Error(err, spec)
+	}
+}
@@ -0,0 +1,307 @@
+package scenario
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/log"
+)
+
+// Template patterns can contain actions between {{ and }}, a function name followed by its
+// arguments, e.g. {{duration "lognormal" 50ms 2}}. Arguments are quoted strings or bare words.
+// An action with a single quoted string writes it as is, {{"{{"}} escapes braces.
+//
+//	ts [layout]                the entry timestamp: rfc3339nano (default), rfc3339, unix, unixms, unixnano, apache, syslog or a Go layout
+//	pick values...             one of the values
+//	int min max                an integer between min and max
+//	float min max [decimals]   a number between min and max, with 2 decimals by default
+//	duration [dist params...]  a duration from a dist.New distribution, e.g. lognormal 50ms 2, log.RandDuration without arguments
+//	number dist params...      an integer from a dist.New distribution, e.g. pareto 100 1.5
+//	seq n                      n random letters and digits
+//	user, org, uri, file       log.RandUserID, log.RandOrgID, log.RandURI and log.RandFileName
+//	error [service]            a recurring error of the service, log.RandServiceError, the service of the template by default
+//	ip, uuid, word             a fake IP, UUID or word
+var funcs = map[string]func(args []string) (func(t time.Time) string, error){
+	"ts":       tsFunc,
+	"pick":     pickFunc,
+	"int":      intFunc,
+	"float":    floatFunc,
+	"duration": durationFunc,
+	"number":   numberFunc,
+	"seq":      seqFunc,
+	"user":     helperFunc(log.RandUserID),
+	"org":      helperFunc(log.RandOrgID),
+	"error":    errorFunc,
+	"uri":      helperFunc(log.RandURI),
+	"file":     helperFunc(log.RandFileName),
+	"ip":       helperFunc(flog.FakeIP),
+	"uuid":     helperFunc(gofakeit.UUID),
+	"word":     helperFunc(gofakeit.Word),
+}
+
+// timeLayouts are the names accepted by the ts function.
+var timeLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"apache":      "02/Jan/2006:15:04:05 -0700",
+	"syslog":      time.Stamp,
+}
+
+func helperFunc(fn func() string) func([]string) (func(time.Time) string, error) {
+	return func(args []string) (func(time.Time) string, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("takes no arguments")
+		}
+		return func(time.Time) string { return fn() }, nil
+	}
+}
+
+func errorFunc(args []string) (func(time.Time) string, error) {
+	switch len(args) {
+	case 0:
+		return nil, fmt.Errorf("needs a service outside of the templates of a service")
+	case 1:
+		return func(time.Time) string { return log.RandServiceError(args[0]) }, nil
+	}
+	return nil, fmt.Errorf("takes at most a service, got %d arguments", len(args))
+}
+
+func tsFunc(args []string) (func(time.Time) string, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("takes at most one layout")
+	}
+	layout := time.RFC3339Nano
+	if len(args) == 1 {
+		switch args[0] {
+		case "unix":
+			return func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }, nil
+		case "unixms":
+			return func(t time.Time) string { return strconv.FormatInt(t.UnixMilli(), 10) }, nil
+		case "unixnano":
+			return func(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }, nil
+		}
+		layout = args[0]
+		if named, ok := timeLayouts[strings.ToLower(layout)]; ok {
+			layout = named
+		}
+	}
+	return func(t time.Time) string { return t.Format(layout) }, nil
+}
+
+func pickFunc(args []string) (func(time.Time) string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("needs at least one value")
+	}
+	return func(time.Time) string { return args[rand.Intn(len(args))] }, nil
+}
+
+func intFunc(args []string) (func(time.Time) string, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("needs min and max")
+	}
+	lo, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, err
+	}
+	hi, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if hi < lo {
+		return nil, fmt.Errorf("max %d is lower than min %d", hi, lo)
+	}
+	return func(time.Time) string { return strconv.Itoa(lo + rand.Intn(hi-lo+1)) }, nil
+}
+
+func floatFunc(args []string) (func(time.Time) string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("needs min, max and optional decimals")
+	}
+	nums, err := parseFloats(args[:2])
+	if err != nil {
+		return nil, err
+	}
+	decimals := 2
+	if len(args) == 3 {
+		if decimals, err = strconv.Atoi(args[2]); err != nil {
+			return nil, err
+		}
+	}
+	return func(time.Time) string {
+		return strconv.FormatFloat(nums[0]+rand.Float64()*(nums[1]-nums[0]), 'f', decimals, 64)
+	}, nil
+}
+
+func durationFunc(args []string) (func(time.Time) string, error) {
+	if len(args) == 0 {
+		return func(time.Time) string { return log.RandDuration() }, nil
+	}
+	d, err := distribution(args)
+	if err != nil {
+		return nil, err
+	}
+	return func(time.Time) string {
+		return formatDuration(time.Duration(math.Max(d.Sample(), 0) * float64(time.Second)))
+	}, nil
+}
+
+func numberFunc(args []string) (func(time.Time) string, error) {
+	d, err := distribution(args)
+	if err != nil {
+		return nil, err
+	}
+	return func(time.Time) string { return strconv.FormatInt(int64(math.Round(d.Sample())), 10) }, nil
+}
+
+// distribution parses a distribution name followed by its parameters, durations are in seconds.
+func distribution(args []string) (dist.Distribution, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("needs a distribution")
+	}
+	params := make([]float64, len(args)-1)
+	for i, arg := range args[1:] {
+		v, err := dist.ParseParam(arg)
+		if err != nil {
+			return nil, err
+		}
+		params[i] = v
+	}
+	return dist.New(args[0], params...)
+}
+
+func seqFunc(args []string) (func(time.Time) string, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("needs a length")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return func(time.Time) string { return log.RandSeq(n) }, nil
+}
+
+func parseFloats(args []string) ([]float64, error) {
+	out := make([]float64, len(args))
+	for i, arg := range args {
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// formatDuration rounds durations the way services usually log them.
+func formatDuration(d time.Duration) string {
+	if d >= time.Millisecond {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// compileAction compiles the content of an action of a template of service, which error uses
+// when it has no arguments.
+func compileAction(action, service string) (func(time.Time) string, error) {
+	args, err := splitArgs(action)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty action")
+	}
+	if strings.HasPrefix(strings.TrimSpace(action), `"`) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("unexpected arguments after string %q", args[0])
+		}
+		return func(time.Time) string { return args[0] }, nil
+	}
+	fn, ok := funcs[args[0]]
+	if !ok {
+		names := make([]string, 0, len(funcs))
+		for name := range funcs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown function %q, expected one of %s", args[0], strings.Join(names, ", "))
+	}
+	if args[0] == "error" && len(args) == 1 && service != "" {
+		args = append(args, service)
+	}
+	out, err := fn(args[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// splitArgs splits an action on spaces, quoted strings are unquoted.
+func splitArgs(action string) ([]string, error) {
+	var args []string
+	for i := 0; i < len(action); {
+		switch {
+		case action[i] == ' ' || action[i] == '\t':
+			i++
+		case action[i] == '"':
+			end := quoteEnd(action, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(action[i : end+1])
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, s)
+			i = end + 1
+		default:
+			end := strings.IndexAny(action[i:], " \t")
+			if end < 0 {
+				end = len(action) - i
+			}
+			args = append(args, action[i:i+end])
+			i += end
+		}
+	}
+	return args, nil
+}
+
+// quoteEnd returns the index of the quote closing the string starting at start, or -1.
+func quoteEnd(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// actionEnd returns the index of the }} closing the action that starts at start, or -1.
+func actionEnd(s string, start int) int {
+	for i := start; i < len(s)-1; i++ {
+		switch {
+		case s[i] == '"':
+			if i = quoteEnd(s, i); i < 0 {
+				return -1
+			}
+		case s[i] == '}' && s[i+1] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// escapeActions makes text render as is.
+func escapeActions(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
@@ -0,0 +1,103 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/catalog"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActions(t *testing.T) {
+	a := assert.New(t)
+	ts := time.Date(2024, 9, 13, 10, 0, 0, 123456789, time.UTC)
+	for pattern, expected := range map[string]string{
+		`ts={{ts}}`:                                               `^ts=2024-09-13T10:00:00.123456789Z$`,
+		`ts={{ts "rfc3339"}}`:                                     `^ts=2024-09-13T10:00:00Z$`,
+		`ts={{ts unixms}}`:                                        `^ts=1726221600123$`,
+		`[{{ts "apache"}}]`:                                       `^\[13/Sep/2024:10:00:00 \+0000\]$`,
+		`{{ts "15:04"}}`:                                          `^10:00$`,
+		`caller={{pick "a.go:1" "b.go:2"}}`:                       `^caller=(a\.go:1|b\.go:2)$`,
+		`n={{int 5 7}} f={{float 0 1 3}}`:                         `^n=[5-7] f=0\.\d{3}$`,
+		`duration={{duration "lognormal" 50ms 2}}`:                `^duration=[\d.]+(µs|ms|s|m\d+(\.\d+)?s)$`,
+		`d={{duration uniform 1s 2s}}`:                            `^d=(1(\.\d+)?s|2s)$`,
+		`b={{number pareto 100 1.5}} h={{number histogram 10 1}}`: `^b=\d+ h=([0-9]|10)$`,
+		`d={{duration exponential 10ms}} {{duration}}`:            `^d=\S+ \S+$`,
+		`user={{user}} org={{org}} id={{seq 4}}`:                  `^user=\d{5} org=\d+ id=[a-z0-9]{4}$`,
+		`ip={{ip}} uuid={{uuid}}`:                                 `^ip=\d+\.\d+\.\d+\.\d+ uuid=[0-9a-f-]{36}$`,
+		`err="{{error "web"}}" uri={{uri}} file={{file}}`:         `^err=".+" uri=/api/\S+ file=\S+$`,
+		`literal {{"{{"}}ts}} <IP>`:                               `^literal \{\{ts\}\} \d+\.\d+\.\d+\.\d+$`,
+		`{{ pick "}}" }}`:                                         `^\}\}$`,
+		`level=info msg="started" {{word}} {{ts "unix"}}`:         `^level=info msg="started" \S+ 1726221600$`,
+	} {
+		a.Regexp(expected, render(t, Template{Pattern: pattern}, ts), pattern)
+	}
+}
+
+func TestActionErrors(t *testing.T) {
+	a := assert.New(t)
+	for pattern, expected := range map[string]string{
+		`{{ts`:                      "unterminated action",
+		`{{}}`:                      "empty action",
+		`{{nope}}`:                  `unknown function "nope"`,
+		`{{pick}}`:                  "pick: needs at least one value",
+		`{{int 5 1}}`:               "int: max 1 is lower than min 5",
+		`{{int a 1}}`:               "int: strconv.Atoi",
+		`{{duration "gamma" 1s 2}}`: `duration: unknown distribution "gamma"`,
+		`{{duration lognormal 1s}}`: "duration: lognormal: unexpected number of parameters 1",
+		`{{number}}`:                "number: needs a distribution",
+		`{{number pareto 1 x}}`:     `number: invalid parameter "x"`,
+		`{{user 1}}`:                "user: takes no arguments",
+		`{{ error }}`:               "error: needs a service",
+		`{{pick "a}}`:               "unterminated action",
+		`{{"a" "b"}}`:               "unexpected arguments",
+	} {
+		_, err := Template{Pattern: pattern}.Compile()
+		a.ErrorContains(err, expected, pattern)
+	}
+}
+
+func TestLearnEscapesActions(t *testing.T) {
+	learner := NewLearner(LearnOptions{})
+	learner.Add(log.Entry{Labels: map[string]string{"service_name": "web"}, Line: "rendering {{.Name}} took 5ms"})
+	tpl := learner.Scenario().Services[0].Templates[0]
+	assert.Regexp(t, `^rendering \{\{\.Name\}\} took \d+ms$`, render(t, tpl, time.Now()))
+}
+
+func TestExampleScenarios(t *testing.T) {
+	s, err := Load("../scenarios/tempo.json")
+	require.NoError(t, err)
+	templates, err := s.Services[0].Compile()
+	require.NoError(t, err)
+	for _, tpl := range templates {
+		assert.Contains(t, tpl.Render(time.Now()), "level="+string(tpl.Level))
+	}
+
+	s, err = Load("../scenarios/dependencies.json")
+	require.NoError(t, err)
+	for _, svc := range s.Services {
+		errs, deps, err := svc.compileErrors()
+		require.NoError(t, err)
+		assert.NotEmpty(t, errs)
+		assert.Len(t, deps, len(svc.DependsOn))
+		for _, templates := range deps {
+			for _, tpl := range templates {
+				assert.NotContains(t, tpl.Render(time.Now()), "{{")
+			}
+		}
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	a := assert.New(t)
+	templates, err := Service{Name: "checkout", Templates: []Template{{Pattern: `err="{{ error }}"`}}}.Compile()
+	require.NoError(t, err)
+	now := time.Now()
+	var messages []string
+	for _, e := range catalog.For("checkout", now).Errors(now) {
+		messages = append(messages, `err="`+e.Message+`"`)
+	}
+	a.Contains(messages, templates[0].Render(now), "the error comes from the catalog of the service")
+}
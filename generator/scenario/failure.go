@@ -0,0 +1,219 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/load"
+	"github.com/prometheus/common/model"
+)
+
+// Failure makes a service fail, its dependents start failing too after Propagation.Delay.
+type Failure struct {
+	Service model.LabelValue `json:"service"`
+	// After is the time between the start of the generator and the failure.
+	After Duration `json:"after,omitempty"`
+	// For is how long the failure lasts, it lasts until recovered when zero.
+	For Duration `json:"for,omitempty"`
+	// Rate is the share of the service's lines that are errors, 1 by default.
+	Rate float64 `json:"rate,omitempty"`
+}
+
+// Propagation describes how failures reach the dependents of a failed service.
+type Propagation struct {
+	// Delay is the time it takes for the dependents to notice, 10s by default. Clients first
+	// wait for their timeouts before logging errors.
+	Delay Duration `json:"delay,omitempty"`
+	// Rate is the share of a dependency's errors that a dependent turns into its own errors, 0.8
+	// by default. Retries and caches hide some of them.
+	Rate float64 `json:"rate,omitempty"`
+}
+
+const (
+	defaultPropagationDelay = 10 * time.Second
+	defaultPropagationRate  = 0.8
+)
+
+// outage is a failure with absolute times.
+type outage struct {
+	start, end time.Time
+	rate       float64
+}
+
+func (o outage) active(t time.Time) bool {
+	return !t.Before(o.start) && (o.end.IsZero() || t.Before(o.end))
+}
+
+// Failures tracks the failed services of a scenario and propagates their failures along the
+// dependency graph. It is safe for concurrent use.
+type Failures struct {
+	deps  map[model.LabelValue][]model.LabelValue
+	delay time.Duration
+	rate  float64
+	// start is when the scenario started, rollouts are relative to it.
+	start time.Time
+
+	mtx     sync.RWMutex
+	outages map[model.LabelValue][]outage
+}
+
+// NewFailures creates the failures of a scenario started at start, with its configured failures.
+func NewFailures(s *Scenario, start time.Time) *Failures {
+	f := &Failures{
+		deps:    map[model.LabelValue][]model.LabelValue{},
+		delay:   time.Duration(s.Propagation.Delay),
+		rate:    s.Propagation.Rate,
+		outages: map[model.LabelValue][]outage{},
+		start:   start,
+	}
+	if f.delay <= 0 {
+		f.delay = defaultPropagationDelay
+	}
+	if f.rate <= 0 {
+		f.rate = defaultPropagationRate
+	}
+	for _, svc := range s.Services {
+		f.deps[svc.Name] = append(f.deps[svc.Name], svc.DependsOn...)
+	}
+	for _, failure := range s.Failures {
+		f.Fail(failure.Service, start.Add(time.Duration(failure.After)), time.Duration(failure.For), failure.Rate)
+	}
+	return f
+}
+
+// Fail makes service fail from start for d, or until recovered when d is zero. Rates outside
+// (0, 1] mean every line fails.
+func (f *Failures) Fail(service model.LabelValue, start time.Time, d time.Duration, rate float64) {
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	o := outage{start: start, rate: rate}
+	if d > 0 {
+		o.end = start.Add(d)
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.outages[service] = append(f.outages[service], o)
+}
+
+// Recover ends the failures of service at t.
+func (f *Failures) Recover(service model.LabelValue, t time.Time) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	outages := f.outages[service][:0]
+	for _, o := range f.outages[service] {
+		if o.start.After(t) {
+			continue
+		}
+		if o.end.IsZero() || o.end.After(t) {
+			o.end = t
+		}
+		outages = append(outages, o)
+	}
+	f.outages[service] = outages
+}
+
+// State returns the error rate of a service at t and the cause of the errors: the service
+// itself when it failed, or the dependency through which a failure reached it.
+func (f *Failures) State(service model.LabelValue, t time.Time) (model.LabelValue, float64) {
+	if f == nil {
+		return "", 0
+	}
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.state(service, t, map[model.LabelValue]bool{})
+}
+
+func (f *Failures) state(service model.LabelValue, t time.Time, seen map[model.LabelValue]bool) (model.LabelValue, float64) {
+	if seen[service] {
+		return "", 0
+	}
+	seen[service] = true
+	defer delete(seen, service)
+
+	var cause model.LabelValue
+	var rate float64
+	for _, o := range f.outages[service] {
+		if o.active(t) && o.rate > rate {
+			cause, rate = service, o.rate
+		}
+	}
+	for _, dep := range f.deps[service] {
+		if _, r := f.state(dep, t.Add(-f.delay), seen); r*f.rate > rate {
+			cause, rate = dep, r*f.rate
+		}
+	}
+	return cause, rate
+}
+
+// status is a failure as reported by the control API.
+type status struct {
+	Service model.LabelValue `json:"service"`
+	Cause   model.LabelValue `json:"cause"`
+	Rate    float64          `json:"rate"`
+}
+
+// ServeHTTP is the control API of the failures:
+//
+//	GET  /failures                                  the failing services and their error rates
+//	POST /failures/fail?service=db&rate=0.5&for=5m  makes a service fail, rate and for are optional
+//	POST /failures/recover?service=db               ends the failures of a service
+func (f *Failures) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := load.Now()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/failures":
+		f.mtx.RLock()
+		out := []status{}
+		for service := range f.deps {
+			if cause, rate := f.state(service, now, map[model.LabelValue]bool{}); rate > 0 {
+				out = append(out, status{Service: service, Cause: cause, Rate: rate})
+			}
+		}
+		f.mtx.RUnlock()
+		sort.Slice(out, func(i, j int) bool { return out[i].Service < out[j].Service })
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	case r.Method == http.MethodPost && (r.URL.Path == "/failures/fail" || r.URL.Path == "/failures/recover"):
+		service := model.LabelValue(r.URL.Query().Get("service"))
+		if _, ok := f.deps[service]; !ok {
+			http.Error(w, fmt.Sprintf("unknown service %q", service), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/failures/recover" {
+			f.Recover(service, now)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rate, d, err := parseFailure(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.Fail(service, now, d, rate)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func parseFailure(r *http.Request) (float64, time.Duration, error) {
+	var rate float64
+	var d time.Duration
+	var err error
+	if s := r.URL.Query().Get("rate"); s != "" {
+		if rate, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid rate: %w", err)
+		}
+	}
+	if s := r.URL.Query().Get("for"); s != "" {
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, 0, fmt.Errorf("invalid duration: %w", err)
+		}
+	}
+	return rate, d, nil
+}
@@ -0,0 +1,185 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var graph = &Scenario{
+	Services: []Service{
+		{Name: "gateway", DependsOn: []model.LabelValue{"api"}},
+		{Name: "api", DependsOn: []model.LabelValue{"cache", "db"}},
+		{Name: "cache"},
+		{Name: "db"},
+	},
+	Propagation: Propagation{Delay: Duration(10 * time.Second), Rate: 0.5},
+}
+
+func TestFailuresPropagate(t *testing.T) {
+	a := assert.New(t)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s := *graph
+	s.Failures = []Failure{{Service: "db", After: Duration(time.Minute), For: Duration(5 * time.Minute)}}
+	f := NewFailures(&s, start)
+
+	state := func(service model.LabelValue, after time.Duration) (model.LabelValue, float64) {
+		return f.State(service, start.Add(after))
+	}
+	_, rate := state("db", 59*time.Second)
+	a.Zero(rate)
+
+	cause, rate := state("db", time.Minute)
+	a.Equal(model.LabelValue("db"), cause)
+	a.Equal(1.0, rate)
+	_, rate = state("api", time.Minute+5*time.Second)
+	a.Zero(rate, "dependents notice after the propagation delay")
+
+	cause, rate = state("api", time.Minute+10*time.Second)
+	a.Equal(model.LabelValue("db"), cause)
+	a.Equal(0.5, rate)
+	_, rate = state("gateway", time.Minute+15*time.Second)
+	a.Zero(rate)
+	cause, rate = state("gateway", time.Minute+20*time.Second)
+	a.Equal(model.LabelValue("api"), cause, "the cause is the dependency the failure comes from")
+	a.Equal(0.25, rate)
+	_, rate = state("cache", 2*time.Minute)
+	a.Zero(rate)
+
+	_, rate = state("db", 6*time.Minute)
+	a.Zero(rate)
+	_, rate = state("gateway", 6*time.Minute+19*time.Second)
+	a.Equal(0.25, rate, "dependents recover after the delay too")
+
+	f.Fail("api", start.Add(10*time.Minute), 0, 0.9)
+	cause, rate = state("api", time.Hour)
+	a.Equal(model.LabelValue("api"), cause)
+	a.Equal(0.9, rate)
+	f.Recover("api", start.Add(time.Hour))
+	_, rate = state("api", time.Hour)
+	a.Zero(rate)
+	_, rate = state("gateway", time.Hour+5*time.Second)
+	a.Equal(0.45, rate)
+
+	var none *Failures
+	_, rate = none.State("api", start)
+	a.Zero(rate)
+}
+
+func TestFailuresCycle(t *testing.T) {
+	f := NewFailures(&Scenario{
+		Services: []Service{{Name: "a", DependsOn: []model.LabelValue{"b"}}, {Name: "b", DependsOn: []model.LabelValue{"a"}}},
+		Failures: []Failure{{Service: "a"}},
+	}, time.Now().Add(-time.Hour))
+	cause, rate := f.State("b", time.Now())
+	assert.Equal(t, model.LabelValue("a"), cause)
+	assert.Equal(t, 0.8, rate)
+}
+
+func TestFailuresControlAPI(t *testing.T) {
+	a := assert.New(t)
+	s := *graph
+	s.Propagation.Delay = Duration(time.Nanosecond)
+	srv := httptest.NewServer(NewFailures(&s, time.Now()))
+	defer srv.Close()
+
+	post := func(path string) int {
+		resp, err := http.Post(srv.URL+path, "", nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	a.Equal(http.StatusNoContent, post("/failures/fail?service=cache&rate=0.5&for=1h"))
+	a.Equal(http.StatusBadRequest, post("/failures/fail?service=nope"))
+	a.Equal(http.StatusBadRequest, post("/failures/fail?service=db&for=soon"))
+	a.Equal(http.StatusNotFound, post("/failures/explode?service=db"))
+
+	var got []status
+	resp, err := http.Get(srv.URL + "/failures")
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	_ = resp.Body.Close()
+	a.Equal([]status{
+		{Service: "api", Cause: "cache", Rate: 0.25},
+		{Service: "cache", Cause: "cache", Rate: 0.5},
+		{Service: "gateway", Cause: "api", Rate: 0.125},
+	}, got)
+
+	a.Equal(http.StatusNoContent, post("/failures/recover?service=cache"))
+	resp, err = http.Get(srv.URL + "/failures")
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	_ = resp.Body.Close()
+	a.Empty(got)
+}
+
+func TestRunWithFailures(t *testing.T) {
+	a := assert.New(t)
+	svc := Service{
+		Name:      "api",
+		Interval:  Interval{Mean: Duration(time.Millisecond)},
+		Templates: []Template{{Pattern: "ok", Level: log.INFO, Weight: 1}},
+		DependsOn: []model.LabelValue{"db"},
+	}
+	f := NewFailures(&Scenario{
+		Services:    []Service{svc, {Name: "db"}},
+		Failures:    []Failure{{Service: "db"}},
+		Propagation: Propagation{Delay: Duration(time.Nanosecond), Rate: 1},
+	}, time.Now())
+
+	var (
+		mtx   sync.Mutex
+		lines = map[model.LabelValue][]string{}
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.RunWithFailures(ctx, log.NewAppLogger(model.LabelSet{"service_name": "api"}, log.LoggerFunc(func(labels model.LabelSet, _ time.Time, msg string, _ push.LabelsAdapter) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		lines[labels["level"]] = append(lines[labels["level"]], msg)
+		return nil
+	})), nil, f)
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(lines[log.ERROR]) > 0 && len(lines[log.WARN]) > 0
+	}, 5*time.Second, time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	a.Empty(lines[log.INFO])
+	for _, line := range lines[log.ERROR] {
+		a.Contains(line, "upstream=db")
+	}
+}
+
+func TestLoadDependencies(t *testing.T) {
+	for content, msg := range map[string]string{
+		`{"services": [{"name": "api", "templates": [{"pattern": "x"}], "dependsOn": ["db"]}]}`:                                                           `depends on unknown service "db"`,
+		`{"services": [{"name": "api", "templates": [{"pattern": "x"}]}], "failures": [{"service": "db"}]}`:                                               `failure of unknown service "db"`,
+		`{"services": [{"name": "api", "templates": [{"pattern": "x"}], "errors": [{"pattern": "{{nope}}"}]}]}`:                                           `unknown function "nope"`,
+		`{"services": [{"name": "api", "templates": [{"pattern": "x"}], "dependsOn": ["api"]}]}`:                                                          `service api depends on itself`,
+		`{"services": [{"name": "api", "templates": [{"pattern": "x"}], "dependencyErrors": [{"pattern": "{{int}}"}]}]}`:                                  `int: needs min and max`,
+		`{"services": [{"name": "api", "templates": [{"pattern": "x"}], "rollout": {"to": "v2"}}]}`:                                                       `rollout of service api needs from and to versions`,
+		`{"services": [{"name": "api", "templates": [{"pattern": "x"}], "rollout": {"from": "v1", "to": "v2", "templates": [{"pattern": "{{nope}}"}]}}]}`: `rollout: service api: `,
+	} {
+		path := filepath.Join(t.TempDir(), "scenario.json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		_, err := Load(path)
+		assert.ErrorContains(t, err, msg)
+		assert.True(t, strings.HasPrefix(err.Error(), path), err.Error())
+	}
+}
@@ -0,0 +1,305 @@
+package scenario
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/ingest"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// LearnOptions tunes template clustering.
+type LearnOptions struct {
+	// Similarity is the share of tokens a line must have in common with a template to join it, 0.4 by default.
+	Similarity float64
+	// PrefixTokens is the number of leading tokens that must match exactly, 1 by default.
+	PrefixTokens int
+	// MaxValues is the number of distinct values kept for word slots, 20 by default.
+	MaxValues int
+	// Namespace is used for services without a namespace label, "learned" by default.
+	Namespace model.LabelValue
+}
+
+func (o LearnOptions) withDefaults() LearnOptions {
+	if o.Similarity <= 0 {
+		o.Similarity = 0.4
+	}
+	if o.PrefixTokens <= 0 {
+		o.PrefixTokens = 1
+	}
+	if o.MaxValues <= 0 {
+		o.MaxValues = 20
+	}
+	if o.Namespace == "" {
+		o.Namespace = "learned"
+	}
+	return o
+}
+
+// Learner clusters log lines into templates the same way Drain does: lines are grouped by token
+// count and leading tokens, then join the most similar template or start a new one. Tokens that
+// differ between lines of a template become word slots, known variables are typed slots.
+type Learner struct {
+	opts     LearnOptions
+	services map[model.LabelValue]*learnedService
+	order    []model.LabelValue
+}
+
+type learnedService struct {
+	namespace model.LabelValue
+	times     map[string][]time.Time
+	groups    map[string][]*cluster
+	clusters  []*cluster
+}
+
+type cluster struct {
+	positions []*position
+	count     int
+	levels    map[model.LabelValue]int
+}
+
+// position is a token of a template. A key= prefix shared by all values is kept when it varies.
+type position struct {
+	masked string
+	key    string
+	varies bool
+	values []string
+	slots  []*slotStats
+}
+
+type slotStats struct {
+	typ      SlotType
+	min, max float64
+	decimals int
+	width    int
+	layout   string
+}
+
+// NewLearner creates an empty Learner.
+func NewLearner(opts LearnOptions) *Learner {
+	return &Learner{opts: opts.withDefaults(), services: map[model.LabelValue]*learnedService{}}
+}
+
+// Add learns from a single entry, its service_name label picks the service.
+func (l *Learner) Add(e log.Entry) {
+	name := model.LabelValue(e.Labels["service_name"])
+	if name == "" {
+		name = "unknown_service"
+	}
+	svc, ok := l.services[name]
+	if !ok {
+		svc = &learnedService{
+			namespace: model.LabelValue(e.Labels["namespace"]),
+			times:     map[string][]time.Time{},
+			groups:    map[string][]*cluster{},
+		}
+		if svc.namespace == "" {
+			svc.namespace = l.opts.Namespace
+		}
+		l.services[name] = svc
+		l.order = append(l.order, name)
+	}
+	// Generators run per stream and level is added by the AppLogger, so gaps are measured per stream without it.
+	stream := e.LabelSet()
+	delete(stream, "level")
+	svc.times[stream.String()] = append(svc.times[stream.String()], e.Timestamp)
+
+	level := model.LabelValue(e.Labels["level"])
+	if level == "" {
+		level = ingest.DetectLevel(e.Line)
+	}
+	if level == "" {
+		level = log.INFO
+	}
+
+	// Multiline entries are learned from their first line.
+	line, _, _ := strings.Cut(e.Line, "\n")
+	fields := strings.Fields(line)
+	tokens := make([]maskedToken, len(fields))
+	for i, f := range fields {
+		tokens[i] = maskToken(f)
+	}
+
+	key := l.groupKey(tokens)
+	var best *cluster
+	bestScore := -1.0
+	for _, c := range svc.groups[key] {
+		if score := c.similarity(tokens); score >= l.opts.Similarity && score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	if best == nil {
+		best = &cluster{positions: make([]*position, len(tokens)), levels: map[model.LabelValue]int{}}
+		for i, t := range tokens {
+			best.positions[i] = &position{masked: t.masked}
+			if key, _, ok := strings.Cut(t.masked, "="); ok {
+				best.positions[i].key = key + "="
+			}
+			for _, typ := range t.types {
+				best.positions[i].slots = append(best.positions[i].slots, &slotStats{typ: typ, min: math.Inf(1), max: math.Inf(-1)})
+			}
+		}
+		svc.groups[key] = append(svc.groups[key], best)
+		svc.clusters = append(svc.clusters, best)
+	}
+	best.add(tokens, fields, level, l.opts.MaxValues)
+}
+
+func (l *Learner) groupKey(tokens []maskedToken) string {
+	key := []string{strconv.Itoa(len(tokens))}
+	for i := 0; i < len(tokens) && i < l.opts.PrefixTokens; i++ {
+		t := tokens[i].masked
+		if strings.ContainsAny(t, "0123456789") {
+			t = "<*>"
+		}
+		key = append(key, t)
+	}
+	return strings.Join(key, " ")
+}
+
+func (c *cluster) similarity(tokens []maskedToken) float64 {
+	if len(tokens) == 0 {
+		return 1
+	}
+	same := 0
+	for i, t := range tokens {
+		if c.positions[i].varies || c.positions[i].masked == t.masked {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+func (c *cluster) add(tokens []maskedToken, raw []string, level model.LabelValue, maxValues int) {
+	c.count++
+	c.levels[level]++
+	for i, t := range tokens {
+		p := c.positions[i]
+		if !p.varies && p.masked != t.masked {
+			p.varies = true
+			p.slots = nil
+		}
+		if !strings.HasPrefix(raw[i], p.key) {
+			p.key = ""
+		}
+		if len(p.values) < maxValues && !contains(p.values, raw[i]) {
+			p.values = append(p.values, raw[i])
+		}
+		for j, s := range p.slots {
+			s.observe(t.values[j])
+		}
+	}
+}
+
+func (s *slotStats) observe(value string) {
+	var v float64
+	switch s.typ {
+	case SlotTimestamp:
+		s.layout = timestampLayout(value)
+		return
+	case SlotDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return
+		}
+		v = d.Seconds()
+	case SlotNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return
+		}
+		whole, frac, _ := strings.Cut(value, ".")
+		if len(frac) > s.decimals {
+			s.decimals = len(frac)
+		}
+		if len(whole) > 1 && whole[0] == '0' && len(whole) > s.width {
+			s.width = len(whole)
+		}
+		v = n
+	default:
+		return
+	}
+	s.min = math.Min(s.min, v)
+	s.max = math.Max(s.max, v)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Scenario returns the services learned so far, templates are ordered by frequency.
+func (l *Learner) Scenario() *Scenario {
+	s := &Scenario{}
+	for _, name := range l.order {
+		svc := l.services[name]
+		out := Service{Namespace: svc.namespace, Name: name, Interval: interval(svc.times)}
+		for _, c := range svc.clusters {
+			out.Templates = append(out.Templates, c.template())
+		}
+		sort.SliceStable(out.Templates, func(i, j int) bool { return out.Templates[i].Weight > out.Templates[j].Weight })
+		s.Services = append(s.Services, out)
+	}
+	return s
+}
+
+func (c *cluster) template() Template {
+	tpl := Template{Weight: float64(c.count), Level: log.INFO}
+	levelCount := 0
+	for level, n := range c.levels {
+		if n > levelCount || (n == levelCount && level < tpl.Level) {
+			tpl.Level, levelCount = level, n
+		}
+	}
+	tokens := make([]string, len(c.positions))
+	for i, p := range c.positions {
+		if p.varies {
+			values := make([]string, len(p.values))
+			for j, v := range p.values {
+				values[j] = strings.TrimPrefix(v, p.key)
+			}
+			tokens[i] = escapeActions(p.key) + SlotWord.Placeholder()
+			tpl.Slots = append(tpl.Slots, Slot{Type: SlotWord, Values: values})
+			continue
+		}
+		tokens[i] = escapeActions(p.masked)
+		for _, s := range p.slots {
+			slot := Slot{Type: s.typ, Layout: s.layout, Decimals: s.decimals, Width: s.width}
+			if !math.IsInf(s.min, 0) {
+				slot.Min, slot.Max = s.min, s.max
+			}
+			tpl.Slots = append(tpl.Slots, slot)
+		}
+	}
+	tpl.Pattern = strings.Join(tokens, " ")
+	return tpl
+}
+
+// interval computes the mean and standard deviation of the gaps between entries of the same stream.
+func interval(streams map[string][]time.Time) Interval {
+	var sum, sumSq, n float64
+	for _, times := range streams {
+		sorted := append([]time.Time(nil), times...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+		for i := 1; i < len(sorted); i++ {
+			gap := float64(sorted[i].Sub(sorted[i-1]))
+			sum += gap
+			sumSq += gap * gap
+			n++
+		}
+	}
+	if n == 0 {
+		return Interval{Mean: Duration(defaultInterval)}
+	}
+	mean := sum / n
+	variance := math.Max(sumSq/n-mean*mean, 0)
+	return Interval{Mean: Duration(mean), StdDev: Duration(math.Sqrt(variance))}
+}
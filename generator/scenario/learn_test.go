@@ -0,0 +1,122 @@
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLearn(t *testing.T) {
+	a := assert.New(t)
+	learner := NewLearner(LearnOptions{})
+	start := time.Date(2024, 9, 13, 10, 0, 0, 0, time.UTC)
+	users := []string{"alice", "bob", "carol"}
+	for i := 0; i < 30; i++ {
+		ts := start.Add(time.Duration(i) * time.Second)
+		learner.Add(log.Entry{
+			Timestamp: ts,
+			Labels:    map[string]string{"service_name": "api"},
+			Line:      fmt.Sprintf("ts=%s level=info msg=\"request done\" user=%s took=%dms from=10.0.0.%d", ts.Format(time.RFC3339), users[i%3], 10+i, i),
+		})
+		if i%3 == 0 {
+			learner.Add(log.Entry{
+				Timestamp: ts,
+				Labels:    map[string]string{"service_name": "api"},
+				Line:      fmt.Sprintf("ts=%s level=error msg=\"upstream failed\" request_id=%s code=%d", ts.Format(time.RFC3339), "0b7c7a42-3f52-4b0c-9a47-3d3f8b8c0b1a", 500+i%4),
+			})
+		}
+	}
+	learner.Add(log.Entry{Timestamp: start, Labels: map[string]string{"service_name": "db", "namespace": "storage"}, Line: "checkpoint starting"})
+	// Other streams of a service don't shorten the interval.
+	learner.Add(log.Entry{Timestamp: start.Add(500 * time.Millisecond), Labels: map[string]string{"service_name": "api", "pod": "api-2"}, Line: "ts=2024-09-13T10:00:00Z level=info msg=\"request done\" user=dave took=12ms from=10.0.0.1"})
+
+	s := learner.Scenario()
+	require.Len(t, s.Services, 2)
+
+	api := s.Services[0]
+	a.Equal(Service{Namespace: "learned", Name: "api"}, Service{Namespace: api.Namespace, Name: api.Name})
+	require.Len(t, api.Templates, 2)
+
+	done := api.Templates[0]
+	a.Equal(`ts=<TS> level=info msg="request done" user=<WORD> took=<DURATION> from=<IP>`, done.Pattern)
+	a.Equal(log.INFO, done.Level)
+	a.Equal(31.0, done.Weight)
+	a.Equal([]Slot{
+		{Type: SlotTimestamp, Layout: "2006-01-02T15:04:05Z07:00"},
+		{Type: SlotWord, Values: []string{"alice", "bob", "carol", "dave"}},
+		{Type: SlotDuration, Min: 0.01, Max: 0.039},
+		{Type: SlotIP},
+	}, done.Slots)
+
+	failed := api.Templates[1]
+	a.Equal(`ts=<TS> level=error msg="upstream failed" request_id=<UUID> code=<NUM>`, failed.Pattern)
+	a.Equal(log.ERROR, failed.Level)
+	a.Equal(10.0, failed.Weight)
+	a.Equal(Slot{Type: SlotNumber, Min: 500, Max: 503}, failed.Slots[2])
+
+	// 40 lines over 29 seconds
+	a.InDelta(float64(29*time.Second/39), float64(api.Interval.Mean), float64(time.Millisecond))
+	a.Positive(api.Interval.StdDev)
+
+	db := s.Services[1]
+	a.Equal(Service{
+		Namespace: "storage",
+		Name:      "db",
+		Interval:  Interval{Mean: Duration(time.Second)},
+		Templates: []Template{{Pattern: "checkpoint starting", Level: log.INFO, Weight: 1}},
+	}, db)
+}
+
+func TestLearnSeparatesShapes(t *testing.T) {
+	a := assert.New(t)
+	learner := NewLearner(LearnOptions{})
+	for _, line := range []string{
+		"GET /api/users 200 12ms",
+		"GET /api/orders 200 15ms",
+		"POST /api/orders 201 40ms",
+		"connection reset by peer",
+		"connection closed by peer",
+	} {
+		learner.Add(log.Entry{Labels: map[string]string{"service_name": "web"}, Line: line})
+	}
+	templates := learner.Scenario().Services[0].Templates
+	require.Len(t, templates, 3)
+	a.Equal("GET <WORD> <NUM> <DURATION>", templates[0].Pattern)
+	a.Equal([]string{"/api/users", "/api/orders"}, templates[0].Slots[0].Values)
+	a.Equal("connection <WORD> by peer", templates[1].Pattern)
+	a.Equal("POST /api/orders <NUM> <DURATION>", templates[2].Pattern)
+
+	learner.Add(log.Entry{Labels: map[string]string{"service_name": "clock"}, Line: "at 09:05"})
+	learner.Add(log.Entry{Labels: map[string]string{"service_name": "clock"}, Line: "at 13:45"})
+	a.Equal([]Slot{{Type: SlotNumber, Min: 9, Max: 13, Width: 2}, {Type: SlotNumber, Min: 5, Max: 45, Width: 2}}, learner.Scenario().Services[1].Templates[0].Slots)
+}
+
+func TestLoad(t *testing.T) {
+	a := assert.New(t)
+	s := &Scenario{Services: []Service{{
+		Namespace: "shop",
+		Name:      "cart",
+		Interval:  Interval{Mean: Duration(1500 * time.Millisecond), StdDev: Duration(time.Second)},
+		Templates: []Template{{Pattern: "added <NUM> items", Level: log.INFO, Weight: 3, Slots: []Slot{{Type: SlotNumber, Min: 1, Max: 5}}}},
+	}}}
+	var buf bytes.Buffer
+	require.NoError(t, s.Write(&buf))
+	a.Contains(buf.String(), `"mean": "1.5s"`)
+
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	a.Equal(s, loaded)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"services":[{"name":"cart"}]}`), 0o644))
+	_, err = Load(path)
+	a.ErrorContains(err, "service cart has no templates")
+}
@@ -0,0 +1,174 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/grafana/explore-logs/generator/load"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/explore-logs/generator/rollout"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultErrors are logged by failed services without errors of their own.
+var DefaultErrors = []Template{
+	{Pattern: `level=error ts={{ts}} msg="request failed" error="{{error}}" duration={{duration}}`, Level: log.ERROR, Weight: 3},
+	{Pattern: `level=error ts={{ts}} msg="health check failed" error="{{error}}"`, Level: log.ERROR, Weight: 1},
+}
+
+// DefaultDependencyErrors are the timeouts, retries and 5xx of services calling a failed dependency.
+var DefaultDependencyErrors = []Template{
+	{Pattern: `level=error ts={{ts}} msg="request failed" upstream={{dependency}} error="context deadline exceeded" duration={{duration "normal" 5s 50ms}}`, Level: log.ERROR, Weight: 4},
+	{Pattern: `level=warn ts={{ts}} msg="retrying request" upstream={{dependency}} attempt={{int 1 3}} backoff={{duration "lognormal" 200ms 0.5}}`, Level: log.WARN, Weight: 3},
+	{Pattern: `level=error ts={{ts}} msg="upstream returned an error" upstream={{dependency}} status={{pick 500 502 503 504}}`, Level: log.ERROR, Weight: 3},
+}
+
+// Compile compiles all templates of the service.
+func (s Service) Compile() ([]*CompiledTemplate, error) {
+	return compileAll(s.Name, s.Templates)
+}
+
+// compileErrors compiles the error templates of the service, and the dependency errors of each dependency.
+func (s Service) compileErrors() ([]*CompiledTemplate, map[model.LabelValue][]*CompiledTemplate, error) {
+	errs := s.Errors
+	if len(errs) == 0 {
+		errs = DefaultErrors
+	}
+	own, err := compileAll(s.Name, errs)
+	if err != nil {
+		return nil, nil, err
+	}
+	depErrs := s.DependencyErrors
+	if len(depErrs) == 0 {
+		depErrs = DefaultDependencyErrors
+	}
+	deps := make(map[model.LabelValue][]*CompiledTemplate, len(s.DependsOn))
+	for _, dep := range s.DependsOn {
+		if deps[dep], err = compileAll(s.Name, withDependency(depErrs, dep)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(s.DependsOn) == 0 {
+		// Still report invalid templates of services without dependencies.
+		if _, err := compileAll(s.Name, withDependency(depErrs, "dependency")); err != nil {
+			return nil, nil, err
+		}
+	}
+	return own, deps, nil
+}
+
+// withDependency replaces {{dependency}} in the patterns by the name of the dependency.
+func withDependency(templates []Template, dep model.LabelValue) []Template {
+	out := make([]Template, len(templates))
+	for i, tpl := range templates {
+		tpl.Pattern = strings.ReplaceAll(tpl.Pattern, "{{dependency}}", escapeActions(string(dep)))
+		out[i] = tpl
+	}
+	return out
+}
+
+// compileAll compiles templates of a service, their {{error}} actions pick the errors of the service.
+func compileAll(service model.LabelValue, templates []Template) ([]*CompiledTemplate, error) {
+	out := make([]*CompiledTemplate, len(templates))
+	for i, tpl := range templates {
+		c, err := tpl.compile(string(service))
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", service, err)
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+// Run starts generating the service's logs to logger until ctx is done. Templates are expected
+// to be valid, which Load checks.
+func (s Service) Run(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
+	s.RunWithFailures(ctx, logger, metadata, nil)
+}
+
+// RunWithFailures is like Run, but the service logs errors while it or its dependencies fail.
+// Rollouts start relative to the start of failures, or to the call without them.
+func (s Service) RunWithFailures(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter, failures *Failures) {
+	current, err := s.compile()
+	if err != nil {
+		panic(err)
+	}
+	var (
+		stream *rollout.Stream
+		next   compiled
+	)
+	if s.Rollout != nil {
+		if next, err = s.Rollout.next(s).compile(); err != nil {
+			panic(err)
+		}
+		start := load.Now()
+		if failures != nil {
+			start = failures.start
+		}
+		r := s.Rollout.at(start)
+		stream = r.Stream()
+		metadata, next.metadata = r.Metadata(metadata)
+	}
+	current.metadata = metadata
+	go func() {
+		for ctx.Err() == nil {
+			t := load.Now()
+			version := current
+			if stream != nil && stream.Upgraded(t) {
+				version = next
+			}
+			tpl := pick(version.templates)
+			if cause, rate := failures.State(s.Name, t); rate > 0 && rand.Float64() < rate {
+				switch {
+				case cause == s.Name:
+					tpl = pick(version.errs)
+				case len(version.depErrs[cause]) > 0:
+					tpl = pick(version.depErrs[cause])
+				}
+			}
+			logger.LogWithMetadata(tpl.Level, t, tpl.Render(t), version.metadata)
+			load.Sleep(s.Interval.Next())
+		}
+	}()
+}
+
+// compiled are the templates of a version of a service.
+type compiled struct {
+	templates, errs []*CompiledTemplate
+	depErrs         map[model.LabelValue][]*CompiledTemplate
+	metadata        push.LabelsAdapter
+}
+
+func (s Service) compile() (compiled, error) {
+	var (
+		c   compiled
+		err error
+	)
+	if c.templates, err = s.Compile(); err != nil {
+		return c, err
+	}
+	c.errs, c.depErrs, err = s.compileErrors()
+	return c, err
+}
+
+// pick returns a random template according to the template weights.
+func pick(templates []*CompiledTemplate) *CompiledTemplate {
+	var total float64
+	for _, tpl := range templates {
+		total += tpl.Weight
+	}
+	if total <= 0 {
+		return templates[rand.Intn(len(templates))]
+	}
+	r := rand.Float64() * total
+	for _, tpl := range templates {
+		r -= tpl.Weight
+		if r < 0 {
+			return tpl
+		}
+	}
+	return templates[len(templates)-1]
+}
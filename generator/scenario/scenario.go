@@ -0,0 +1,179 @@
+// Package scenario describes synthetic services as data, so new workloads can be generated
+// without writing Go code. Scenarios are JSON files, written by hand with template actions or
+// created by the learn subcommand.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/rollout"
+	"github.com/prometheus/common/model"
+)
+
+// Scenario is a set of services to generate logs for.
+type Scenario struct {
+	Services []Service `json:"services"`
+	// Failures are injected when the generator starts, the control API adds more.
+	Failures    []Failure   `json:"failures,omitempty"`
+	Propagation Propagation `json:"propagation,omitempty"`
+}
+
+// Service generates logs by picking weighted templates at a given interval.
+type Service struct {
+	Namespace model.LabelValue `json:"namespace"`
+	Name      model.LabelValue `json:"name"`
+	// Interval is the time between two lines of a stream.
+	Interval  Interval   `json:"interval"`
+	Templates []Template `json:"templates"`
+	// DependsOn are the names of the services this one calls, their failures reach it.
+	DependsOn []model.LabelValue `json:"dependsOn,omitempty"`
+	// Errors are logged instead of the templates while the service fails, DefaultErrors when empty.
+	Errors []Template `json:"errors,omitempty"`
+	// DependencyErrors are logged while a dependency fails, {{dependency}} is replaced by its
+	// name. DefaultDependencyErrors when empty.
+	DependencyErrors []Template `json:"dependencyErrors,omitempty"`
+	// Rollout moves the service to a new version while the generator runs.
+	Rollout *Rollout `json:"rollout,omitempty"`
+}
+
+// Rollout moves the streams of a service from version From to version To like a canary. Both
+// versions add a version field to the structured metadata.
+type Rollout struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// After is the time between the start of the generator and the rollout.
+	After Duration `json:"after,omitempty"`
+	// Window is how long it takes for all the streams to run the new version, 1h by default.
+	Window Duration `json:"window,omitempty"`
+	// Templates and Errors replace those of the service in the new version, they are kept when
+	// empty.
+	Templates []Template `json:"templates,omitempty"`
+	Errors    []Template `json:"errors,omitempty"`
+}
+
+// next returns the service as it runs in the new version.
+func (r Rollout) next(s Service) Service {
+	if len(r.Templates) > 0 {
+		s.Templates = r.Templates
+	}
+	if len(r.Errors) > 0 {
+		s.Errors = r.Errors
+	}
+	s.Rollout = nil
+	return s
+}
+
+// at returns the rollout of a generator started at start.
+func (r Rollout) at(start time.Time) rollout.Rollout {
+	window := time.Duration(r.Window)
+	if window <= 0 {
+		window = rollout.DefaultWindow
+	}
+	return rollout.Rollout{From: r.From, To: r.To, Start: start.Add(time.Duration(r.After)), Window: window}
+}
+
+// Interval is the distribution of the time between two lines.
+type Interval struct {
+	Mean   Duration `json:"mean"`
+	StdDev Duration `json:"stddev,omitempty"`
+}
+
+// Duration is a time.Duration written as a string like "1.5s" in scenario files.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// defaultInterval is used when a scenario doesn't set one.
+const defaultInterval = time.Second
+
+// Next returns a random wait time. Intervals with a standard deviation follow a log-normal
+// distribution with the same mean, which fits the bursty gaps of real logs.
+func (i Interval) Next() time.Duration {
+	mean := float64(i.Mean)
+	if mean <= 0 {
+		return defaultInterval
+	}
+	if i.StdDev <= 0 {
+		return time.Duration(mean)
+	}
+	cv := float64(i.StdDev) / mean
+	sigma := math.Sqrt(math.Log(1 + cv*cv))
+	mu := math.Log(mean) - sigma*sigma/2
+	return time.Duration(math.Exp(mu + sigma*rand.NormFloat64()))
+}
+
+// Load reads a scenario file.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	names := map[model.LabelValue]bool{}
+	for _, svc := range s.Services {
+		names[svc.Name] = true
+	}
+	for i, svc := range s.Services {
+		if svc.Name == "" {
+			return nil, fmt.Errorf("%s: service %d has no name", path, i)
+		}
+		if len(svc.Templates) == 0 {
+			return nil, fmt.Errorf("%s: service %s has no templates", path, svc.Name)
+		}
+		for _, dep := range svc.DependsOn {
+			if dep == svc.Name {
+				return nil, fmt.Errorf("%s: service %s depends on itself", path, svc.Name)
+			}
+			if !names[dep] {
+				return nil, fmt.Errorf("%s: service %s depends on unknown service %q", path, svc.Name, dep)
+			}
+		}
+		if _, err := svc.Compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, _, err := svc.compileErrors(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if r := svc.Rollout; r != nil {
+			if r.From == "" || r.To == "" {
+				return nil, fmt.Errorf("%s: rollout of service %s needs from and to versions", path, svc.Name)
+			}
+			if _, err := r.next(svc).compile(); err != nil {
+				return nil, fmt.Errorf("%s: rollout: %w", path, err)
+			}
+		}
+	}
+	for _, f := range s.Failures {
+		if !names[f.Service] {
+			return nil, fmt.Errorf("%s: failure of unknown service %q", path, f.Service)
+		}
+	}
+	return &s, nil
+}
+
+// Write encodes the scenario as indented JSON.
+func (s *Scenario) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
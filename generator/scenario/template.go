@@ -0,0 +1,225 @@
+package scenario
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/prometheus/common/model"
+)
+
+// SlotType is the kind of value generated for a variable part of a template.
+type SlotType string
+
+const (
+	SlotTimestamp SlotType = "TS"
+	SlotUUID      SlotType = "UUID"
+	SlotIP        SlotType = "IP"
+	SlotDuration  SlotType = "DURATION"
+	SlotNumber    SlotType = "NUM"
+	// SlotWord picks one of the values seen while learning.
+	SlotWord SlotType = "WORD"
+)
+
+// Placeholder is how a slot appears in a template pattern, e.g. <IP>.
+func (t SlotType) Placeholder() string {
+	return "<" + string(t) + ">"
+}
+
+// Template is a log line shape, its pattern mixes text, actions like {{ts "rfc3339"}} and learned
+// placeholders like <IP>. Every placeholder is filled from the slot at the same position.
+type Template struct {
+	Pattern string           `json:"pattern"`
+	Level   model.LabelValue `json:"level"`
+	// Weight is how often the template is picked compared to the others of its service.
+	Weight float64 `json:"weight"`
+	Slots  []Slot  `json:"slots,omitempty"`
+}
+
+// Slot describes how to generate fresh values for a placeholder.
+type Slot struct {
+	Type SlotType `json:"type"`
+	// Min and Max bound numbers, and durations in seconds.
+	Min      float64 `json:"min,omitempty"`
+	Max      float64 `json:"max,omitempty"`
+	Decimals int     `json:"decimals,omitempty"`
+	// Width zero pads numbers, e.g. hours or time zone offsets.
+	Width int `json:"width,omitempty"`
+	// Layout is the time layout of timestamps.
+	Layout string `json:"layout,omitempty"`
+	// Values are picked from for words.
+	Values []string `json:"values,omitempty"`
+}
+
+var placeholders = regexp.MustCompile(`<(TS|UUID|IP|DURATION|NUM|WORD)>`)
+
+// CompiledTemplate is a Template ready to render lines.
+type CompiledTemplate struct {
+	Template
+	parts []func(t time.Time) string
+}
+
+// Compile parses the actions and placeholders of the pattern.
+func (tpl Template) Compile() (*CompiledTemplate, error) {
+	return tpl.compile("")
+}
+
+// compile is Compile for a template of service, its {{error}} actions pick the errors of service.
+func (tpl Template) compile(service string) (*CompiledTemplate, error) {
+	c := &CompiledTemplate{Template: tpl}
+	slots := 0
+	rest := tpl.Pattern
+	for rest != "" {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			c.addText(rest, &slots)
+			break
+		}
+		c.addText(rest[:start], &slots)
+		end := actionEnd(rest, start+2)
+		if end < 0 {
+			return nil, fmt.Errorf("template %q: unterminated action", tpl.Pattern)
+		}
+		fn, err := compileAction(rest[start+2:end], service)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %w", tpl.Pattern, err)
+		}
+		c.parts = append(c.parts, fn)
+		rest = rest[end+2:]
+	}
+	return c, nil
+}
+
+func (c *CompiledTemplate) addText(text string, slots *int) {
+	prev := 0
+	for _, match := range placeholders.FindAllStringSubmatchIndex(text, -1) {
+		literal := text[prev:match[0]]
+		c.parts = append(c.parts, func(time.Time) string { return literal })
+		slot := Slot{Type: SlotType(text[match[2]:match[3]])}
+		if *slots < len(c.Slots) {
+			slot = c.Slots[*slots]
+		}
+		*slots++
+		c.parts = append(c.parts, slot.Generate)
+		prev = match[1]
+	}
+	literal := text[prev:]
+	c.parts = append(c.parts, func(time.Time) string { return literal })
+}
+
+// Render returns a line with fresh values, timestamps are set to t.
+func (c *CompiledTemplate) Render(t time.Time) string {
+	var b strings.Builder
+	for _, part := range c.parts {
+		b.WriteString(part(t))
+	}
+	return b.String()
+}
+
+// Generate returns a fresh value for the slot.
+func (s Slot) Generate(t time.Time) string {
+	switch s.Type {
+	case SlotTimestamp:
+		layout := s.Layout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		return t.Format(layout)
+	case SlotUUID:
+		return gofakeit.UUID()
+	case SlotIP:
+		return flog.FakeIP()
+	case SlotDuration:
+		return formatDuration(time.Duration(s.uniform() * float64(time.Second)))
+	case SlotNumber:
+		n := strconv.FormatFloat(s.uniform(), 'f', s.Decimals, 64)
+		if pad := s.Width - len(strings.SplitN(n, ".", 2)[0]); pad > 0 {
+			n = strings.Repeat("0", pad) + n
+		}
+		return n
+	default:
+		if len(s.Values) == 0 {
+			return gofakeit.Word()
+		}
+		return s.Values[rand.Intn(len(s.Values))]
+	}
+}
+
+func (s Slot) uniform() float64 {
+	if s.Max <= s.Min {
+		return s.Min
+	}
+	v := s.Min + rand.Float64()*(s.Max-s.Min)
+	if s.Type == SlotNumber && s.Decimals == 0 {
+		return math.Round(v)
+	}
+	return v
+}
+
+// variables finds values that change between otherwise identical lines. Timestamps come first so
+// their digits aren't taken as numbers, durations before numbers for the same reason.
+var variables = regexp.MustCompile(strings.Join([]string{
+	`(?P<TS>\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?)`,
+	`(?P<UUID>\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b)`,
+	`(?P<IP>\b\d{1,3}(?:\.\d{1,3}){3}\b)`,
+	`(?P<DURATION>\b(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))+\b)`,
+	`(?P<NUM>\b\d+(?:\.\d+)?\b)`,
+}, "|"))
+
+// maskedToken is a token with its variables replaced by placeholders.
+type maskedToken struct {
+	masked string
+	types  []SlotType
+	values []string
+}
+
+func maskToken(token string) maskedToken {
+	matches := variables.FindAllStringSubmatchIndex(token, -1)
+	if matches == nil {
+		return maskedToken{masked: token}
+	}
+	names := variables.SubexpNames()
+	var (
+		b    strings.Builder
+		prev int
+		m    = maskedToken{}
+	)
+	for _, match := range matches {
+		for g := 1; g < len(names); g++ {
+			if match[2*g] < 0 {
+				continue
+			}
+			typ := SlotType(names[g])
+			b.WriteString(token[prev:match[0]])
+			b.WriteString(typ.Placeholder())
+			m.types = append(m.types, typ)
+			m.values = append(m.values, token[match[0]:match[1]])
+			break
+		}
+		prev = match[1]
+	}
+	b.WriteString(token[prev:])
+	m.masked = b.String()
+	return m
+}
+
+// timestampLayout returns the layout of a timestamp matched by the TS variable.
+func timestampLayout(ts string) string {
+	layout := "2006-01-02T15:04:05"
+	rest := ts[len(layout):]
+	if strings.HasPrefix(rest, ".") {
+		digits := len(rest) - len(strings.TrimLeft(rest[1:], "0123456789")) - 1
+		layout += "." + strings.Repeat("0", digits)
+		rest = rest[1+digits:]
+	}
+	if rest != "" {
+		layout += "Z07:00"
+	}
+	return layout
+}
@@ -0,0 +1,145 @@
+package scenario
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskToken(t *testing.T) {
+	a := assert.New(t)
+	for token, masked := range map[string]string{
+		"ts=2024-09-13T10:00:00.123Z":             "ts=<TS>",
+		"id=0b7c7a42-3f52-4b0c-9a47-3d3f8b8c0b1a": "id=<UUID>",
+		"10.0.0.1:8080":                           "<IP>:<NUM>",
+		"duration=1m30.5s":                        "duration=<DURATION>",
+		"bytes=1024":                              "bytes=<NUM>",
+		"ratio=0.25,":                             "ratio=<NUM>,",
+		"compactor-abc12":                         "compactor-abc12",
+		"caller=flush.go:253":                     "caller=flush.go:<NUM>",
+		"[13/Sep/2024:10:00:02":                   "[<NUM>/Sep/<NUM>:<NUM>:<NUM>:<NUM>",
+		"msg=done":                                "msg=done",
+	} {
+		a.Equal(masked, maskToken(token).masked, token)
+	}
+	m := maskToken("10.0.0.1:8080")
+	a.Equal([]SlotType{SlotIP, SlotNumber}, m.types)
+	a.Equal([]string{"10.0.0.1", "8080"}, m.values)
+}
+
+func TestTimestampLayout(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("2006-01-02T15:04:05", timestampLayout("2024-09-13T10:00:00"))
+	a.Equal("2006-01-02T15:04:05.000Z07:00", timestampLayout("2024-09-13T10:00:00.123Z"))
+	a.Equal("2006-01-02T15:04:05.000000Z07:00", timestampLayout("2024-09-13T10:00:00.123456+02:00"))
+}
+
+func render(t *testing.T, tpl Template, ts time.Time) string {
+	t.Helper()
+	c, err := tpl.Compile()
+	require.NoError(t, err)
+	return c.Render(ts)
+}
+
+func TestRender(t *testing.T) {
+	a := assert.New(t)
+	tpl := Template{
+		Pattern: "ts=<TS> user=<WORD> took=<DURATION> bytes=<NUM> ratio=<NUM> from=<IP> id=<UUID>",
+		Slots: []Slot{
+			{Type: SlotTimestamp, Layout: "2006-01-02T15:04:05.000Z07:00"},
+			{Type: SlotWord, Values: []string{"alice"}},
+			{Type: SlotDuration, Min: 0.01, Max: 0.02},
+			{Type: SlotNumber, Min: 100, Max: 200},
+			{Type: SlotNumber, Min: 0, Max: 1, Decimals: 2},
+			{Type: SlotIP},
+			{Type: SlotUUID},
+		},
+	}
+	line := render(t, tpl, time.Date(2024, 9, 13, 10, 0, 0, 5e8, time.UTC))
+	a.Regexp(regexp.MustCompile(`^ts=2024-09-13T10:00:00.500Z user=alice took=(1\d|20)ms bytes=\d{3} ratio=[01]\.\d{2} from=\d+\.\d+\.\d+\.\d+ id=[0-9a-f-]{36}$`), line)
+
+	a.Equal("+0000", render(t, Template{Pattern: "+<NUM>", Slots: []Slot{{Type: SlotNumber, Width: 4}}}, time.Now()))
+
+	// Missing slots fall back to defaults for the placeholder type.
+	a.Regexp(`^\d+$`, render(t, Template{Pattern: "<NUM>"}, time.Now()))
+}
+
+func TestServiceRun(t *testing.T) {
+	a := assert.New(t)
+	svc := Service{
+		Name:     "cart",
+		Interval: Interval{Mean: Duration(time.Millisecond)},
+		Templates: []Template{
+			{Pattern: "added item", Level: log.INFO, Weight: 1},
+			{Pattern: "checkout failed", Level: log.ERROR, Weight: 0},
+		},
+	}
+	var (
+		mtx   sync.Mutex
+		lines []string
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	svc.Run(ctx, log.NewAppLogger(model.LabelSet{"service_name": "cart"}, log.LoggerFunc(func(labels model.LabelSet, _ time.Time, msg string, _ push.LabelsAdapter) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		a.Equal(model.LabelValue("info"), labels["level"])
+		lines = append(lines, msg)
+		return nil
+	})), nil)
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(lines) >= 5
+	}, time.Second, time.Millisecond)
+	cancel()
+	mtx.Lock()
+	defer mtx.Unlock()
+	a.Equal("added item", lines[0])
+}
+
+func TestServiceRollout(t *testing.T) {
+	a := assert.New(t)
+	svc := Service{
+		Name:      "cart",
+		Interval:  Interval{Mean: Duration(time.Millisecond)},
+		Templates: []Template{{Pattern: "added item", Level: log.INFO, Weight: 1}},
+		Rollout: &Rollout{
+			From: "v1", To: "v2",
+			After:     Duration(-time.Hour),
+			Templates: []Template{{Pattern: "added item to cart", Level: log.INFO, Weight: 1}},
+		},
+	}
+	var (
+		mtx   sync.Mutex
+		lines = map[string][]string{}
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.Run(ctx, log.NewAppLogger(model.LabelSet{"service_name": "cart"}, log.LoggerFunc(func(_ model.LabelSet, _ time.Time, msg string, metadata push.LabelsAdapter) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		require.Len(t, metadata, 1)
+		lines[metadata[0].Value] = append(lines[metadata[0].Value], msg)
+		return nil
+	})), nil)
+
+	// The rollout ended before the service started.
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(lines["v2"]) >= 5
+	}, time.Second, time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	a.Empty(lines["v1"])
+	a.Equal("added item to cart", lines["v2"][0])
+}
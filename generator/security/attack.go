@@ -0,0 +1,208 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/load"
+)
+
+// Attack is a kind of attack against the hosts.
+type Attack string
+
+const (
+	// BruteForce guesses the passwords of common accounts over SSH, from a single address.
+	BruteForce Attack = "ssh-brute-force"
+	// CredentialStuffing tries leaked credentials against the login page, from many addresses
+	// sharing the user agent of the tool.
+	CredentialStuffing Attack = "credential-stuffing"
+	// PortScan probes the ports of the hosts, from a single address.
+	PortScan Attack = "port-scan"
+)
+
+// Kinds are the kinds of attacks.
+var Kinds = []Attack{BruteForce, CredentialStuffing, PortScan}
+
+// DefaultDuration is how long attacks last when they don't set a duration.
+const DefaultDuration = 15 * time.Minute
+
+// campaign is an attack with absolute times.
+type campaign struct {
+	start, end time.Time
+	source     string
+}
+
+func (c campaign) active(t time.Time) bool {
+	return !t.Before(c.start) && t.Before(c.end)
+}
+
+// Attacks tracks the attacks against the hosts. It is safe for concurrent use, its nil value
+// has no attacks.
+type Attacks struct {
+	mtx       sync.RWMutex
+	campaigns map[Attack][]campaign
+}
+
+// NewAttacks returns an empty set of attacks.
+func NewAttacks() *Attacks {
+	return &Attacks{campaigns: map[Attack][]campaign{}}
+}
+
+// Start starts an attack at start for d, DefaultDuration when zero. It returns the address of
+// the attacker, taken from the flog.FakeIP pool so it shows up in the access logs too.
+// Credential stuffing doesn't use it since it comes from many addresses.
+func (a *Attacks) Start(kind Attack, start time.Time, d time.Duration) string {
+	if d <= 0 {
+		d = DefaultDuration
+	}
+	c := campaign{start: start, end: start.Add(d), source: flog.FakeIP()}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.campaigns[kind] = append(a.campaigns[kind], c)
+	return c.source
+}
+
+// Stop ends the attacks of a kind at t.
+func (a *Attacks) Stop(kind Attack, t time.Time) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	campaigns := a.campaigns[kind][:0]
+	for _, c := range a.campaigns[kind] {
+		if c.start.After(t) {
+			continue
+		}
+		if c.end.After(t) {
+			c.end = t
+		}
+		campaigns = append(campaigns, c)
+	}
+	a.campaigns[kind] = campaigns
+}
+
+// Active returns the address of the attacker when an attack of a kind is running at t.
+func (a *Attacks) Active(kind Attack, t time.Time) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+	for _, c := range a.campaigns[kind] {
+		if c.active(t) {
+			return c.source, true
+		}
+	}
+	return "", false
+}
+
+// Configure schedules the attacks of spec, written as attacks separated by semicolons with
+// optional comma separated settings, e.g. "ssh-brute-force:after=10m,for=5m;port-scan". Attacks
+// start after the given time since start.
+func (a *Attacks) Configure(spec string, start time.Time) error {
+	for _, attack := range strings.Split(spec, ";") {
+		if strings.TrimSpace(attack) == "" {
+			continue
+		}
+		name, settings, _ := strings.Cut(attack, ":")
+		kind := Attack(strings.TrimSpace(name))
+		if !known(kind) {
+			return fmt.Errorf("unknown attack %q", kind)
+		}
+		at, d := start, time.Duration(0)
+		for _, setting := range strings.Split(settings, ",") {
+			if strings.TrimSpace(setting) == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(setting, "=")
+			if !ok {
+				return fmt.Errorf("invalid attack setting %q, expected name=value", setting)
+			}
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			v, err := time.ParseDuration(value)
+			if err != nil || v < 0 {
+				return fmt.Errorf("%s: invalid %s %q", kind, name, value)
+			}
+			switch name {
+			case "after":
+				at = start.Add(v)
+			case "for":
+				d = v
+			default:
+				return fmt.Errorf("unknown attack setting %q", name)
+			}
+		}
+		a.Start(kind, at, d)
+	}
+	return nil
+}
+
+func known(kind Attack) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// status is an attack as reported by the control API.
+type status struct {
+	Attack Attack    `json:"attack"`
+	Source string    `json:"source"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// ServeHTTP is the control API of the attacks:
+//
+//	GET  /attacks                                     the running and scheduled attacks
+//	POST /attacks/start?attack=ssh-brute-force&for=5m  starts an attack, for is optional
+//	POST /attacks/stop?attack=ssh-brute-force          stops the attacks of a kind
+func (a *Attacks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := load.Now()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/attacks":
+		a.mtx.RLock()
+		out := []status{}
+		for kind, campaigns := range a.campaigns {
+			for _, c := range campaigns {
+				if c.end.After(now) {
+					out = append(out, status{Attack: kind, Source: c.source, Start: c.start, End: c.end})
+				}
+			}
+		}
+		a.mtx.RUnlock()
+		sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	case r.Method == http.MethodPost && (r.URL.Path == "/attacks/start" || r.URL.Path == "/attacks/stop"):
+		kind := Attack(r.URL.Query().Get("attack"))
+		if !known(kind) {
+			http.Error(w, fmt.Sprintf("unknown attack %q", kind), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/attacks/stop" {
+			a.Stop(kind, now)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var d time.Duration
+		if s := r.URL.Query().Get("for"); s != "" {
+			var err error
+			if d, err = time.ParseDuration(s); err != nil {
+				http.Error(w, fmt.Sprintf("invalid duration: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+		source := a.Start(kind, now, d)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"source": source})
+	default:
+		http.NotFound(w, r)
+	}
+}
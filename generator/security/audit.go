@@ -0,0 +1,105 @@
+package security
+
+import (
+	"fmt"
+	"math/rand"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// unset is the audit user ID of processes started before any login, like sshd.
+const unset = 4294967295
+
+// audit renders the records of an audit event, they share its time and serial.
+func (h *Host) audit(t time.Time, level model.LabelValue, records ...[2]string) []log.Line {
+	h.serial++
+	lines := make([]log.Line, len(records))
+	for i, r := range records {
+		lines[i] = log.Line{Time: t, Level: level, Text: fmt.Sprintf("type=%s msg=audit(%d.%03d:%d): %s", r[0], t.Unix(), t.Nanosecond()/int(time.Millisecond), h.serial, r[1])}
+	}
+	return lines
+}
+
+// proctitle returns the command line of a process as recorded by PROCTITLE records: hex
+// encoded, with the arguments separated by NUL bytes.
+func proctitle(args []string) string {
+	return fmt.Sprintf("%X", strings.Join(args, "\x00"))
+}
+
+// execve returns the SYSCALL, EXECVE, CWD, PATH and PROCTITLE records of a process started by
+// u. Commands run with sudo are run by root on behalf of u.
+func (h *Host) execve(u User, c Command, syscall int, exit int, key string, file string) [][2]string {
+	uid := u.UID
+	if c.Root {
+		uid = 0
+	}
+	success := "yes"
+	if exit < 0 {
+		success = "no"
+	}
+	ppid, pid := h.pid(), h.pid()
+	records := [][2]string{{"SYSCALL", fmt.Sprintf(`arch=c000003e syscall=%d success=%s exit=%d a0=%x a1=%x a2=%x a3=0 items=%d ppid=%d pid=%d auid=%d uid=%d gid=%d euid=%d suid=%d fsuid=%d egid=%d sgid=%d fsgid=%d tty=pts%d ses=%d comm="%s" exe="%s" subj=unconfined key="%s"`,
+		syscall, success, exit, 0x55d0c0000000+rand.Intn(0xffffff), 0x7ffd00000000+rand.Intn(0xffffff), 0x7ffd00000000+rand.Intn(0xffffff), 1, ppid, pid,
+		u.UID, uid, uid, uid, uid, uid, uid, uid, uid, rand.Intn(4), h.session, path.Base(c.Args[0]), c.Args[0], key)}}
+	if syscall == 59 {
+		args := make([]string, len(c.Args))
+		for i, arg := range c.Args {
+			args[i] = fmt.Sprintf(`a%d="%s"`, i, arg)
+		}
+		records = append(records, [2]string{"EXECVE", fmt.Sprintf("argc=%d %s", len(c.Args), strings.Join(args, " "))})
+	}
+	records = append(records, [2]string{"CWD", fmt.Sprintf(`cwd="%s"`, u.Home())})
+	mode := "0100755"
+	if file == "" {
+		file = c.Args[0]
+	} else {
+		mode = "0100640"
+	}
+	records = append(records, [2]string{"PATH", fmt.Sprintf(`item=0 name="%s" inode=%d dev=ca:01 mode=%s ouid=0 ogid=0 rdev=00:00 nametype=NORMAL cap_fp=0 cap_fi=0 cap_fe=0 cap_fver=0 cap_frootid=0`,
+		file, 100000+len(file)*7919, mode)})
+	return append(records, [2]string{"PROCTITLE", "proctitle=" + proctitle(c.Args)})
+}
+
+// Audit returns the records of an audit event at t. Logins and commands are recorded at info,
+// denied accesses to watched files at warn and crashing processes at error and critical.
+func (h *Host) Audit(t time.Time, level model.LabelValue) []log.Line {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	switch level {
+	case log.WARN:
+		c := sensitiveCommands[0]
+		c.Root = false
+		// Without sudo, reading /etc/shadow is denied.
+		return h.audit(t, log.WARN, h.execve(randUser(false), c, 257, -13, "shadow", c.Args[1])...)
+	case log.ERROR, log.CRITICAL:
+		exe := []string{"/usr/bin/node", "/usr/sbin/nginx", "/usr/bin/python3"}[rand.Intn(3)]
+		return h.audit(t, log.ERROR, [2]string{"ANOM_ABEND", fmt.Sprintf(`auid=%d uid=0 gid=0 ses=%d subj=unconfined pid=%d comm="%s" exe="%s" sig=11 res=1`, unset, unset, h.pid(), path.Base(exe), exe)})
+	}
+	u := randUser(false)
+	if rand.Intn(4) == 0 {
+		h.session++
+		return h.audit(t, log.INFO, [2]string{"USER_LOGIN", fmt.Sprintf(`pid=%d uid=0 auid=%d ses=%d subj=unconfined msg='op=login id=%d exe="/usr/sbin/sshd" hostname=? addr=%s terminal=/dev/pts/%d res=success'`, h.pid(), u.UID, h.session, u.UID, bastion(), rand.Intn(4))})
+	}
+	c := Commands[rand.Intn(len(Commands))]
+	key := "exec"
+	if c.Root {
+		u, key = randUser(true), "rootcmd"
+	}
+	return h.audit(t, log.INFO, h.execve(u, c, 59, 0, key, "")...)
+}
+
+// FailedLogin returns the records of a failed SSH login from source at t, like those of brute
+// force attacks.
+func (h *Host) FailedLogin(t time.Time, source string) []log.Line {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	pid, user := h.pid(), guessedUsers[rand.Intn(len(guessedUsers))]
+	if user != "root" {
+		return h.audit(t, log.WARN, [2]string{"USER_LOGIN", fmt.Sprintf(`pid=%d uid=0 auid=%d ses=%d subj=unconfined msg='op=login acct="(invalid user)" exe="/usr/sbin/sshd" hostname=? addr=%s terminal=sshd res=failed'`, pid, unset, unset, source)})
+	}
+	return h.audit(t, log.WARN, [2]string{"USER_AUTH", fmt.Sprintf(`pid=%d uid=0 auid=%d ses=%d subj=unconfined msg='op=PAM:authentication grantors=? acct="%s" exe="/usr/sbin/sshd" hostname=%s addr=%s terminal=ssh res=failed'`, pid, unset, unset, user, source, source)})
+}
@@ -0,0 +1,196 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// guessedUsers are the accounts tried by SSH brute force attacks, root is the only one existing
+// on the hosts.
+var guessedUsers = []string{"root", "root", "root", "admin", "ubuntu", "test", "oracle", "postgres", "user", "pi", "git", "ftpuser", "guest"}
+
+// maxAuthTries is the number of passwords sshd accepts per connection.
+const maxAuthTries = 6
+
+// fingerprint returns the SHA256 fingerprint of the key of a user.
+func fingerprint(user string) string {
+	sum := sha256.Sum256([]byte(user))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// SSHD returns the auth.log lines of sshd for a connection at t. Operators log in and out at
+// info, scanners of the internet fail to authenticate at warn, error and critical break the
+// connections.
+func (h *Host) SSHD(t time.Time, level model.LabelValue) []log.Line {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	pid, port := h.pid(), clientPort()
+	var lines []log.Line
+	add := func(level model.LabelValue, format string, args ...any) {
+		lines = append(lines, h.syslog(t, authpriv, level, "sshd", pid, fmt.Sprintf(format, args...)))
+	}
+	switch level {
+	case log.WARN:
+		ip := gofakeit.IPv4Address()
+		switch rand.Intn(3) {
+		case 0:
+			user := guessedUsers[3+rand.Intn(len(guessedUsers)-3)]
+			add(log.WARN, "Invalid user %s from %s port %d", user, ip, port)
+			add(log.INFO, "Connection closed by invalid user %s %s port %d [preauth]", user, ip, port)
+		case 1:
+			add(log.INFO, "Connection closed by %s port %d [preauth]", ip, port)
+		default:
+			add(log.WARN, "banner exchange: Connection from %s port %d: invalid format", ip, port)
+		}
+	case log.ERROR, log.CRITICAL:
+		ip := gofakeit.IPv4Address()
+		if rand.Intn(2) == 0 {
+			add(log.ERROR, "error: kex_exchange_identification: read: Connection reset by peer")
+			add(log.INFO, "Connection reset by %s port %d", ip, port)
+		} else {
+			add(log.ERROR, "fatal: Timeout before authentication for %s port %d", ip, port)
+		}
+	default:
+		u, ip := randUser(false), bastion()
+		if rand.Intn(3) == 0 {
+			add(log.INFO, "Received disconnect from %s port %d:11: disconnected by user", ip, port)
+			add(log.INFO, "Disconnected from user %s %s port %d", u.Name, ip, port)
+			add(log.INFO, "pam_unix(sshd:session): session closed for user %s", u.Name)
+			break
+		}
+		add(log.INFO, "Accepted publickey for %s from %s port %d ssh2: ED25519 %s", u.Name, ip, port, fingerprint(u.Name))
+		add(log.INFO, "pam_unix(sshd:session): session opened for user %s(uid=%d) by (uid=0)", u.Name, u.UID)
+	}
+	return lines
+}
+
+// BruteForce returns the lines of a connection of an SSH brute force attack from source at t.
+// The attacker guesses passwords of an account until sshd disconnects it.
+func (h *Host) BruteForce(t time.Time, source string) []log.Line {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	pid, port := h.pid(), clientPort()
+	user := guessedUsers[rand.Intn(len(guessedUsers))]
+	valid := user == "root"
+	var lines []log.Line
+	add := func(level model.LabelValue, format string, args ...any) {
+		lines = append(lines, h.syslog(t, authpriv, level, "sshd", pid, fmt.Sprintf(format, args...)))
+	}
+	if !valid {
+		add(log.WARN, "Invalid user %s from %s port %d", user, source, port)
+	}
+	pamUser := ""
+	if valid {
+		pamUser = "  user=" + user
+	}
+	add(log.WARN, "pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=%s%s", source, pamUser)
+	failed, who := user, "authenticating user "+user
+	if !valid {
+		failed, who = "invalid user "+user, "invalid user "+user
+	}
+	tries := 1 + rand.Intn(maxAuthTries)
+	for i := 0; i < tries; i++ {
+		add(log.WARN, "Failed password for %s from %s port %d ssh2", failed, source, port)
+		t = t.Add(time.Duration(500+rand.Intn(2000)) * time.Millisecond)
+	}
+	if tries == maxAuthTries {
+		add(log.ERROR, "error: maximum authentication attempts exceeded for %s from %s port %d ssh2 [preauth]", failed, source, port)
+		add(log.INFO, "Disconnecting %s %s port %d: Too many authentication failures [preauth]", who, source, port)
+		return lines
+	}
+	add(log.INFO, "Connection closed by %s %s port %d [preauth]", who, source, port)
+	return lines
+}
+
+// Command is a command run by the users of the hosts.
+type Command struct {
+	// Args are the arguments of the command, starting with the path of the executable.
+	Args []string
+	// Root is whether the command is run with sudo.
+	Root bool
+}
+
+// String returns the command line.
+func (c Command) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Commands are the commands run on the hosts.
+var Commands = []Command{
+	{Args: []string{"/usr/bin/systemctl", "restart", "nginx"}, Root: true},
+	{Args: []string{"/usr/bin/journalctl", "-u", "ssh", "--since", "today"}, Root: true},
+	{Args: []string{"/usr/bin/apt-get", "upgrade", "-y"}, Root: true},
+	{Args: []string{"/usr/bin/docker", "ps"}, Root: true},
+	{Args: []string{"/usr/bin/tail", "-n", "100", "/var/log/auth.log"}, Root: true},
+	{Args: []string{"/usr/bin/ls", "-la"}},
+	{Args: []string{"/usr/bin/git", "pull"}},
+	{Args: []string{"/usr/bin/kubectl", "get", "pods", "-A"}},
+}
+
+// sensitiveCommands are run by users trying to read what they shouldn't.
+var sensitiveCommands = []Command{
+	{Args: []string{"/usr/bin/cat", "/etc/shadow"}, Root: true},
+	{Args: []string{"/usr/bin/vi", "/etc/sudoers"}, Root: true},
+	{Args: []string{"/usr/bin/su", "-"}, Root: true},
+}
+
+// randCommand returns a command run with sudo.
+func randCommand() Command {
+	for {
+		c := Commands[rand.Intn(len(Commands))]
+		if c.Root {
+			return c
+		}
+	}
+}
+
+// Sudo returns the auth.log lines of sudo for a command run at t. Sudoers run commands at info,
+// users mistype their password at warn, and are refused at error.
+func (h *Host) Sudo(t time.Time, level model.LabelValue) []log.Line {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	tty := fmt.Sprintf("pts/%d", rand.Intn(4))
+	var lines []log.Line
+	add := func(t time.Time, level model.LabelValue, pid int, format string, args ...any) {
+		lines = append(lines, h.syslog(t, authpriv, level, "sudo", pid, fmt.Sprintf(format, args...)))
+	}
+	entry := func(u User, c Command) string {
+		return fmt.Sprintf("TTY=%s ; PWD=%s ; USER=root ; COMMAND=%s", tty, u.Home(), c)
+	}
+	authFailure := func(u User) {
+		add(t, log.WARN, h.pid(), "pam_unix(sudo:auth): authentication failure; logname=%s uid=%d euid=0 tty=/dev/%s ruser=%s rhost=  user=%s", u.Name, u.UID, tty, u.Name, u.Name)
+	}
+	switch level {
+	case log.WARN:
+		u := randUser(true)
+		authFailure(u)
+		c := randCommand()
+		add(t, log.INFO, 0, "%8s : %s", u.Name, entry(u, c))
+		add(t, log.INFO, h.pid(), "pam_unix(sudo:session): session opened for user root(uid=0) by %s(uid=%d)", u.Name, u.UID)
+	case log.ERROR, log.CRITICAL:
+		if rand.Intn(2) == 0 {
+			u := randUser(true)
+			authFailure(u)
+			add(t, log.ERROR, 0, "%8s : 3 incorrect password attempts ; %s", u.Name, entry(u, randCommand()))
+			break
+		}
+		// bob isn't a sudoer.
+		u := Users[2]
+		add(t, log.ERROR, 0, "%8s : user NOT in sudoers ; %s", u.Name, entry(u, sensitiveCommands[rand.Intn(len(sensitiveCommands))]))
+	default:
+		u, c := randUser(true), randCommand()
+		pid := h.pid()
+		add(t, log.INFO, 0, "%8s : %s", u.Name, entry(u, c))
+		add(t, log.INFO, pid, "pam_unix(sudo:session): session opened for user root(uid=0) by %s(uid=%d)", u.Name, u.UID)
+		add(t.Add(time.Duration(50+rand.Intn(3000))*time.Millisecond), log.INFO, pid, "pam_unix(sudo:session): session closed for user root")
+	}
+	return lines
+}
@@ -0,0 +1,83 @@
+package security
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit"
+	"github.com/grafana/explore-logs/generator/log"
+)
+
+// Log prefixes of the rules dropping packets, iptables and nftables log them the same way.
+const (
+	IptablesPrefix = "IPTABLES-DROP: "
+	NftablesPrefix = "nft-drop-input: "
+)
+
+// Packet is a packet received by a host.
+type Packet struct {
+	Src, Dst string
+	// Protocol is TCP, UDP or ICMP.
+	Protocol         string
+	SrcPort, DstPort int
+	TTL, ID, Length  int
+	// Window is the TCP window size.
+	Window int
+}
+
+// NewPacket returns a packet sent to the host by a random address of the internet: probes of
+// the usual services, stray UDP traffic and pings.
+func (h *Host) NewPacket() Packet {
+	p := Packet{
+		Src: gofakeit.IPv4Address(), Dst: h.Address, Protocol: "TCP",
+		SrcPort: 1024 + rand.Intn(64511), DstPort: []int{22, 23, 80, 443, 445, 1433, 3306, 3389, 5900, 6379, 8080, 9200}[rand.Intn(12)],
+		TTL: 40 + rand.Intn(200), ID: rand.Intn(65536), Length: 60, Window: []int{29200, 64240, 65535}[rand.Intn(3)],
+	}
+	switch rand.Intn(10) {
+	case 0:
+		p.Protocol, p.DstPort, p.Length = "UDP", []int{53, 123, 161, 1900, 5060}[rand.Intn(5)], 28+rand.Intn(100)
+	case 1:
+		p.Protocol, p.Length = "ICMP", 84
+	}
+	return p
+}
+
+// ScanPacket returns a SYN sent to port of the host by a port scanner at source. Scanners send
+// small SYNs with the same source port, TTL and window to every port.
+func (h *Host) ScanPacket(source string, port int) Packet {
+	return Packet{
+		Src: source, Dst: h.Address, Protocol: "TCP", SrcPort: 40000 + int(hash(source)%20000), DstPort: port,
+		TTL: 37, ID: rand.Intn(65536), Length: 44, Window: 1024,
+	}
+}
+
+// ScanPorts returns the next ports probed by a port scanner, n of the 1024 well known ports in
+// a random order.
+func ScanPorts(n int) []int {
+	ports := make([]int, n)
+	for i := range ports {
+		ports[i] = 1 + rand.Intn(1024)
+	}
+	return ports
+}
+
+// Drop returns the kernel message of a packet dropped at t by the rule logging with prefix.
+func (h *Host) Drop(t time.Time, prefix string, p Packet) log.Line {
+	uptime := t.Sub(h.Boot)
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%12.6f] %sIN=eth0 OUT= MAC=%s:0a:00:00:00:00:01:08:00 SRC=%s DST=%s LEN=%d TOS=0x00 PREC=0x00 TTL=%d ID=%d ", uptime.Seconds(), prefix, h.MAC, p.Src, p.Dst, p.Length, p.TTL, p.ID)
+	switch p.Protocol {
+	case "ICMP":
+		fmt.Fprintf(&b, "PROTO=ICMP TYPE=8 CODE=0 ID=%d SEQ=%d", p.SrcPort, 1+p.ID%100)
+	case "UDP":
+		fmt.Fprintf(&b, "PROTO=UDP SPT=%d DPT=%d LEN=%d", p.SrcPort, p.DstPort, p.Length-20)
+	default:
+		if p.Length > 44 {
+			b.WriteString("DF ")
+		}
+		fmt.Fprintf(&b, "PROTO=TCP SPT=%d DPT=%d WINDOW=%d RES=0x00 SYN URGP=0", p.SrcPort, p.DstPort, p.Window)
+	}
+	return h.syslog(t, kern, log.WARN, "kernel", 0, b.String())
+}
@@ -0,0 +1,126 @@
+// Package security generates the logs security teams investigate: sshd and sudo messages of
+// auth.log, Linux audit records, iptables and nftables drops and web application firewall
+// events. Attacks can be started while the generator runs, they flood those logs with the
+// traces of an SSH brute force, a credential stuffing campaign or a port scan.
+package security
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// Syslog facilities of the generated messages.
+const (
+	kern     = 0
+	authpriv = 10
+)
+
+// User is an account of the hosts.
+type User struct {
+	Name string
+	UID  int
+	// Sudoer is whether the user may run commands as root.
+	Sudoer bool
+}
+
+// Home returns the home directory of the user.
+func (u User) Home() string {
+	return "/home/" + u.Name
+}
+
+// Users are the accounts of the hosts besides root.
+var Users = []User{
+	{Name: "deploy", UID: 1000, Sudoer: true},
+	{Name: "alice", UID: 1001, Sudoer: true},
+	{Name: "bob", UID: 1002},
+	{Name: "ci", UID: 1003, Sudoer: true},
+}
+
+// Host is a machine whose logs are generated. It keeps the state shared by its logs, like the
+// last process ID and audit serial. It is safe for concurrent use.
+type Host struct {
+	Name string
+	// Address is the private address of the host.
+	Address string
+	MAC     string
+	// Boot is when the host started, kernel messages are timestamped relative to it.
+	Boot time.Time
+
+	mtx                      sync.Mutex
+	lastPID, serial, session int
+	// loginFailures counts the failed logins of credential stuffing tools.
+	loginFailures int
+}
+
+// NewHost returns a host named name, booted a few days before t. Hosts named after EC2 nodes,
+// like ip-10-1-2-3.us-east-1.compute.internal, get the address of their name.
+func NewHost(name string, t time.Time) *Host {
+	sum := hash(name)
+	address := fmt.Sprintf("10.0.%d.%d", sum%16, 2+(sum>>8)%250)
+	if ip, ok := strings.CutPrefix(strings.Split(name, ".")[0], "ip-"); ok && strings.Count(ip, "-") == 3 {
+		address = strings.ReplaceAll(ip, "-", ".")
+	}
+	return &Host{
+		Name:    name,
+		Address: address,
+		MAC:     fmt.Sprintf("0a:%02x:%02x:%02x:%02x:%02x", byte(sum>>16), byte(sum>>24), byte(sum>>32), byte(sum>>40), byte(sum>>48)),
+		Boot:    t.Add(-time.Duration(1+sum%30*24) * time.Hour).Add(-time.Duration(sum % uint64(time.Hour))),
+		lastPID: 1000 + int(sum%30000),
+		serial:  int(sum % 100000),
+		session: 1 + int(sum%50),
+	}
+}
+
+// hash returns a stable hash of s.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// pid returns the ID of a new process, IDs grow like those of a busy host.
+func (h *Host) pid() int {
+	h.lastPID += 1 + rand.Intn(40)
+	if h.lastPID > 4194304 {
+		h.lastPID = 300
+	}
+	return h.lastPID
+}
+
+// syslog renders a message of app as an RFC 3164 line with the priority of facility and level.
+// The process ID is left out of the tag when zero, like the kernel and sudo do.
+func (h *Host) syslog(t time.Time, facility int, level model.LabelValue, app string, pid int, message string) log.Line {
+	priority := facility*8 + log.SyslogSeverity(level)
+	if pid == 0 {
+		return log.Line{Time: t, Level: level, Text: fmt.Sprintf("<%d>%s %s %s: %s", priority, t.Format(flog.RFC3164), h.Name, app, message)}
+	}
+	return log.Line{Time: t, Level: level, Text: fmt.Sprintf(flog.RFC3164Log, priority, t.Format(flog.RFC3164), h.Name, app, pid, message)}
+}
+
+// randUser returns a user of the hosts, sudoers only when sudoer is set.
+func randUser(sudoer bool) User {
+	for {
+		u := Users[rand.Intn(len(Users))]
+		if u.Sudoer || !sudoer {
+			return u
+		}
+	}
+}
+
+// bastion returns the address of a machine of the VPC the operators connect from.
+func bastion() string {
+	return fmt.Sprintf("10.0.0.%d", 10+rand.Intn(4))
+}
+
+// clientPort returns an ephemeral port of a client.
+func clientPort() int {
+	return 32768 + rand.Intn(28232)
+}
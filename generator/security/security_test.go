@@ -0,0 +1,115 @@
+package security
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const node = "ip-10-12-34-56.us-east-1.compute.internal"
+
+func TestNewHost(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	h := NewHost(node, now)
+	a.Equal("10.12.34.56", h.Address)
+	a.Equal(h.Boot, NewHost(node, now).Boot, "hosts are the same on every call")
+}
+
+func TestBruteForce(t *testing.T) {
+	a := assert.New(t)
+	lines := NewHost(node, time.Now()).BruteForce(time.Now(), "203.0.113.7")
+	var failed int
+	for _, line := range lines {
+		a.Contains(line.Text, "203.0.113.7")
+		if strings.Contains(line.Text, "Failed password for ") {
+			failed++
+		}
+	}
+	a.GreaterOrEqual(failed, 1)
+	a.LessOrEqual(failed, maxAuthTries, "connections are closed after MaxAuthTries")
+}
+
+func TestSudo(t *testing.T) {
+	a := assert.New(t)
+	lines := NewHost(node, time.Now()).Sudo(time.Now(), log.INFO)
+	require.Len(t, lines, 3)
+	a.Contains(lines[1].Text, "session opened for user root(uid=0) by ")
+	a.Contains(lines[2].Text, "session closed for user root")
+	a.True(lines[2].Time.After(lines[1].Time))
+}
+
+func TestFailedLogin(t *testing.T) {
+	a := assert.New(t)
+	line := NewHost(node, time.Now()).FailedLogin(time.Now(), "203.0.113.7")[0]
+	a.Equal(log.WARN, line.Level)
+	a.Contains(line.Text, " addr=203.0.113.7 ")
+	a.True(strings.HasSuffix(line.Text, " res=failed'"))
+}
+
+func TestScan(t *testing.T) {
+	a := assert.New(t)
+	h := NewHost(node, time.Now())
+	for _, port := range ScanPorts(10) {
+		line := h.Drop(time.Now(), NftablesPrefix, h.ScanPacket("203.0.113.7", port))
+		a.Contains(line.Text, "nft-drop-input: IN=eth0")
+		a.Contains(line.Text, "SRC=203.0.113.7 DST=10.12.34.56 ")
+	}
+}
+
+func TestCredentialStuffing(t *testing.T) {
+	a := assert.New(t)
+	h := NewHost(node, time.Now())
+	e1, e2 := h.CredentialStuffing(time.Now()), h.CredentialStuffing(time.Now())
+	a.Equal(log.WARN, e1.Level())
+	a.Equal("/login", e1.Transaction.Request.URI)
+	a.Equal(e1.Transaction.Request.Headers["User-Agent"], e2.Transaction.Request.Headers["User-Agent"], "the tool keeps its user agent")
+	a.Contains(e2.Transaction.Messages[0].Details.Match, "(Value: `22' )")
+}
+
+func TestAttacks(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	attacks := NewAttacks()
+	require.NoError(t, attacks.Configure("ssh-brute-force:after=10m,for=5m; port-scan", now))
+	_, ok := attacks.Active(BruteForce, now.Add(9*time.Minute))
+	a.False(ok)
+	_, ok = attacks.Active(BruteForce, now.Add(10*time.Minute))
+	a.True(ok)
+	_, ok = attacks.Active(BruteForce, now.Add(15*time.Minute))
+	a.False(ok)
+
+	attacks.Stop(PortScan, now.Add(time.Minute))
+	_, ok = attacks.Active(PortScan, now.Add(2*time.Minute))
+	a.False(ok)
+
+	a.EqualError(NewAttacks().Configure("sql-injection", now), `unknown attack "sql-injection"`)
+}
+
+func TestAttacksAPI(t *testing.T) {
+	a := assert.New(t)
+	srv := httptest.NewServer(NewAttacks())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/attacks/start?attack=ssh-brute-force&for=1h", "", nil)
+	require.NoError(t, err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	var started map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&started))
+	_ = resp.Body.Close()
+
+	resp, err = http.Get(srv.URL + "/attacks")
+	require.NoError(t, err)
+	var got []status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	_ = resp.Body.Close()
+	require.Len(t, got, 1)
+	a.Equal(started["source"], got[0].Source)
+}
@@ -0,0 +1,237 @@
+package security
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"time"
+
+	"github.com/brianvoe/gofakeit"
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// WAFEvent is a transaction of the ModSecurity JSON audit log: a request that matched rules of
+// the web application firewall.
+type WAFEvent struct {
+	Transaction Transaction `json:"transaction"`
+}
+
+// Transaction is a request and response seen by the firewall with the messages of the rules
+// it matched.
+type Transaction struct {
+	ClientIP   string      `json:"client_ip"`
+	TimeStamp  string      `json:"time_stamp"`
+	ServerID   string      `json:"server_id"`
+	ClientPort int         `json:"client_port"`
+	HostIP     string      `json:"host_ip"`
+	HostPort   int         `json:"host_port"`
+	UniqueID   string      `json:"unique_id"`
+	Request    WAFRequest  `json:"request"`
+	Response   WAFResponse `json:"response"`
+	Producer   Producer    `json:"producer"`
+	Messages   []Message   `json:"messages"`
+}
+
+// WAFRequest is the request of a transaction.
+type WAFRequest struct {
+	Method      string            `json:"method"`
+	HTTPVersion float64           `json:"http_version"`
+	URI         string            `json:"uri"`
+	Headers     map[string]string `json:"headers"`
+}
+
+// WAFResponse is the response of a transaction.
+type WAFResponse struct {
+	HTTPCode int               `json:"http_code"`
+	Headers  map[string]string `json:"headers"`
+}
+
+// Producer is the firewall and the rule sets logging the transaction.
+type Producer struct {
+	ModSecurity    string   `json:"modsecurity"`
+	Connector      string   `json:"connector"`
+	SecRulesEngine string   `json:"secrules_engine"`
+	Components     []string `json:"components"`
+}
+
+// Message is a rule matched by a transaction.
+type Message struct {
+	Message string  `json:"message"`
+	Details Details `json:"details"`
+}
+
+// Details are the rule and the data of a message.
+type Details struct {
+	Match      string   `json:"match"`
+	Reference  string   `json:"reference"`
+	RuleID     string   `json:"ruleId"`
+	File       string   `json:"file"`
+	LineNumber string   `json:"lineNumber"`
+	Data       string   `json:"data"`
+	Severity   string   `json:"severity"`
+	Ver        string   `json:"ver"`
+	Rev        string   `json:"rev"`
+	Tags       []string `json:"tags"`
+	Maturity   string   `json:"maturity"`
+	Accuracy   string   `json:"accuracy"`
+}
+
+// crsVersion is the version of the OWASP Core Rule Set.
+const crsVersion = "OWASP_CRS/4.0.0"
+
+// rule is a rule of the firewall with a request matching it.
+type rule struct {
+	id, file, message, match, data string
+	line, severity                 int
+	tags                           []string
+	// score is added to the anomaly score of the request, requests scoring 5 or more are blocked.
+	score int
+	// uri and agent are those of a request matching the rule.
+	uri, agent string
+}
+
+// Message returns the message of the rule for a match.
+func (r rule) Message() Message {
+	file := r.file
+	if file[0] != '/' {
+		file = "/usr/share/modsecurity-crs/rules/" + file
+	}
+	return Message{Message: r.message, Details: Details{
+		Match: r.match, Reference: fmt.Sprintf("v%d,%d", 4+len(r.id), len(r.data)%40), RuleID: r.id, File: file,
+		LineNumber: fmt.Sprint(r.line), Data: r.data, Severity: fmt.Sprint(r.severity), Ver: crsVersion,
+		Tags: append([]string{"application-multi", "language-multi", "platform-multi"}, r.tags...), Maturity: "0", Accuracy: "0",
+	}}
+}
+
+// attackRules are rules of the Core Rule Set matched by the requests of attackers.
+var attackRules = []rule{
+	{
+		id: "942100", file: "REQUEST-942-APPLICATION-ATTACK-SQLI.conf", line: 46, severity: 2, score: 5,
+		message: "SQL Injection Attack Detected via libinjection", match: "detected SQLi using libinjection.",
+		data: "Matched Data: s&sos found within ARGS:q: 1' OR '1'='1", tags: []string{"attack-sqli", "paranoia-level/1", "OWASP_CRS", "capec/1000/152/248/66"},
+		uri: "/search?q=" + url.QueryEscape("1' OR '1'='1"), agent: "sqlmap/1.7.2#stable (https://sqlmap.org)",
+	},
+	{
+		id: "941100", file: "REQUEST-941-APPLICATION-ATTACK-XSS.conf", line: 55, severity: 2, score: 5,
+		message: "XSS Attack Detected via libinjection", match: "detected XSS using libinjection.",
+		data: "Matched Data: XSS data found within ARGS:q: <script>alert(1)</script>", tags: []string{"attack-xss", "paranoia-level/1", "OWASP_CRS", "capec/1000/152/242"},
+		uri: "/search?q=" + url.QueryEscape("<script>alert(1)</script>"), agent: "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	},
+	{
+		id: "930110", file: "REQUEST-930-APPLICATION-ATTACK-LFI.conf", line: 47, severity: 2, score: 5,
+		message: "Path Traversal Attack (/../) or (/.../)", match: "Matched \"Operator `Rx' with parameter `(?:/|\\\\)(?:\\.\\.\\.?)(?:/|\\\\)' against variable `ARGS:file' (Value: `../../../etc/passwd' )",
+		data: "Matched Data: /../ found within ARGS:file: ../../../etc/passwd", tags: []string{"attack-lfi", "paranoia-level/1", "OWASP_CRS", "capec/1000/255/153/126"},
+		uri: "/download?file=" + url.QueryEscape("../../../etc/passwd"), agent: "curl/8.5.0",
+	},
+	{
+		id: "913100", file: "REQUEST-913-SCANNER-DETECTION.conf", line: 34, severity: 2, score: 5,
+		message: "Found User-Agent associated with security scanner", match: "Matched \"Operator `PmFromFile' with parameter `scanners-user-agents.data' against variable `REQUEST_HEADERS:User-Agent' (Value: `Nikto/2.5.0' )",
+		data: "Matched Data: nikto found within REQUEST_HEADERS:User-Agent: nikto/2.5.0", tags: []string{"attack-reputation-scanner", "paranoia-level/1", "OWASP_CRS", "capec/1000/118/224/541/310"},
+		uri: "/admin/config.php", agent: "Nikto/2.5.0",
+	},
+}
+
+// numericHost is matched by requests addressing the host by its IP, it isn't enough to block them.
+var numericHost = rule{
+	id: "920350", file: "REQUEST-920-PROTOCOL-ENFORCEMENT.conf", line: 777, severity: 4, score: 3,
+	message: "Host header is a numeric IP address", match: "Matched \"Operator `Rx' with parameter `^[\\d.:]+$' against variable `REQUEST_HEADERS:Host'",
+	tags: []string{"attack-protocol", "paranoia-level/1", "OWASP_CRS", "capec/1000/210/272"},
+	uri:  "/", agent: "Mozilla/5.0 zgrab/0.x",
+}
+
+// loginFailures is a custom rule counting the failed logins of each user agent, it catches
+// credential stuffing tools spreading their requests over many addresses.
+var loginFailures = rule{
+	id: "100010", file: "/etc/modsecurity/rules/login-protection.conf", line: 12, severity: 2, score: 5,
+	message: "Excessive failed logins from the same user agent", tags: []string{"attack-credential-stuffing", "custom"},
+	uri: "/login", agent: "python-requests/2.31.0",
+}
+
+// blocking is the rule blocking requests whose anomaly score reaches the threshold.
+func blocking(score int) rule {
+	return rule{
+		id: "949110", file: "REQUEST-949-BLOCKING-EVALUATION.conf", line: 222, severity: 0,
+		message: fmt.Sprintf("Inbound Anomaly Score Exceeded (Total Score: %d)", score),
+		match:   fmt.Sprintf("Matched \"Operator `Ge' with parameter `5' against variable `TX:BLOCKING_INBOUND_ANOMALY_SCORE' (Value: `%d' )", score),
+		tags:    []string{"anomaly-evaluation", "OWASP_CRS"},
+	}
+}
+
+// transaction returns a transaction of a request of client to uri matching rules, blocked with
+// status when the rules reach the anomaly threshold.
+func (h *Host) transaction(t time.Time, client, method, uri, agent string, status int, rules ...rule) WAFEvent {
+	score := 0
+	messages := make([]Message, 0, len(rules)+1)
+	for _, r := range rules {
+		score += r.score
+		messages = append(messages, r.Message())
+	}
+	if score >= 5 {
+		messages = append(messages, blocking(score).Message())
+	} else {
+		status = 200
+	}
+	sum := sha1.Sum([]byte(h.Name))
+	headers := map[string]string{"Host": flog.Authority, "User-Agent": agent, "Accept": "*/*"}
+	if method == "POST" {
+		headers["Content-Type"] = "application/x-www-form-urlencoded"
+	}
+	return WAFEvent{Transaction: Transaction{
+		ClientIP: client, TimeStamp: t.Format(time.ANSIC), ServerID: hex.EncodeToString(sum[:]), ClientPort: clientPort(),
+		HostIP: h.Address, HostPort: 443, UniqueID: fmt.Sprintf("%d%02d.%06d", t.Unix(), t.Nanosecond()/int(10*time.Millisecond), rand.Intn(1000000)),
+		Request:  WAFRequest{Method: method, HTTPVersion: 1.1, URI: uri, Headers: headers},
+		Response: WAFResponse{HTTPCode: status, Headers: map[string]string{"Server": "nginx", "Content-Type": "text/html"}},
+		Producer: Producer{
+			ModSecurity: "ModSecurity v3.0.12 (Linux)", Connector: "ModSecurity-nginx v1.0.3",
+			SecRulesEngine: "Enabled", Components: []string{crsVersion},
+		},
+		Messages: messages,
+	}}
+}
+
+// WAF returns a transaction of the firewall of the host at t. Requests to the IP of the host are
+// logged at info without being blocked, attacks from the clients of the access logs are
+// blocked at warn and above.
+func (h *Host) WAF(t time.Time, level model.LabelValue) WAFEvent {
+	if level == log.INFO || level == log.DEBUG || level == log.TRACE {
+		e := h.transaction(t, gofakeit.IPv4Address(), "GET", numericHost.uri, numericHost.agent, 200, numericHost)
+		e.Transaction.Request.Headers["Host"] = h.Address
+		e.Transaction.Messages[0].Details.Data = "Matched Data: " + h.Address + " found within REQUEST_HEADERS:Host: " + h.Address
+		return e
+	}
+	r := attackRules[rand.Intn(len(attackRules))]
+	return h.transaction(t, flog.FakeIP(), "GET", r.uri, r.agent, 403, r)
+}
+
+// CredentialStuffing returns a transaction of a credential stuffing attack at t: a login with a
+// leaked email from a random address. The tool reuses its user agent, so its failed logins add
+// up past the threshold of the custom rule and the firewall blocks it.
+func (h *Host) CredentialStuffing(t time.Time) WAFEvent {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.loginFailures++
+	r := loginFailures
+	email := gofakeit.Email()
+	r.match = fmt.Sprintf("Matched \"Operator `Ge' with parameter `20' against variable `USER:LOGIN_FAILURES' (Value: `%d' )", 20+h.loginFailures)
+	r.data = fmt.Sprintf("Matched Data: %s found within ARGS_POST:username: %s", email, email)
+	return h.transaction(t, gofakeit.IPv4Address(), "POST", r.uri, r.agent, 429, r)
+}
+
+// Level returns the level of the transaction, warn for blocked requests.
+func (e WAFEvent) Level() model.LabelValue {
+	if e.Transaction.Response.HTTPCode >= 400 {
+		return log.WARN
+	}
+	return log.INFO
+}
+
+// String returns the transaction as a line of the JSON audit log.
+func (e WAFEvent) String() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
@@ -0,0 +1,137 @@
+package session
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/grafana/explore-logs/generator/dist"
+)
+
+// action is a page of the shop, call returns the operation served by the backend.
+type action struct {
+	method string
+	uri    string
+	call   func(sess *Session) call
+	// sku is added to the cart when the request succeeds.
+	sku string
+}
+
+var skus = func() []string {
+	out := make([]string, 50)
+	for i := range out {
+		out[i] = fmt.Sprintf("SKU-%05d", rand.Intn(100000))
+	}
+	return out
+}()
+
+var skuDist = dist.NewZipf(1.1, 0)
+
+func randSKU() string {
+	return skus[dist.Pick(skuDist, len(skus))]
+}
+
+var (
+	fast   = dist.LogNormal{Median: 0.002, Sigma: 0.5}
+	medium = dist.LogNormal{Median: 0.01, Sigma: 0.8}
+	slow   = dist.LogNormal{Median: 0.05, Sigma: 1}
+)
+
+func cacheGet(key string) call {
+	return call{service: "cache", op: "GET", attrs: "key=" + key, latency: dist.LogNormal{Median: 0.0003, Sigma: 0.5}}
+}
+
+func cacheSet(key string) call {
+	return call{service: "cache", op: "SET", attrs: "key=" + key, latency: dist.LogNormal{Median: 0.0004, Sigma: 0.5}}
+}
+
+func query(statement string, rows int) call {
+	return call{
+		service: "db", op: "query", attrs: fmt.Sprintf("statement=%q rows=%d", statement, rows), latency: medium,
+		errRate: 0.005, err: "context deadline exceeded", status: 500,
+	}
+}
+
+func viewProduct(sku string) action {
+	return action{method: "GET", uri: "/products/" + sku, call: func(*Session) call {
+		c := call{service: "api", op: "get product", attrs: "sku=" + sku, latency: fast, calls: []call{cacheGet("product:" + sku)}}
+		if rand.Intn(10) == 0 {
+			c.attrs += " cache=miss"
+			c.calls = append(c.calls, query("SELECT * FROM products WHERE sku = $1", 1))
+		}
+		return c
+	}}
+}
+
+func addToCart(sku string) action {
+	return action{method: "POST", uri: "/cart/items", sku: sku, call: func(sess *Session) call {
+		return call{
+			service: "cart", op: "add item", attrs: fmt.Sprintf("sku=%s qty=%d items=%d", sku, 1+rand.Intn(3), len(sess.cart)+1), latency: fast,
+			calls: []call{query("INSERT INTO cart_items (session_id, sku, qty) VALUES ($1, $2, $3)", 1), cacheSet("cart:" + sess.ID)},
+		}
+	}}
+}
+
+func viewCart() action {
+	return action{method: "GET", uri: "/cart", call: func(sess *Session) call {
+		return getCart(sess)
+	}}
+}
+
+func getCart(sess *Session) call {
+	return call{service: "cart", op: "get cart", attrs: fmt.Sprintf("items=%d", len(sess.cart)), latency: fast, calls: []call{cacheGet("cart:" + sess.ID)}}
+}
+
+func checkout() action {
+	return action{method: "POST", uri: "/checkout", call: func(sess *Session) call {
+		amount := float64(len(sess.cart)) * (5 + rand.Float64()*95)
+		order := fmt.Sprintf("ORD-%d", 100000+rand.Intn(900000))
+		return call{
+			service: "checkout", op: "place order", attrs: fmt.Sprintf("order_id=%s items=%d skus=%s", order, len(sess.cart), strings.Join(sess.cart, ",")), latency: medium,
+			calls: []call{
+				getCart(sess),
+				{
+					service: "payment", op: "charge card", attrs: fmt.Sprintf("order_id=%s amount=%.2f currency=USD", order, amount), latency: slow,
+					calls:   []call{query("INSERT INTO payments (order_id, amount) VALUES ($1, $2)", 1)},
+					errRate: 0.05, err: "card declined", status: 402,
+				},
+				query("INSERT INTO orders (id, user_id, amount) VALUES ($1, $2, $3)", 1),
+			},
+		}
+	}}
+}
+
+type state int
+
+const (
+	browsing state = iota
+	shopping
+	done
+)
+
+// next picks the next page of a user and the state once it is served.
+func (s state) next(sess *Session) (action, state) {
+	r := rand.Float64()
+	switch s {
+	case browsing:
+		switch {
+		case r < 0.6:
+			return viewProduct(randSKU()), browsing
+		case r < 0.85:
+			return addToCart(randSKU()), shopping
+		}
+		return viewProduct(randSKU()), done
+	default:
+		switch {
+		case r < 0.3:
+			return viewProduct(randSKU()), shopping
+		case r < 0.5:
+			return addToCart(randSKU()), shopping
+		case r < 0.65:
+			return viewCart(), shopping
+		case r < 0.9:
+			return checkout(), done
+		}
+		return viewCart(), done
+	}
+}
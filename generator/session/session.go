@@ -0,0 +1,290 @@
+// Package session simulates users browsing a shop. Each request of a session goes through the
+// gateway to the backend services and down to the databases, and every service logs it with the
+// same trace, user and session IDs, at causally ordered timestamps.
+package session
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/dist"
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/load"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// Gateway is the service receiving the requests of the users.
+const Gateway = "gateway"
+
+// Options configures the simulation.
+type Options struct {
+	// Namespace is the namespace label of the services, "shop" by default.
+	Namespace string
+	// Clusters are the clusters users are routed to, a session stays in one cluster. log.Clusters by default.
+	Clusters []string
+	// Rate is the mean number of new sessions per second, 0.5 by default.
+	Rate float64
+	// ThinkTime is the time users wait between two requests, in seconds.
+	ThinkTime dist.Distribution
+}
+
+// Entry is a log line of a service.
+type Entry struct {
+	Service  model.LabelValue
+	Level    model.LabelValue
+	Time     time.Time
+	Line     string
+	Metadata push.LabelsAdapter
+}
+
+// Simulator starts sessions and writes their entries to a logger.
+type Simulator struct {
+	opts   Options
+	logger log.Logger
+
+	mtx  sync.Mutex
+	apps map[string]*log.AppLogger
+	pods map[string]string
+}
+
+// NewSimulator creates a simulator writing to logger.
+func NewSimulator(logger log.Logger, opts Options) *Simulator {
+	if opts.Namespace == "" {
+		opts.Namespace = "shop"
+	}
+	if len(opts.Clusters) == 0 {
+		opts.Clusters = log.Clusters
+	}
+	if opts.Rate <= 0 {
+		opts.Rate = 0.5
+	}
+	if opts.ThinkTime == nil {
+		opts.ThinkTime = dist.LogNormal{Median: 3, Sigma: 1}
+	}
+	return &Simulator{
+		opts:   opts,
+		logger: logger,
+		apps:   map[string]*log.AppLogger{},
+		pods:   map[string]string{},
+	}
+}
+
+// Start starts new sessions until ctx is done.
+func (s *Simulator) Start(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			go s.run(ctx, s.NewSession())
+			load.Sleep(time.Duration(rand.ExpFloat64() / s.opts.Rate * float64(time.Second)))
+		}
+	}()
+}
+
+func (s *Simulator) run(ctx context.Context, sess *Session) {
+	for ctx.Err() == nil {
+		entries, ok := sess.Next(load.Now())
+		for _, e := range entries {
+			load.SleepUntil(e.Time)
+			s.app(sess.Cluster, e.Service).LogWithMetadata(e.Level, e.Time, e.Line, e.Metadata)
+		}
+		if !ok {
+			return
+		}
+		load.SleepUntil(load.Now().Add(time.Duration(dist.Between(s.opts.ThinkTime, 0, 600) * float64(time.Second))))
+	}
+}
+
+func (s *Simulator) app(cluster string, service model.LabelValue) *log.AppLogger {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key := cluster + "/" + string(service)
+	app, ok := s.apps[key]
+	if !ok {
+		app = log.NewAppLogger(model.LabelSet{
+			"cluster":      model.LabelValue(cluster),
+			"namespace":    model.LabelValue(s.opts.Namespace),
+			"service_name": service,
+		}, s.logger)
+		s.apps[key] = app
+	}
+	return app
+}
+
+// pod returns the pod of a service in a cluster, pods are stable so their logs can be followed.
+func (s *Simulator) pod(cluster string, service string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key := cluster + "/" + service
+	pod, ok := s.pods[key]
+	if !ok {
+		pod = service + "-" + log.RandSeq(5)
+		s.pods[key] = pod
+	}
+	return pod
+}
+
+// NewSession starts the session of a random user.
+func (s *Simulator) NewSession() *Session {
+	return &Session{
+		ID:      log.RandSeq(16),
+		UserID:  log.RandUserID(),
+		Cluster: s.opts.Clusters[rand.Intn(len(s.opts.Clusters))],
+		IP:      flog.FakeIP(),
+		Agent:   gofakeit.UserAgent(),
+		state:   browsing,
+		sim:     s,
+	}
+}
+
+// Session is the visit of a user, a series of requests made by the same browser.
+type Session struct {
+	ID      string
+	UserID  string
+	Cluster string
+	IP      string
+	Agent   string
+
+	state state
+	cart  []string
+	sim   *Simulator
+}
+
+// Next returns the entries of the next request of the session made at t, sorted by time. It
+// returns false when the user leaves after this request.
+func (sess *Session) Next(t time.Time) ([]Entry, bool) {
+	action, next := sess.state.next(sess)
+	r := &request{
+		sess:    sess,
+		traceID: strings.ReplaceAll(gofakeit.UUID(), "-", ""),
+	}
+	status, end := r.call(action.call(sess), t)
+	end = end.Add(seconds(hop))
+
+	req := flog.NewRequest(end, action.uri, status)
+	req.Host, req.Agent, req.Method, req.User = sess.IP, sess.Agent, action.method, "-"
+	req.Referer = "-"
+	req.Duration = end.Sub(t).Round(time.Millisecond)
+	req.Upstream = max(req.Duration-time.Millisecond, 0)
+	req.Timing = true
+	r.log(Gateway, statusLevel(status), end, req.ApacheCombined())
+
+	if status < 400 {
+		if action.sku != "" {
+			sess.cart = append(sess.cart, action.sku)
+		}
+		sess.state = next
+	}
+	sort.SliceStable(r.entries, func(i, j int) bool { return r.entries[i].Time.Before(r.entries[j].Time) })
+	return r.entries, sess.state != done
+}
+
+func statusLevel(status int) model.LabelValue {
+	switch {
+	case status >= 500:
+		return log.ERROR
+	case status >= 400:
+		return log.WARN
+	}
+	return log.INFO
+}
+
+// request collects the entries of a request while it flows through the services.
+type request struct {
+	sess    *Session
+	traceID string
+	entries []Entry
+}
+
+func (r *request) log(service string, level model.LabelValue, t time.Time, line string) {
+	r.entries = append(r.entries, Entry{
+		Service: model.LabelValue(service),
+		Level:   level,
+		Time:    t,
+		Line:    line,
+		Metadata: push.LabelsAdapter{
+			{Name: "traceID", Value: r.traceID},
+			{Name: "pod", Value: r.sess.sim.pod(r.sess.Cluster, service)},
+			{Name: "user", Value: r.sess.UserID},
+			{Name: "session_id", Value: r.sess.ID},
+		},
+	})
+}
+
+// call is an operation of a service, it runs its calls one after the other and then does its own work.
+type call struct {
+	service string
+	op      string
+	// attrs are logged with the operation.
+	attrs string
+	// latency is the time spent by the service itself, in seconds.
+	latency dist.Distribution
+	calls   []call
+	// errRate is the probability the operation fails with err and status.
+	errRate float64
+	err     string
+	status  int
+}
+
+// hop is the network latency between two services.
+var hop = dist.LogNormal{Median: 0.0005, Sigma: 0.5}
+
+// call runs c starting at t and returns the status and the time it ended.
+func (r *request) call(c call, t time.Time) (int, time.Time) {
+	start := t.Add(seconds(hop))
+	end := start
+	spanID := log.RandSeq(16)
+	status := 200
+	for _, child := range c.calls {
+		childStatus, childEnd := r.call(child, end)
+		end = childEnd.Add(seconds(hop))
+		if childStatus >= 400 {
+			status = childStatus
+			if childStatus >= 500 {
+				status = 502
+				if child.service == "db" {
+					status = 500
+				}
+			}
+			r.logf(c.service, statusLevel(status), end, spanID, c, status, start, end, fmt.Sprintf(`error="%s call failed" upstream_status=%d`, child.service, childStatus))
+			return status, end
+		}
+	}
+	end = end.Add(seconds(c.latency))
+	if rand.Float64() < c.errRate {
+		r.logf(c.service, statusLevel(c.status), end, spanID, c, c.status, start, end, fmt.Sprintf("error=%q", c.err))
+		return c.status, end
+	}
+	r.logf(c.service, log.INFO, end, spanID, c, status, start, end, "")
+	return status, end
+}
+
+func (r *request) logf(service string, level model.LabelValue, t time.Time, spanID string, c call, status int, start, end time.Time, extra string) {
+	fields := []string{
+		"ts=" + t.UTC().Format(time.RFC3339Nano),
+		"level=" + string(level),
+		"trace_id=" + r.traceID,
+		"span_id=" + spanID,
+		"session_id=" + r.sess.ID,
+		"user=" + r.sess.UserID,
+		fmt.Sprintf("msg=%q", c.op),
+	}
+	if c.attrs != "" {
+		fields = append(fields, c.attrs)
+	}
+	fields = append(fields, fmt.Sprintf("status=%d", status), "duration="+end.Sub(start).Round(time.Microsecond).String())
+	if extra != "" {
+		fields = append(fields, extra)
+	}
+	r.log(service, level, t, strings.Join(fields, " "))
+}
+
+func seconds(d dist.Distribution) time.Duration {
+	return time.Duration(dist.Between(d, 0, 60) * float64(time.Second))
+}
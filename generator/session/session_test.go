@@ -0,0 +1,140 @@
+package session
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func metadata(e Entry, name string) string {
+	for _, m := range e.Metadata {
+		if m.Name == name {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+func TestSessionRequests(t *testing.T) {
+	a := assert.New(t)
+	sim := NewSimulator(nil, Options{Clusters: []string{"eu-west-1"}})
+	sess := sim.NewSession()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	services := map[model.LabelValue]bool{}
+	traces := map[string]bool{}
+	for i, more := 0, true; more && i < 100; i++ {
+		var entries []Entry
+		entries, more = sess.Next(start)
+		require.NotEmpty(t, entries)
+
+		// The gateway logs the request once all the services are done.
+		last := entries[len(entries)-1]
+		a.Equal(model.LabelValue(Gateway), last.Service)
+		a.Contains(last.Line, "request_time=")
+		trace := metadata(last, "traceID")
+		a.False(traces[trace], "each request has its own trace")
+		traces[trace] = true
+
+		for j, e := range entries {
+			services[e.Service] = true
+			a.Equal(trace, metadata(e, "traceID"))
+			a.Equal(sess.UserID, metadata(e, "user"))
+			a.Equal(sess.ID, metadata(e, "session_id"))
+			a.True(strings.HasPrefix(metadata(e, "pod"), string(e.Service)+"-"))
+			a.True(e.Time.After(start))
+			if j > 0 {
+				a.True(e.Time.After(entries[j-1].Time), "entries are ordered")
+			}
+			if e.Service != Gateway {
+				a.Contains(e.Line, "trace_id="+trace)
+				a.Contains(e.Line, "session_id="+sess.ID)
+			}
+		}
+		start = last.Time.Add(time.Second)
+	}
+	a.True(services["api"] || services["cart"])
+	a.True(services["cache"])
+}
+
+func TestCartOnlyKeepsAddedItems(t *testing.T) {
+	a := assert.New(t)
+	sim := NewSimulator(nil, Options{Clusters: []string{"eu-west-1"}})
+	added := 0
+	for i := 0; i < 200; i++ {
+		sess := sim.NewSession()
+		for more := true; more; {
+			before := len(sess.cart)
+			var entries []Entry
+			entries, more = sess.Next(time.Now())
+			gateway := entries[len(entries)-1]
+			if !strings.Contains(gateway.Line, `"POST /cart/items `) {
+				a.Equal(before, len(sess.cart))
+				continue
+			}
+			if gateway.Level == log.INFO {
+				a.Equal(before+1, len(sess.cart))
+				added++
+			} else {
+				a.Equal(before, len(sess.cart), "failed requests don't add items")
+			}
+		}
+	}
+	a.Positive(added)
+}
+
+func TestCallErrors(t *testing.T) {
+	a := assert.New(t)
+	sess := NewSimulator(nil, Options{}).NewSession()
+	r := &request{sess: sess, traceID: "trace"}
+	db := query("SELECT 1", 1)
+	db.errRate = 1
+	status, _ := r.call(call{service: "checkout", op: "place order", latency: fast, calls: []call{db}}, time.Now())
+	a.Equal(500, status)
+	require.Len(t, r.entries, 2)
+	a.Equal(model.LabelValue("db"), r.entries[0].Service)
+	a.Contains(r.entries[0].Line, `error="context deadline exceeded"`)
+	a.Equal(log.ERROR, r.entries[1].Level)
+	a.Contains(r.entries[1].Line, "upstream_status=500")
+
+	r = &request{sess: sess, traceID: "trace"}
+	status, _ = r.call(call{service: "checkout", op: "place order", latency: fast, calls: []call{
+		{service: "payment", op: "charge card", latency: fast, errRate: 1, err: "card declined", status: 402},
+		db,
+	}}, time.Now())
+	a.Equal(402, status)
+	require.Len(t, r.entries, 2, "calls stop at the first failure")
+	a.Equal(log.WARN, r.entries[1].Level)
+}
+
+func TestSimulatorStart(t *testing.T) {
+	var mtx sync.Mutex
+	var got []model.LabelSet
+	logger := log.LoggerFunc(func(labels model.LabelSet, _ time.Time, _ string, _ push.LabelsAdapter) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		got = append(got, labels)
+		return nil
+	})
+	sim := NewSimulator(logger, Options{Namespace: "test-shop", Rate: 100})
+	sim.Start(t.Context())
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(got) > 10
+	}, 5*time.Second, 10*time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	for _, labels := range got {
+		assert.Equal(t, model.LabelValue("test-shop"), labels["namespace"])
+		assert.Contains(t, log.Clusters, string(labels["cluster"]))
+	}
+}
@@ -0,0 +1,103 @@
+package stacktrace
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Entry returns the log entry of a service failing a request with the trace, as its framework
+// logs it: a header line followed by the trace.
+func (t Trace) Entry(ts time.Time, method, path string) string {
+	var header string
+	switch t.Language {
+	case Java:
+		header = fmt.Sprintf("%s ERROR 1 --- [nio-8080-exec-%d] %s : Servlet.service() for servlet [dispatcherServlet] threw exception",
+			ts.Format("2006-01-02 15:04:05.000"), 1+rand.Intn(10), javaLogger(t))
+	case Go:
+		header = fmt.Sprintf("%s http: panic serving 10.0.%d.%d:%d: %s", ts.Format("2006/01/02 15:04:05"), rand.Intn(16), 2+rand.Intn(250), 30000+rand.Intn(30000), firstLine(t.Message))
+		// net/http doesn't repeat the panic line.
+		return header + "\n" + strings.Join(t.Lines[strings.Count(t.Message, "\n")+2:], "\n")
+	case Python:
+		header = fmt.Sprintf("%s ERROR [app] Exception on %s [%s]", ts.Format("2006-01-02 15:04:05,000"), path, method)
+	case Node:
+		header = fmt.Sprintf("%s error: Unhandled error in %s %s", ts.UTC().Format("2006-01-02T15:04:05.000Z"), method, path)
+	case DotNet:
+		header = "fail: Microsoft.AspNetCore.Diagnostics.ExceptionHandlerMiddleware[1]\n      An unhandled exception has occurred while executing the request."
+	case Ruby:
+		header = fmt.Sprintf("E, [%s #1] ERROR -- : [%s] ", ts.Format("2006-01-02T15:04:05.000000"), requestID())
+	}
+	return header + "\n" + t.String()
+}
+
+// Access returns the log line of a service completing a request.
+func Access(lang Language, ts time.Time, method, path string, status int, d time.Duration) string {
+	switch lang {
+	case Java:
+		return fmt.Sprintf("%s  INFO 1 --- [nio-8080-exec-%d] o.s.web.servlet.DispatcherServlet : Completed %d %s %s in %d ms",
+			ts.Format("2006-01-02 15:04:05.000"), 1+rand.Intn(10), status, method, path, d.Milliseconds())
+	case Go:
+		return fmt.Sprintf(`level=info ts=%s caller=http.go:%d msg="request completed" method=%s path=%s status=%d duration=%s`,
+			ts.UTC().Format(time.RFC3339Nano), 120+rand.Intn(5), method, path, status, d)
+	case Python:
+		return fmt.Sprintf(`%s INFO [werkzeug] 10.0.%d.%d - - "%s %s HTTP/1.1" %d -`, ts.Format("2006-01-02 15:04:05,000"), rand.Intn(16), 2+rand.Intn(250), method, path, status)
+	case Node:
+		return fmt.Sprintf("%s info: %s %s %d %.3f ms", ts.UTC().Format("2006-01-02T15:04:05.000Z"), method, path, status, float64(d.Microseconds())/1000)
+	case DotNet:
+		return fmt.Sprintf("info: Microsoft.AspNetCore.Hosting.Diagnostics[2]\n      Request finished HTTP/1.1 %s http://localhost:8080%s - %d - application/json %.4fms", method, path, status, float64(d.Microseconds())/1000)
+	case Ruby:
+		return fmt.Sprintf("I, [%s #1]  INFO -- : [%s] Completed %d OK in %dms (Views: %.1fms | ActiveRecord: %.1fms)",
+			ts.Format("2006-01-02T15:04:05.000000"), requestID(), status, d.Milliseconds(), float64(d.Milliseconds())/3, float64(d.Milliseconds())/2)
+	}
+	panic(fmt.Sprintf("unknown language %q", lang))
+}
+
+// Split returns the lines of an entry as separate entries, like a log shipper without
+// multiline support would, each one a nanosecond after the previous one to keep their order.
+func Split(ts time.Time, entry string) ([]time.Time, []string) {
+	lines := strings.Split(entry, "\n")
+	times := make([]time.Time, len(lines))
+	for i := range lines {
+		times[i] = ts.Add(time.Duration(i))
+	}
+	return times, lines
+}
+
+// javaLogger returns the abbreviated logger name of the outermost frame, like logback does.
+func javaLogger(t Trace) string {
+	for _, line := range t.Lines[1:] {
+		frame, ok := strings.CutPrefix(line, "\tat ")
+		if !ok {
+			continue
+		}
+		frame, _, _ = strings.Cut(frame, "(")
+		parts := strings.Split(frame, ".")
+		// Drop the method, abbreviate the packages.
+		parts = parts[:len(parts)-1]
+		for i := 0; i < len(parts)-1; i++ {
+			parts[i] = parts[i][:1]
+		}
+		return strings.Join(parts, ".")
+	}
+	return "o.a.c.c.C.[.[.[/].[dispatcherServlet]"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func requestID() string {
+	const hex = "0123456789abcdef"
+	b := make([]byte, 36)
+	for i := range b {
+		switch i {
+		case 8, 13, 18, 23:
+			b[i] = '-'
+		default:
+			b[i] = hex[rand.Intn(len(hex))]
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,418 @@
+// Package stacktrace generates the stack traces of Java, Go, Python, Node.js, .NET and Ruby
+// services. Traces are derived from an error fingerprint: the same fingerprint always gives the
+// same exceptions, frames and line numbers, so recurring errors group together, while addresses
+// and goroutine IDs change like they do between two real crashes.
+package stacktrace
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// Language is the runtime of a service, it decides the format of its traces.
+type Language string
+
+const (
+	Java   Language = "java"
+	Go     Language = "go"
+	Python Language = "python"
+	Node   Language = "nodejs"
+	DotNet Language = "dotnet"
+	Ruby   Language = "ruby"
+)
+
+// Languages lists all supported languages.
+var Languages = []Language{Java, Go, Python, Node, DotNet, Ruby}
+
+// Trace is the stack trace of an error.
+type Trace struct {
+	Language Language
+	// Type and Message are those of the outermost exception, Type is empty for Go panics.
+	Type, Message string
+	Lines         []string
+}
+
+// String returns the trace as one multiline string.
+func (t Trace) String() string {
+	return strings.Join(t.Lines, "\n")
+}
+
+// New returns the trace of the error with the given fingerprint in a service written in lang.
+// The service names the packages of the frames.
+func New(lang Language, service, fingerprint string) Trace {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(string(lang) + "/" + service + "/" + fingerprint))
+	g := &gen{rnd: rand.New(rand.NewSource(int64(h.Sum64()))), service: service}
+	switch lang {
+	case Java:
+		return g.java()
+	case Go:
+		return g.golang()
+	case Python:
+		return g.python()
+	case Node:
+		return g.node()
+	case DotNet:
+		return g.dotnet()
+	case Ruby:
+		return g.ruby()
+	}
+	panic(fmt.Sprintf("unknown language %q", lang))
+}
+
+var (
+	domains = []string{"cart", "order", "payment", "user", "inventory", "shipping", "catalog", "session", "invoice", "auth"}
+	layers  = []string{"Controller", "Service", "Repository", "Client", "Handler", "Validator", "Mapper"}
+	verbs   = []string{"get", "load", "compute", "validate", "process", "save", "find", "update", "resolve", "build"}
+	nouns   = []string{"Total", "Items", "Price", "Address", "Token", "Customer", "Discount", "Stock", "Rate", "Status"}
+)
+
+// frame is a function of the application.
+type frame struct {
+	domain, layer, verb, noun string
+	line, column              int
+}
+
+// Type returns the type of the frame, e.g. CartService.
+func (f frame) Type() string {
+	return title(f.domain) + f.layer
+}
+
+// Method returns the method in camel case, e.g. computeTotal.
+func (f frame) Method() string {
+	return f.verb + f.noun
+}
+
+// Snake returns the method in snake case, e.g. compute_total.
+func (f frame) Snake() string {
+	return f.verb + "_" + strings.ToLower(f.noun)
+}
+
+// gen draws the parts of a trace from the random source of its fingerprint.
+type gen struct {
+	rnd     *rand.Rand
+	service string
+}
+
+func (g *gen) pick(values []string) string {
+	return values[g.rnd.Intn(len(values))]
+}
+
+// frames returns n frames of a request going down the layers of the application.
+func (g *gen) frames(n int) []frame {
+	domain := g.pick(domains)
+	out := make([]frame, n)
+	for i := range out {
+		out[i] = frame{
+			domain: domain,
+			layer:  layers[(n-1-i)%len(layers)],
+			verb:   g.pick(verbs),
+			noun:   g.pick(nouns),
+			line:   10 + g.rnd.Intn(400),
+			column: 5 + g.rnd.Intn(40),
+		}
+	}
+	return out
+}
+
+// exception is an error type and a message where {noun}, {domain}, {field} and {n} are replaced.
+type exception struct {
+	typ, message string
+}
+
+func (g *gen) exception(exceptions []exception, f frame) exception {
+	e := exceptions[g.rnd.Intn(len(exceptions))]
+	e.message = strings.NewReplacer(
+		"{noun}", f.noun,
+		"{domain}", f.domain,
+		"{Domain}", title(f.domain),
+		"{field}", strings.ToLower(f.noun),
+		"{n}", fmt.Sprint(g.rnd.Intn(5000)),
+		"{ip}", fmt.Sprintf("10.0.%d.%d", g.rnd.Intn(16), 2+g.rnd.Intn(250)),
+	).Replace(e.message)
+	return e
+}
+
+// pkg returns the service name as a package name.
+func (g *gen) pkg() string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, g.service)
+}
+
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+var (
+	javaExceptions = []exception{
+		{"java.lang.NullPointerException", `Cannot invoke "com.example.model.{noun}.getValue()" because the return value of "get{noun}()" is null`},
+		{"java.lang.IllegalStateException", "{Domain} {n} not found"},
+		{"java.lang.IllegalArgumentException", "Invalid {field}: -{n}"},
+		{"java.util.ConcurrentModificationException", ""},
+		{"org.springframework.dao.DataAccessResourceFailureException", "Unable to acquire JDBC Connection"},
+		{"java.lang.ArrayIndexOutOfBoundsException", "Index {n} out of bounds for length 0"},
+	}
+	javaCauses = []exception{
+		{"java.net.SocketTimeoutException", "Read timed out"},
+		{"java.sql.SQLTransientConnectionException", "HikariPool-1 - Connection is not available, request timed out after 30000ms."},
+		{"java.io.IOException", "Broken pipe"},
+		{"java.net.ConnectException", "Connection refused"},
+	}
+	javaFramework = []string{
+		"org.springframework.web.servlet.FrameworkServlet.processRequest(FrameworkServlet.java:1014)",
+		"org.springframework.web.servlet.FrameworkServlet.doPost(FrameworkServlet.java:914)",
+		"jakarta.servlet.http.HttpServlet.service(HttpServlet.java:590)",
+		"org.apache.catalina.core.ApplicationFilterChain.doFilter(ApplicationFilterChain.java:138)",
+		"org.apache.tomcat.util.net.NioEndpoint$SocketProcessor.doRun(NioEndpoint.java:1744)",
+		"java.base/java.util.concurrent.ThreadPoolExecutor.runWorker(ThreadPoolExecutor.java:1144)",
+		"java.base/java.lang.Thread.run(Thread.java:1583)",
+	}
+)
+
+func (g *gen) java() Trace {
+	frames := g.frames(3 + g.rnd.Intn(4))
+	javaFrame := func(f frame) string {
+		return fmt.Sprintf("\tat com.example.%s.%s.%s.%s(%s.java:%d)", g.pkg(), f.domain, f.Type(), f.Method(), f.Type(), f.line)
+	}
+	e := g.exception(javaExceptions, frames[0])
+	t := Trace{Language: Java, Type: e.typ, Message: e.message}
+	t.Lines = append(t.Lines, javaHeader(e))
+	for _, f := range frames {
+		t.Lines = append(t.Lines, javaFrame(f))
+	}
+	for _, f := range javaFramework {
+		t.Lines = append(t.Lines, "\tat "+f)
+	}
+	// Causes repeat the frames of the exceptions wrapping them, which Java folds into "... n more".
+	for causes := g.rnd.Intn(3); causes > 0; causes-- {
+		c := g.exception(javaCauses, frames[0])
+		t.Lines = append(t.Lines, "Caused by: "+javaHeader(c))
+		for _, f := range g.frames(1 + g.rnd.Intn(3)) {
+			t.Lines = append(t.Lines, javaFrame(f))
+		}
+		t.Lines = append(t.Lines, fmt.Sprintf("\t... %d more", len(frames)+len(javaFramework)))
+	}
+	return t
+}
+
+func javaHeader(e exception) string {
+	if e.message == "" {
+		return e.typ
+	}
+	return e.typ + ": " + e.message
+}
+
+var (
+	goPanics = []string{
+		"runtime error: invalid memory address or nil pointer dereference\n[signal SIGSEGV: segmentation violation code=0x1 addr=0x{n} pc=0x4a3f{n}]",
+		"runtime error: index out of range [{n}] with length 0",
+		"assignment to entry in nil map",
+		"runtime error: slice bounds out of range [:{n}] with capacity 16",
+		"interface conversion: interface {} is nil, not *{domain}.{noun}",
+		"sync: negative WaitGroup counter",
+	}
+	// goBlocked are the other goroutines of a dump.
+	goBlocked = [][]string{
+		{"IO wait", "internal/poll.runtime_pollWait(0x7f2c{n}, 0x72)", "\t/usr/local/go/src/runtime/netpoll.go:351 +0x85", "net.(*netFD).accept(0xc000{n})", "\t/usr/local/go/src/net/fd_unix.go:172 +0x29"},
+		{"select", "google.golang.org/grpc.(*addrConn).resetTransportAndUnlock(0xc000{n})", "\t/go/pkg/mod/google.golang.org/grpc@v1.66.0/clientconn.go:1291 +0x2c5"},
+		{"chan receive", "github.com/prometheus/client_golang/prometheus.(*Registry).Gather(0xc000{n})", "\t/go/pkg/mod/github.com/prometheus/client_golang@v1.20.2/prometheus/registry.go:474 +0x1f7"},
+		{"sync.Mutex.Lock", "sync.runtime_SemacquireMutex(0xc000{n}, 0x0, 0x1)", "\t/usr/local/go/src/runtime/sema.go:95 +0x25", "sync.(*Mutex).lockSlow(0xc000{n})", "\t/usr/local/go/src/sync/mutex.go:173 +0x15d"},
+	}
+)
+
+// addr returns a random pointer, they change between two panics.
+func addr() string {
+	return fmt.Sprintf("0xc000%06x", rand.Intn(1<<24))
+}
+
+func (g *gen) golang() Trace {
+	frames := g.frames(2 + g.rnd.Intn(4))
+	msg := g.exception([]exception{{message: g.pick(goPanics)}}, frames[0]).message
+	t := Trace{Language: Go, Message: msg}
+	t.Lines = append(t.Lines, strings.Split("panic: "+msg, "\n")...)
+	t.Lines = append(t.Lines, "", fmt.Sprintf("goroutine %d [running]:", 2+rand.Intn(5000)))
+	for _, f := range frames {
+		t.Lines = append(t.Lines,
+			fmt.Sprintf("github.com/example/%s/internal/%s.(*%s).%s(%s, {0x1c3e0a0, %s})", g.pkg(), f.domain, f.Type(), title(f.Method()), addr(), addr()),
+			fmt.Sprintf("\t/src/internal/%s/%s.go:%d +0x%x", f.domain, strings.ToLower(f.layer), f.line, f.column*8),
+		)
+	}
+	t.Lines = append(t.Lines,
+		fmt.Sprintf("net/http.HandlerFunc.ServeHTTP(%s, {0x1c40f58, %s}, %s)", addr(), addr(), addr()),
+		"\t/usr/local/go/src/net/http/server.go:2171 +0x29",
+		fmt.Sprintf("net/http.serverHandler.ServeHTTP({%s?}, {0x1c40f58?, %s?}, %s)", addr(), addr(), addr()),
+		"\t/usr/local/go/src/net/http/server.go:3210 +0x8e",
+		fmt.Sprintf("net/http.(*conn).serve(%s, {0x1c42a10, %s})", addr(), addr()),
+		"\t/usr/local/go/src/net/http/server.go:2092 +0x5d0",
+		"created by net/http.(*Server).Serve in goroutine 1",
+		"\t/usr/local/go/src/net/http/server.go:3360 +0x485",
+	)
+	// The dump of the other goroutines.
+	for i, n := 0, g.rnd.Intn(3); i < n; i++ {
+		blocked := goBlocked[g.rnd.Intn(len(goBlocked))]
+		t.Lines = append(t.Lines, "", fmt.Sprintf("goroutine %d [%s]:", 1+i*7+rand.Intn(7), blocked[0]))
+		for _, line := range blocked[1:] {
+			t.Lines = append(t.Lines, strings.ReplaceAll(line, "{n}", fmt.Sprintf("%06x", rand.Intn(1<<24))))
+		}
+	}
+	return t
+}
+
+var (
+	pythonExceptions = []exception{
+		{"KeyError", "'{field}'"},
+		{"AttributeError", "'NoneType' object has no attribute '{field}'"},
+		{"ValueError", "invalid literal for int() with base 10: '{field}'"},
+		{"TypeError", "unsupported operand type(s) for +: 'int' and 'NoneType'"},
+		{"ZeroDivisionError", "division by zero"},
+	}
+	pythonCauses = []exception{
+		{"sqlalchemy.exc.OperationalError", `(psycopg2.OperationalError) connection to server at "db" ({ip}), port 5432 failed: timeout expired`},
+		{"requests.exceptions.ConnectionError", "HTTPConnectionPool(host='{domain}', port=8080): Max retries exceeded with url: /api/{domain}/{n}"},
+		{"redis.exceptions.TimeoutError", "Timeout reading from socket"},
+	}
+)
+
+func (g *gen) python() Trace {
+	t := Trace{Language: Python}
+	// Python prints the cause first.
+	if g.rnd.Intn(3) == 0 {
+		frames := g.frames(1 + g.rnd.Intn(2))
+		c := g.exception(pythonCauses, frames[0])
+		t.Lines = append(t.Lines, g.pythonTraceback(frames, c)...)
+		t.Lines = append(t.Lines, "", "The above exception was the direct cause of the following exception:", "")
+	}
+	frames := g.frames(2 + g.rnd.Intn(4))
+	e := g.exception(pythonExceptions, frames[0])
+	t.Type, t.Message = e.typ, e.message
+	t.Lines = append(t.Lines, g.pythonTraceback(frames, e)...)
+	return t
+}
+
+func (g *gen) pythonTraceback(frames []frame, e exception) []string {
+	lines := []string{
+		"Traceback (most recent call last):",
+		`  File "/usr/local/lib/python3.12/site-packages/flask/app.py", line 1473, in wsgi_app`,
+		"    response = self.full_dispatch_request()",
+		`  File "/usr/local/lib/python3.12/site-packages/flask/app.py", line 880, in full_dispatch_request`,
+		"    rv = self.dispatch_request()",
+	}
+	// Python prints the innermost frame last.
+	for i := len(frames) - 1; i >= 0; i-- {
+		f := frames[i]
+		code := fmt.Sprintf("return self.%s.%s(%s_id)", strings.ToLower(layers[(len(frames)-i)%len(layers)]), frames[max(i-1, 0)].Snake(), f.domain)
+		if i == 0 {
+			code = fmt.Sprintf("%s = %s[%q]", strings.ToLower(f.noun), f.domain, strings.ToLower(f.noun))
+		}
+		lines = append(lines,
+			fmt.Sprintf(`  File "/app/%s/%s/%s.py", line %d, in %s`, g.pkg(), f.domain, strings.ToLower(f.layer), f.line, f.Snake()),
+			"    "+code,
+		)
+	}
+	return append(lines, e.typ+": "+e.message)
+}
+
+var nodeExceptions = []exception{
+	{"TypeError", "Cannot read properties of undefined (reading '{field}')"},
+	{"ReferenceError", "{field} is not defined"},
+	{"RangeError", "Invalid array length"},
+	{"Error", "connect ECONNREFUSED {ip}:5432"},
+	{"SyntaxError", `Unexpected token 'u', "undefined" is not valid JSON`},
+}
+
+func (g *gen) node() Trace {
+	frames := g.frames(2 + g.rnd.Intn(4))
+	e := g.exception(nodeExceptions, frames[0])
+	t := Trace{Language: Node, Type: e.typ, Message: e.message, Lines: []string{e.typ + ": " + e.message}}
+	for i, f := range frames {
+		async := ""
+		if i > 0 {
+			async = "async "
+		}
+		t.Lines = append(t.Lines, fmt.Sprintf("    at %s%s.%s (/app/src/%s/%s.js:%d:%d)", async, f.Type(), f.Method(), f.domain, strings.ToLower(f.layer), f.line, f.column))
+	}
+	return t
+}
+
+var (
+	dotnetExceptions = []exception{
+		{"System.NullReferenceException", "Object reference not set to an instance of an object."},
+		{"System.InvalidOperationException", "Sequence contains no elements"},
+		{"System.Collections.Generic.KeyNotFoundException", "The given key '{field}' was not present in the dictionary."},
+		{"System.ArgumentOutOfRangeException", "Index was out of range. Must be non-negative and less than the size of the collection. (Parameter 'index')"},
+	}
+	dotnetCauses = []exception{
+		{"Npgsql.NpgsqlException", "Exception while reading from stream"},
+		{"System.Net.Http.HttpRequestException", "Connection refused ({domain}:8080)"},
+		{"System.TimeoutException", "The operation has timed out."},
+	}
+)
+
+func (g *gen) dotnet() Trace {
+	frames := g.frames(2 + g.rnd.Intn(4))
+	e := g.exception(dotnetExceptions, frames[0])
+	t := Trace{Language: DotNet, Type: e.typ, Message: e.message, Lines: []string{e.typ + ": " + e.message}}
+	ns := title(g.pkg())
+	dotnetFrame := func(f frame) string {
+		return fmt.Sprintf("   at %s.%s.%s.%s(Guid %sId) in /src/%s.%s/%s.cs:line %d", ns, title(f.domain), f.Type(), title(f.Method()), f.domain, ns, title(f.domain), f.Type(), f.line)
+	}
+	if g.rnd.Intn(2) == 0 {
+		// The inner exception is printed within the outer one.
+		c := g.exception(dotnetCauses, frames[0])
+		t.Lines = append(t.Lines, " ---> "+c.typ+": "+c.message)
+		for _, f := range g.frames(1 + g.rnd.Intn(2)) {
+			t.Lines = append(t.Lines, dotnetFrame(f))
+		}
+		t.Lines = append(t.Lines, "   --- End of inner exception stack trace ---")
+	}
+	for _, f := range frames {
+		t.Lines = append(t.Lines, dotnetFrame(f))
+	}
+	t.Lines = append(t.Lines,
+		"   at Microsoft.AspNetCore.Mvc.Infrastructure.ActionMethodExecutor.TaskOfIActionResultExecutor.Execute(ActionContext actionContext, IActionResultTypeMapper mapper, ObjectMethodExecutor executor, Object controller, Object[] arguments)",
+		"   at Microsoft.AspNetCore.Mvc.Infrastructure.ControllerActionInvoker.<InvokeActionMethodAsync>g__Awaited|12_0(ControllerActionInvoker invoker, ValueTask`1 actionResultValueTask)",
+		"   at Microsoft.AspNetCore.Routing.EndpointMiddleware.<Invoke>g__AwaitRequestTask|7_0(Endpoint endpoint, Task requestTask, ILogger logger)",
+	)
+	return t
+}
+
+var rubyExceptions = []exception{
+	{"NoMethodError", "undefined method `{field}' for nil:NilClass"},
+	{"ActiveRecord::RecordNotFound", "Couldn't find {Domain} with 'id'={n}"},
+	{"ZeroDivisionError", "divided by 0"},
+	{"KeyError", "key not found: :{field}"},
+	{"Redis::TimeoutError", "Connection timed out"},
+}
+
+func (g *gen) ruby() Trace {
+	frames := g.frames(2 + g.rnd.Intn(4))
+	e := g.exception(rubyExceptions, frames[0])
+	rubyFrame := func(f frame) string {
+		dir := "services"
+		if f.layer == "Controller" {
+			dir = "controllers"
+		}
+		return fmt.Sprintf("app/%s/%s_%s.rb:%d:in `%s'", dir, f.domain, strings.ToLower(f.layer), f.line, f.Snake())
+	}
+	t := Trace{Language: Ruby, Type: e.typ, Message: e.message}
+	t.Lines = append(t.Lines, fmt.Sprintf("%s: %s (%s)", rubyFrame(frames[0]), e.message, e.typ))
+	for _, f := range frames[1:] {
+		t.Lines = append(t.Lines, "\tfrom "+rubyFrame(f))
+	}
+	t.Lines = append(t.Lines,
+		"\tfrom /usr/local/bundle/gems/actionpack-7.1.3/lib/action_controller/metal/basic_implicit_render.rb:6:in `send_action'",
+		"\tfrom /usr/local/bundle/gems/actionpack-7.1.3/lib/abstract_controller/base.rb:224:in `process_action'",
+		"\tfrom /usr/local/bundle/gems/puma-6.4.2/lib/puma/thread_pool.rb:155:in `block in spawn_thread'",
+	)
+	return t
+}
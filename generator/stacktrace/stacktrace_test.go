@@ -0,0 +1,110 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// volatile matches the parts of Go traces changing between two panics.
+var volatile = regexp.MustCompile(`0x(c000|7f2c)[0-9a-f]{6}|goroutine \d+`)
+
+func TestFingerprints(t *testing.T) {
+	for _, lang := range Languages {
+		t.Run(string(lang), func(t *testing.T) {
+			a := assert.New(t)
+			first := New(lang, "checkout", "db-timeout")
+			again := New(lang, "checkout", "db-timeout")
+			a.Equal(volatile.ReplaceAllString(first.String(), ""), volatile.ReplaceAllString(again.String(), ""), "same fingerprint, same trace")
+			a.Equal(first.Type, again.Type)
+			a.Equal(first.Message, again.Message)
+			a.NotEqual(first.String(), New(lang, "checkout", "other").String())
+			a.NotEqual(first.String(), New(lang, "cart", "db-timeout").String())
+			a.Greater(len(first.Lines), 2)
+			a.NotEmpty(first.Message)
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	a := assert.New(t)
+	for i := 0; i < 20; i++ {
+		fingerprint := string(rune('a' + i))
+
+		java := New(Java, "checkout", fingerprint)
+		a.Equal(java.Type, strings.SplitN(java.Lines[0], ":", 2)[0])
+		a.Regexp(`^\tat com\.example\.checkout\.\w+\.\w+\.\w+\(\w+\.java:\d+\)$`, java.Lines[1])
+		for j, line := range java.Lines {
+			if strings.HasPrefix(line, "Caused by: ") {
+				a.Regexp(`^\tat `, java.Lines[j+1])
+			}
+		}
+		a.Equal("\tat java.base/java.lang.Thread.run(Thread.java:1583)", java.Lines[len(java.Lines)-1-javaCauseLines(java)])
+
+		golang := New(Go, "checkout-api", fingerprint)
+		a.True(strings.HasPrefix(golang.Lines[0], "panic: "+strings.Split(golang.Message, "\n")[0]))
+		a.Contains(golang.String(), "\n\ngoroutine ")
+		a.Contains(golang.String(), " [running]:\ngithub.com/example/checkoutapi/internal/")
+		a.Empty(golang.Type)
+
+		python := New(Python, "checkout", fingerprint)
+		a.Equal("Traceback (most recent call last):", python.Lines[0])
+		a.Equal(python.Type+": "+python.Message, python.Lines[len(python.Lines)-1])
+
+		node := New(Node, "checkout", fingerprint)
+		a.Equal(node.Type+": "+node.Message, node.Lines[0])
+		a.Regexp(`^    at (async )?\w+\.\w+ \(/app/src/\w+/\w+\.js:\d+:\d+\)$`, node.Lines[1])
+
+		dotnet := New(DotNet, "checkout", fingerprint)
+		a.Equal(dotnet.Type+": "+dotnet.Message, dotnet.Lines[0])
+		a.Contains(dotnet.String(), "   at Checkout.")
+
+		ruby := New(Ruby, "checkout", fingerprint)
+		a.Regexp("^app/\\w+/\\w+\\.rb:\\d+:in `\\w+': .+ \\("+regexp.QuoteMeta(ruby.Type)+"\\)$", ruby.Lines[0])
+		a.True(strings.HasPrefix(ruby.Lines[1], "\tfrom "))
+	}
+	a.Panics(func() { New("cobol", "checkout", "a") })
+}
+
+// javaCauseLines returns the number of lines of the causes of a Java trace.
+func javaCauseLines(t Trace) int {
+	for i, line := range t.Lines {
+		if strings.HasPrefix(line, "Caused by: ") {
+			return len(t.Lines) - i
+		}
+	}
+	return 0
+}
+
+func TestEntry(t *testing.T) {
+	a := assert.New(t)
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC)
+
+	java := New(Java, "checkout", "a")
+	entry := java.Entry(ts, "POST", "/cart")
+	a.Regexp(`^2024-05-01 10:00:00\.123 ERROR 1 --- \[nio-8080-exec-\d+\] c\.e\.c\.\w\.\w+ : `, entry)
+	a.True(strings.HasSuffix(entry, "\n"+java.String()))
+
+	golang := New(Go, "checkout", "a")
+	entry = golang.Entry(ts, "GET", "/cart")
+	a.Regexp(`^2024/05/01 10:00:00 http: panic serving 10\.0\.\d+\.\d+:\d+: `, entry)
+	a.NotContains(entry, "panic: ")
+	a.Contains(entry, "\ngoroutine ")
+
+	python := New(Python, "checkout", "a").Entry(ts, "GET", "/cart")
+	a.True(strings.HasPrefix(python, "2024-05-01 10:00:00,123 ERROR [app] Exception on /cart [GET]\nTraceback"))
+
+	times, lines := Split(ts, entry)
+	require.Len(t, lines, strings.Count(entry, "\n")+1)
+	for i := 1; i < len(times); i++ {
+		a.True(times[i].After(times[i-1]))
+	}
+
+	for _, lang := range Languages {
+		a.Contains(Access(lang, ts, "GET", "/cart", 200, 42*time.Millisecond), "200", lang)
+	}
+}